@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// defaultAckPrefix is the body prefix that marks a message as an
+// acknowledgment when the config sets none: "ACK:42" acknowledges the
+// message with event ID 42.
+const defaultAckPrefix = "ACK:"
+
+// ackPredicate reports whether e acknowledges an earlier message, and
+// which one (by event ID). clockmail has no ack kind of its own, so the
+// convention is up to the agents; the predicate encodes it.
+type ackPredicate func(e model.Event) (ackedID int64, ok bool)
+
+// ackByPrefix returns the predicate for messages whose body is prefix
+// followed by the acknowledged event's ID ("ACK:42", "ACK: #42").
+func ackByPrefix(prefix string) ackPredicate {
+	return func(e model.Event) (int64, bool) {
+		if e.Kind != model.EventMsg {
+			return 0, false
+		}
+		rest, ok := strings.CutPrefix(strings.TrimSpace(e.Body), prefix)
+		if !ok {
+			return 0, false
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(rest), "#"), 10, 64)
+		if err != nil || id <= 0 {
+			return 0, false
+		}
+		return id, true
+	}
+}
+
+// isAck is the acknowledgment convention loaded from the config file at
+// startup.
+var isAck = ackByPrefix(defaultAckPrefix)
+
+// pairAcks matches messages with their acknowledgments, returning the ack
+// event ID for each acknowledged message ID. An ack counts only when it
+// comes from the message's recipient and follows the message; the first
+// such ack wins. Events must be in Lamport order.
+func pairAcks(events []model.Event, isAck ackPredicate) map[int64]int64 {
+	acks := make(map[int64]int64)
+	sent := make(map[int64]model.Event)
+	for _, e := range events {
+		if e.Kind != model.EventMsg {
+			continue
+		}
+		if id, ok := isAck(e); ok {
+			if msg, seen := sent[id]; seen && msg.Target == e.AgentID && msg.LamportTS < e.LamportTS {
+				if _, done := acks[id]; !done {
+					acks[id] = e.ID
+				}
+			}
+			continue
+		}
+		sent[e.ID] = e
+	}
+	return acks
+}
+
+// ackMarkers returns the Messages marker for each loaded message: ✓ when
+// acknowledged, ⏳ while awaiting one. Sessions that never ack get no
+// markers (nil), and acks themselves are not marked.
+func ackMarkers(events []model.Event, isAck ackPredicate) map[int64]string {
+	acks := pairAcks(events, isAck)
+	anyAck := false
+	for _, e := range events {
+		if _, ok := isAck(e); ok {
+			anyAck = true
+			break
+		}
+	}
+	if !anyAck {
+		return nil
+	}
+	markers := make(map[int64]string)
+	for _, e := range events {
+		if e.Kind != model.EventMsg {
+			continue
+		}
+		if _, ok := isAck(e); ok {
+			continue
+		}
+		if _, ok := acks[e.ID]; ok {
+			markers[e.ID] = safeStyle.Render("✓")
+		} else {
+			markers[e.ID] = dimStyle.Render("⏳")
+		}
+	}
+	return markers
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+func TestPairAcks(t *testing.T) {
+	events := []model.Event{
+		{ID: 1, AgentID: "alice", Target: "bob", LamportTS: 1, Kind: model.EventMsg, Body: "build main.go"},
+		{ID: 2, AgentID: "alice", Target: "bob", LamportTS: 2, Kind: model.EventMsg, Body: "and test it"},
+		{ID: 3, AgentID: "carol", Target: "alice", LamportTS: 3, Kind: model.EventMsg, Body: "ACK:2"}, // not the recipient
+		{ID: 4, AgentID: "bob", Target: "alice", LamportTS: 4, Kind: model.EventMsg, Body: "ACK: #1"},
+		{ID: 5, AgentID: "bob", Target: "alice", LamportTS: 5, Kind: model.EventMsg, Body: "ACK:1"}, // duplicate
+	}
+	acks := pairAcks(events, ackByPrefix("ACK:"))
+	if len(acks) != 1 || acks[1] != 4 {
+		t.Errorf("pairAcks = %v, want map[1:4]", acks)
+	}
+
+	markers := ackMarkers(events, ackByPrefix("ACK:"))
+	if got := stripAnsi(markers[1]); got != "✓" {
+		t.Errorf("marker for acked message = %q, want ✓", got)
+	}
+	if got := stripAnsi(markers[2]); got != "⏳" {
+		t.Errorf("marker for unacked message = %q, want ⏳", got)
+	}
+	if _, ok := markers[4]; ok {
+		t.Error("acks themselves should not be marked")
+	}
+
+	// A different convention: no body matches, so no markers at all.
+	if markers := ackMarkers(events, ackByPrefix("ok ")); markers != nil {
+		t.Errorf("markers without any ack = %v, want none", markers)
+	}
+}
+
+func TestMessagesShowAckMarkers(t *testing.T) {
+	m := testModel()
+	// bob acknowledges alice's "hello" (event 1).
+	m.snap.Events = append(m.snap.Events, model.Event{
+		ID: 5, AgentID: "bob", Target: "alice", LamportTS: 5, Kind: model.EventMsg, Body: "ACK:1",
+	})
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, "alice -> bob ✓") {
+		t.Errorf("acked message lacks ✓:\n%s", out)
+	}
+	if !strings.Contains(out, "bob -> alice ⏳") {
+		t.Errorf("unacked message lacks ⏳:\n%s", out)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// activityBucket is the width of one cell in the activity strip.
+const activityBucket = 5 * time.Minute
+
+// activityBuckets counts events per bucket of the given width, from the
+// bucket holding the oldest event through the one holding the newest.
+// Buckets with no events are kept as zeros so the strip shows idle time.
+// Events without a creation time are ignored.
+func activityBuckets(events []model.Event, width time.Duration) (time.Time, []int) {
+	var first, last time.Time
+	for _, e := range events {
+		if e.CreatedAt.IsZero() {
+			continue
+		}
+		if first.IsZero() || e.CreatedAt.Before(first) {
+			first = e.CreatedAt
+		}
+		if e.CreatedAt.After(last) {
+			last = e.CreatedAt
+		}
+	}
+	if first.IsZero() {
+		return time.Time{}, nil
+	}
+	start := first.Truncate(width)
+	counts := make([]int, int(last.Sub(start)/width)+1)
+	for _, e := range events {
+		if e.CreatedAt.IsZero() {
+			continue
+		}
+		counts[int(e.CreatedAt.Sub(start)/width)]++
+	}
+	return start, counts
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+func TestActivityBuckets(t *testing.T) {
+	base := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) model.Event { return model.Event{CreatedAt: base.Add(d)} }
+	events := []model.Event{
+		at(2 * time.Minute), // bucket 0 (15:00–15:05)
+		at(4*time.Minute + 59*time.Second),
+		at(5 * time.Minute),  // bucket 1 starts exactly at 15:05
+		at(17 * time.Minute), // bucket 3; bucket 2 stays empty
+		{},                   // no timestamp: ignored
+	}
+	start, counts := activityBuckets(events, 5*time.Minute)
+	if !start.Equal(base) {
+		t.Errorf("start = %v, want %v", start, base)
+	}
+	if want := []int{2, 1, 0, 1}; !slices.Equal(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	if _, counts := activityBuckets(nil, 5*time.Minute); counts != nil {
+		t.Errorf("no events should give no buckets, got %v", counts)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail_viewer/internal/datasource"
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// runAgents implements `cmv agents`: print a plain table of agents and exit.
+// It returns the process exit code.
+func runAgents(args []string) int {
+	fs := flag.NewFlagSet("agents", flag.ContinueOnError)
+	dbPath := fs.String("db", "", "path to clockmail.db (default: auto-discover)")
+	dbName := fs.String("db-name", "", "database file name to discover in .clockmail/ (default: clockmail.db)")
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	configPath := fs.String("config", "", "path to config file (default: <user config dir>/cmv/config.json)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := applyConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+		return 1
+	}
+	if *noColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	if *dbPath != "" {
+		os.Setenv("CLOCKMAIL_DB", *dbPath)
+	}
+	if *dbName != "" {
+		os.Setenv("CLOCKMAIL_DB_NAME", *dbName)
+	}
+
+	s, _, err := datasource.Open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+		return 1
+	}
+	defer s.Close()
+
+	snap, err := snapshot.Build(s, staleAfter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: snapshot: %v\n", err)
+		return 1
+	}
+	if err := formatAgentTable(os.Stdout, snap.Agents, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// formatAgentTable writes agents as an aligned table, stalest first (oldest
+// LastSeen at the top, ties broken by ID). The status column is last so its
+// color codes don't disturb tabwriter's alignment.
+func formatAgentTable(w io.Writer, agents []model.Agent, now time.Time) error {
+	sorted := make([]model.Agent, len(agents))
+	copy(sorted, agents)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].LastSeen.Equal(sorted[j].LastSeen) {
+			return sorted[i].LastSeen.Before(sorted[j].LastSeen)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCLOCK\tPROGRESS\tLAST SEEN\tSTATUS")
+	for _, ag := range sorted {
+		status := agentActiveStyle.Render("ACTIVE")
+		if isStale(ag, now) {
+			status = agentStaleStyle.Render("STALE")
+		}
+		fmt.Fprintf(tw, "%s\t%d\te%d/r%d\t%s ago\t%s\n",
+			ag.ID, ag.Clock, ag.Epoch, ag.Round, shortDuration(now.Sub(ag.LastSeen)), status)
+	}
+	if len(sorted) == 0 {
+		fmt.Fprintln(tw, "(no agents registered)")
+	}
+	return tw.Flush()
+}
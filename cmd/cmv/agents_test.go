@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+func TestFormatAgentTableFromStore(t *testing.T) {
+	s := newTestStore(t)
+	for _, id := range []string{"alice", "bob"} {
+		if _, err := s.RegisterAgent(id); err != nil {
+			t.Fatalf("RegisterAgent %s: %v", id, err)
+		}
+	}
+	snap, err := snapshot.Build(s, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := formatAgentTable(&buf, snap.Agents, time.Now()); err != nil {
+		t.Fatalf("formatAgentTable: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(stripAnsi(buf.String()), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header + 2 rows, got %d lines:\n%s", len(lines), buf.String())
+	}
+	for _, col := range []string{"ID", "CLOCK", "PROGRESS", "LAST SEEN", "STATUS"} {
+		if !strings.Contains(lines[0], col) {
+			t.Errorf("header missing %q: %q", col, lines[0])
+		}
+	}
+	for _, id := range []string{"alice", "bob"} {
+		if !strings.Contains(buf.String(), id) {
+			t.Errorf("table missing row for %s", id)
+		}
+	}
+}
+
+func TestFormatAgentTableSortsStalestFirst(t *testing.T) {
+	now := time.Now()
+	snap := testSnapshot()
+	snap.Agents[1].LastSeen = now.Add(-20 * time.Minute) // bob stale
+
+	var buf bytes.Buffer
+	if err := formatAgentTable(&buf, snap.Agents, now); err != nil {
+		t.Fatalf("formatAgentTable: %v", err)
+	}
+	lines := strings.Split(stripAnsi(buf.String()), "\n")
+	if !strings.HasPrefix(lines[1], "bob") || !strings.Contains(lines[1], "STALE") {
+		t.Errorf("stale bob should be listed first, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "alice") || !strings.Contains(lines[2], "ACTIVE") {
+		t.Errorf("active alice should follow, got %q", lines[2])
+	}
+	// Input order must be left untouched.
+	if snap.Agents[0].ID != "alice" {
+		t.Error("formatAgentTable should not reorder the snapshot's agents")
+	}
+}
+
+func TestFormatAgentTableEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := formatAgentTable(&buf, nil, time.Now()); err != nil {
+		t.Fatalf("formatAgentTable: %v", err)
+	}
+	if !strings.Contains(buf.String(), "no agents registered") {
+		t.Errorf("expected empty note, got %q", buf.String())
+	}
+}
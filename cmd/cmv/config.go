@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// config is the on-disk cmv configuration (JSON). Every field is optional.
+//
+//	{
+//	  "stale": ["batch-* => 1h", "* => 10m"],
+//	  "glyphs": {"msg": "✉", "other": "·"},
+//	  "keys": {"next-view": ["tab", "L"], "quit": ["q"]},
+//	  "ack": "ACK:"
+//	}
+type config struct {
+	// Stale lists per-agent staleness rules as "<glob> => <duration>",
+	// evaluated in order; the first matching pattern wins.
+	Stale []string `json:"stale"`
+
+	// Glyphs overrides Diagram cell labels, keyed by event kind ("msg",
+	// "lock_req", ...); "other" labels kinds with no glyph of their own.
+	Glyphs map[string]string `json:"glyphs"`
+
+	// Keys rebinds actions (see keyActions) to lists of keys, replacing
+	// their default keys.
+	Keys map[string][]string `json:"keys"`
+
+	// Ack is the body prefix that marks a message as acknowledging an
+	// earlier one, followed by its event ID (default "ACK:").
+	Ack string `json:"ack"`
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/cmv/config.json (or the
+// platform equivalent), or "" if no config directory is known.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cmv", "config.json")
+}
+
+// loadConfig reads the config file at path. A missing file is not an error
+// unless required is set (the user named it explicitly with --config).
+func loadConfig(path string, required bool) (config, error) {
+	var c config
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("config: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// staleRule maps an agent ID glob (path.Match syntax) to a staleness
+// threshold.
+type staleRule struct {
+	Pattern   string
+	Threshold time.Duration
+}
+
+// parseStaleRules parses "<glob> => <duration>" specs.
+func parseStaleRules(specs []string) ([]staleRule, error) {
+	rules := make([]staleRule, 0, len(specs))
+	for _, spec := range specs {
+		pat, dur, ok := strings.Cut(spec, "=>")
+		if !ok {
+			return nil, fmt.Errorf("stale rule %q: want \"<pattern> => <duration>\"", spec)
+		}
+		pat = strings.TrimSpace(pat)
+		if _, err := path.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("stale rule %q: bad pattern: %w", spec, err)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(dur))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("stale rule %q: bad duration", spec)
+		}
+		rules = append(rules, staleRule{Pattern: pat, Threshold: d})
+	}
+	return rules, nil
+}
+
+// staleThresholdFor returns the threshold of the first rule whose pattern
+// matches id, or snapshot.DefaultStaleAfter if none does.
+func staleThresholdFor(id string, rules []staleRule) time.Duration {
+	for _, r := range rules {
+		if ok, _ := path.Match(r.Pattern, id); ok {
+			return r.Threshold
+		}
+	}
+	return snapshot.DefaultStaleAfter
+}
+
+// otherGlyphKey is the "glyphs" key for kinds without their own glyph.
+const otherGlyphKey = "other"
+
+// parseGlyphs merges glyph overrides into the defaults. Each glyph must be
+// one or two terminal cells wide so Diagram columns stay aligned. Any kind
+// name is accepted, so new event kinds can be mapped without code changes.
+func parseGlyphs(overrides map[string]string) (map[model.EventKind]string, string, error) {
+	glyphs := make(map[model.EventKind]string, len(defaultGlyphs)+len(overrides))
+	for k, g := range defaultGlyphs {
+		glyphs[k] = g
+	}
+	other := defaultOtherGlyph
+	for k, g := range overrides {
+		if w := ansi.StringWidth(g); w < 1 || w > 2 {
+			return nil, "", fmt.Errorf("glyph %q for %q: must be 1 or 2 columns wide", g, k)
+		}
+		if k == otherGlyphKey {
+			other = g
+			continue
+		}
+		glyphs[model.EventKind(k)] = g
+	}
+	return glyphs, other, nil
+}
+
+// keyActions names the bindings the "keys" config may rebind.
+var keyActions = map[string]func(*keyMap) *key.Binding{
+	"quit":      func(k *keyMap) *key.Binding { return &k.Quit },
+	"next-view": func(k *keyMap) *key.Binding { return &k.Tab },
+	"refresh":   func(k *keyMap) *key.Binding { return &k.Refresh },
+	"up":        func(k *keyMap) *key.Binding { return &k.Up },
+	"down":      func(k *keyMap) *key.Binding { return &k.Down },
+	"filter":    func(k *keyMap) *key.Binding { return &k.Filter },
+	"help":      func(k *keyMap) *key.Binding { return &k.Help },
+	"enter":     func(k *keyMap) *key.Binding { return &k.Enter },
+	"back":      func(k *keyMap) *key.Binding { return &k.Esc },
+}
+
+// parseKeys merges key overrides into defaultKeys. Unknown actions and
+// empty key lists are errors. A key bound twice (to two actions, or to an
+// action and a view shortcut) is only a warning: the binding checked first
+// wins, which is rarely what the user meant.
+func parseKeys(overrides map[string][]string) (keyMap, []string, error) {
+	km := defaultKeys
+	for name, ks := range overrides {
+		get, ok := keyActions[name]
+		if !ok {
+			return km, nil, fmt.Errorf("keys: unknown action %q", name)
+		}
+		if len(ks) == 0 {
+			return km, nil, fmt.Errorf("keys: %q has no keys", name)
+		}
+		b := get(&km)
+		*b = key.NewBinding(key.WithKeys(ks...), key.WithHelp(strings.Join(ks, "/"), b.Help().Desc))
+	}
+
+	owner := make(map[string]string)
+	var warnings []string
+	claim := func(k, who string) {
+		if prev, ok := owner[k]; ok && prev != who {
+			warnings = append(warnings, fmt.Sprintf("key %q is bound to both %s and %s", k, prev, who))
+			return
+		}
+		owner[k] = who
+	}
+	for _, vi := range views {
+		claim(vi.key, "the "+vi.name+" view")
+	}
+	for _, row := range km.FullHelp() {
+		for _, b := range row {
+			for _, k := range b.Keys() {
+				claim(k, strconv.Quote(b.Help().Desc))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return km, warnings, nil
+}
+
+// staleRules are the rules loaded from the config file at startup.
+var staleRules []staleRule
+
+// staleAfter is the per-agent staleness threshold passed to snapshot
+// builds, from the loaded rules.
+func staleAfter(id string) time.Duration {
+	return staleThresholdFor(id, staleRules)
+}
+
+// isStale reports whether ag has gone unseen longer than its threshold.
+func isStale(ag model.Agent, now time.Time) bool {
+	return now.Sub(ag.LastSeen) > staleThresholdFor(ag.ID, staleRules)
+}
+
+// applyConfig loads the config at path and installs its settings. An empty
+// path means the default location, which may be absent.
+func applyConfig(path string) error {
+	required := path != ""
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	c, err := loadConfig(path, required)
+	if err != nil {
+		return err
+	}
+	rules, err := parseStaleRules(c.Stale)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	glyphs, other, err := parseGlyphs(c.Glyphs)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	km, warnings, err := parseKeys(c.Keys)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "cmv: config %s: %s\n", path, w)
+	}
+	ackPrefix := defaultAckPrefix
+	if c.Ack != "" {
+		ackPrefix = c.Ack
+	}
+	staleRules = rules
+	diagramGlyphs, otherGlyph = glyphs, other
+	keys = km
+	isAck = ackByPrefix(ackPrefix)
+	return nil
+}
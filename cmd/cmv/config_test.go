@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+func TestStaleThresholdFor(t *testing.T) {
+	rules, err := parseStaleRules([]string{"batch-* => 1h", "ci-?? => 30m", "* => 5m"})
+	if err != nil {
+		t.Fatalf("parseStaleRules: %v", err)
+	}
+	tests := []struct {
+		id   string
+		want time.Duration
+	}{
+		{"batch-nightly", time.Hour},
+		{"ci-01", 30 * time.Minute},
+		{"ci-001", 5 * time.Minute}, // ? matches exactly one character
+		{"alice", 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := staleThresholdFor(tt.id, rules); got != tt.want {
+			t.Errorf("staleThresholdFor(%q) = %s, want %s", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestStaleThresholdForFirstMatchWins(t *testing.T) {
+	rules := []staleRule{{"*", time.Minute}, {"batch-*", time.Hour}}
+	if got := staleThresholdFor("batch-1", rules); got != time.Minute {
+		t.Errorf("earlier catch-all should win, got %s", got)
+	}
+}
+
+func TestStaleThresholdForDefault(t *testing.T) {
+	if got := staleThresholdFor("alice", nil); got != snapshot.DefaultStaleAfter {
+		t.Errorf("no rules: got %s, want %s", got, snapshot.DefaultStaleAfter)
+	}
+	rules := []staleRule{{"batch-*", time.Hour}}
+	if got := staleThresholdFor("alice", rules); got != snapshot.DefaultStaleAfter {
+		t.Errorf("no match: got %s, want %s", got, snapshot.DefaultStaleAfter)
+	}
+}
+
+func TestParseStaleRulesErrors(t *testing.T) {
+	for _, spec := range []string{"batch-*", "[ => 1h", "* => soon", "* => -1m"} {
+		if _, err := parseStaleRules([]string{spec}); err == nil {
+			t.Errorf("parseStaleRules(%q) should fail", spec)
+		}
+	}
+}
+
+func TestIsStaleUsesRules(t *testing.T) {
+	defer func(r []staleRule) { staleRules = r }(staleRules)
+	staleRules = []staleRule{{"batch-*", time.Hour}}
+
+	now := time.Now()
+	quiet := now.Add(-30 * time.Minute)
+	if isStale(model.Agent{ID: "batch-1", LastSeen: quiet}, now) {
+		t.Error("batch agent quiet for 30m should still be active")
+	}
+	if !isStale(model.Agent{ID: "alice", LastSeen: quiet}, now) {
+		t.Error("default agent quiet for 30m should be stale")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"stale": ["batch-* => 1h"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadConfig(path, true)
+	if err != nil || len(c.Stale) != 1 || c.Stale[0] != "batch-* => 1h" {
+		t.Fatalf("loadConfig = %+v, %v", c, err)
+	}
+
+	missing := filepath.Join(dir, "nope.json")
+	if _, err := loadConfig(missing, false); err != nil {
+		t.Errorf("missing optional config should not fail: %v", err)
+	}
+	if _, err := loadConfig(missing, true); err == nil {
+		t.Error("missing explicit config should fail")
+	}
+}
+
+func TestParseGlyphs(t *testing.T) {
+	glyphs, other, err := parseGlyphs(map[string]string{"msg": "✉", "review_req": "R", "other": "·"})
+	if err != nil {
+		t.Fatalf("parseGlyphs: %v", err)
+	}
+	if glyphs[model.EventMsg] != "✉" || glyphs[model.EventReviewReq] != "R" || other != "·" {
+		t.Errorf("overrides not applied: %v, other %q", glyphs, other)
+	}
+	if glyphs[model.EventLockReq] != "L" {
+		t.Errorf("unmentioned kinds keep their defaults, got %q", glyphs[model.EventLockReq])
+	}
+	for _, bad := range []string{"", "abc"} {
+		if _, _, err := parseGlyphs(map[string]string{"msg": bad}); err == nil {
+			t.Errorf("glyph %q should be rejected", bad)
+		}
+	}
+}
+
+func TestDiagramUsesConfiguredGlyph(t *testing.T) {
+	defer func(g map[model.EventKind]string) { diagramGlyphs = g }(diagramGlyphs)
+	glyphs, _, err := parseGlyphs(map[string]string{"msg": "M"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagramGlyphs = glyphs
+
+	events := []model.Event{{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob"}}
+	_, rows := buildDiagramData([]model.Agent{{ID: "alice"}, {ID: "bob"}}, events, nil, diagramByRegistration)
+	if len(rows) != 1 || rows[0].cells["alice"].label != "M" {
+		t.Errorf("message cell should use the configured glyph, got %+v", rows)
+	}
+}
+
+func TestParseKeysRemapsNextView(t *testing.T) {
+	km, warnings, err := parseKeys(map[string][]string{"next-view": {"ctrl+n"}})
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("parseKeys = %v, %v", warnings, err)
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyCtrlN}, km.Tab) {
+		t.Error("Tab binding should match the configured key")
+	}
+	if key.Matches(tea.KeyMsg{Type: tea.KeyTab}, km.Tab) {
+		t.Error("configured keys replace the defaults")
+	}
+	if got := km.Tab.Help(); got.Key != "ctrl+n" || got.Desc != "next view" {
+		t.Errorf("help = %+v, want ctrl+n / next view", got)
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyTab}, defaultKeys.Tab) {
+		t.Error("parseKeys must not modify defaultKeys")
+	}
+}
+
+func TestParseKeysConflictsAndErrors(t *testing.T) {
+	if _, warnings, _ := parseKeys(nil); len(warnings) != 0 {
+		t.Errorf("default bindings should not conflict: %v", warnings)
+	}
+
+	_, warnings, err := parseKeys(map[string][]string{"next-view": {"l"}, "refresh": {"q"}})
+	if err != nil {
+		t.Fatalf("parseKeys: %v", err)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "locks view") || !strings.Contains(warnings[1], `"q"`) {
+		t.Errorf("expected conflicts with the locks view and quit, got %q", warnings)
+	}
+
+	for _, bad := range []map[string][]string{{"teleport": {"x"}}, {"quit": {}}} {
+		if _, _, err := parseKeys(bad); err == nil {
+			t.Errorf("parseKeys(%v) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// crashStackLines caps the goroutine dump in a crash report.
+const crashStackLines = 40
+
+// crashReport is what a panic in the TUI leaves behind for --crash-report.
+type crashReport struct {
+	value any    // the recovered panic value
+	stack string // the panicking goroutine's stack, truncated
+	frame string // the last frame View rendered successfully
+}
+
+// crashState is shared by every copy of a crashGuard: Bubble Tea hands
+// models around by value, but the last good frame and the crash must
+// outlive the copy that saw them.
+type crashState struct {
+	frame string
+	crash *crashReport
+}
+
+// record keeps the panic value r, with the current stack, as the crash.
+// Only the first panic is kept.
+func (s *crashState) record(r any) {
+	if s.crash != nil {
+		return
+	}
+	stack := strings.Split(strings.TrimRight(string(debug.Stack()), "\n"), "\n")
+	if len(stack) > crashStackLines {
+		stack = append(stack[:crashStackLines], fmt.Sprintf("… (%d more lines)", len(stack)-crashStackLines))
+	}
+	s.crash = &crashReport{value: r, stack: strings.Join(stack, "\n"), frame: s.frame}
+}
+
+// crashGuard wraps the TUI model for --crash-report. A panic in Update or
+// View is recovered instead of tearing down the program with the terminal
+// still in raw mode: the guard keeps showing the last good frame, quits
+// cleanly on the next message, and leaves a crashReport for main to print
+// once the terminal is restored.
+type crashGuard struct {
+	inner tea.Model
+	state *crashState
+}
+
+func newCrashGuard(inner tea.Model) crashGuard {
+	return crashGuard{inner: inner, state: &crashState{}}
+}
+
+func (g crashGuard) Init() tea.Cmd {
+	return g.inner.Init()
+}
+
+func (g crashGuard) Update(msg tea.Msg) (res tea.Model, cmd tea.Cmd) {
+	if g.state.crash != nil {
+		return g, tea.Quit
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.state.record(r)
+			res, cmd = g, tea.Quit
+		}
+	}()
+	g.inner, cmd = g.inner.Update(msg)
+	return g, cmd
+}
+
+func (g crashGuard) View() (frame string) {
+	if g.state.crash != nil {
+		return g.fallback()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.state.record(r)
+			frame = g.fallback()
+		}
+	}()
+	frame = g.inner.View()
+	g.state.frame = frame
+	return frame
+}
+
+// fallback is the frame drawn after a crash: the last good one, or a
+// one-line notice if nothing rendered before the panic.
+func (g crashGuard) fallback() string {
+	if g.state.frame != "" {
+		return g.state.frame
+	}
+	return fmt.Sprintf("cmv crashed: %v (quitting)", g.state.crash.value)
+}
+
+// writeCrashReport prints c for the user to attach to a bug report.
+func writeCrashReport(w io.Writer, c *crashReport) {
+	if c.frame != "" {
+		fmt.Fprintf(w, "cmv: last frame before the crash:\n\n%s\n\n", c.frame)
+	}
+	fmt.Fprintf(w, "cmv: panic: %v\n\n%s\n", c.value, c.stack)
+	fmt.Fprintln(w, "\nPlease include this report when filing an issue.")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicky renders "frame N" until armed, then panics in View or Update.
+type panicky struct {
+	n                      int
+	panicView, panicUpdate bool
+}
+
+func (p panicky) Init() tea.Cmd { return nil }
+
+func (p panicky) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if p.panicUpdate {
+		panic("update exploded")
+	}
+	if s, ok := msg.(string); ok && s == "arm" {
+		p.panicView = true
+	}
+	p.n++
+	return p, nil
+}
+
+func (p panicky) View() string {
+	if p.panicView {
+		var rows []string
+		_ = rows[p.n] // index out of range
+	}
+	return "frame " + string(rune('0'+p.n))
+}
+
+func TestCrashGuardRecoversView(t *testing.T) {
+	var m tea.Model = newCrashGuard(panicky{})
+	if got := m.View(); got != "frame 0" {
+		t.Fatalf("View = %q, want frame 0", got)
+	}
+	m, _ = m.Update("arm")
+	if got := m.View(); got != "frame 0" {
+		t.Errorf("View after a render panic = %q, want the last good frame", got)
+	}
+	g := m.(crashGuard)
+	if g.state.crash == nil {
+		t.Fatal("the panic was not recorded")
+	}
+	if _, cmd := m.Update(tickMsg{}); cmd == nil || cmd() != tea.Quit() {
+		t.Error("the next message should quit the program")
+	}
+
+	var b bytes.Buffer
+	writeCrashReport(&b, g.state.crash)
+	out := b.String()
+	for _, want := range []string{"frame 0", "index out of range", "goroutine"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report lacks %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(g.state.crash.stack, "\n"); n > crashStackLines {
+		t.Errorf("stack has %d lines, want at most %d", n+1, crashStackLines+1)
+	}
+}
+
+func TestCrashGuardRecoversUpdate(t *testing.T) {
+	var m tea.Model = newCrashGuard(panicky{panicUpdate: true})
+	m, cmd := m.Update(tickMsg{})
+	if cmd == nil || cmd() != tea.Quit() {
+		t.Error("a panic in Update should quit the program")
+	}
+	// Nothing rendered before the panic: the fallback still says why.
+	if got := m.View(); !strings.Contains(got, "update exploded") {
+		t.Errorf("fallback frame = %q, want the panic", got)
+	}
+}
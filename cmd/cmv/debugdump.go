@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// debugDumpSample is how many rows of each query result are printed.
+const debugDumpSample = 3
+
+// runDebugDump prints the raw result of every store query snapshot.Build
+// makes, with row counts and the first few rows, so discrepancies between
+// cmv and the cm CLI can be traced to the data rather than the rendering.
+// Queries run in the same order and with the same arguments as Build; a
+// failing query is reported and the dump continues. The first query error
+// is returned.
+func runDebugDump(w io.Writer, s snapshot.Reader) error {
+	p := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	var firstErr error
+	fail := func(query string, err error) {
+		p("%s: error: %v", query, err)
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", query, err)
+		}
+	}
+
+	agents, err := s.ListAgents()
+	if err != nil {
+		fail("ListAgents", err)
+	} else {
+		p("ListAgents: %d rows", len(agents))
+		for _, ag := range agents[:min(len(agents), debugDumpSample)] {
+			p("  id=%s clock=%d epoch=%d round=%d registered=%s last_seen=%s",
+				ag.ID, ag.Clock, ag.Epoch, ag.Round,
+				ag.Registered.Format(time.RFC3339), ag.LastSeen.Format(time.RFC3339))
+		}
+	}
+
+	maxID := s.MaxEventID()
+	p("MaxEventID: %d", maxID)
+
+	sinceID := snapshot.EventWindowStart(maxID)
+	events, err := s.ListEventsSinceID(sinceID, snapshot.EventLimit)
+	if err != nil {
+		fail(fmt.Sprintf("ListEventsSinceID(%d, %d)", sinceID, snapshot.EventLimit), err)
+	} else {
+		p("ListEventsSinceID(%d, %d): %d rows", sinceID, snapshot.EventLimit, len(events))
+		for _, e := range events[:min(len(events), debugDumpSample)] {
+			p("  %s", formatEventLine(e))
+		}
+		if len(events) > debugDumpSample {
+			p("  ... last: %s", formatEventLine(events[len(events)-1]))
+		}
+	}
+
+	locks, err := s.ListLocks()
+	if err != nil {
+		fail("ListLocks", err)
+	} else {
+		p("ListLocks: %d rows", len(locks))
+		for _, l := range locks[:min(len(locks), debugDumpSample)] {
+			p("  path=%s agent=%s lamport=%d epoch=%d exclusive=%t expires=%s",
+				l.Path, l.AgentID, l.LamportTS, l.Epoch, l.Exclusive, l.ExpiresAt.Format(time.RFC3339))
+		}
+	}
+
+	points, err := s.GetActivePointstamps()
+	if err != nil {
+		fail("GetActivePointstamps", err)
+	} else {
+		p("GetActivePointstamps: %d rows", len(points))
+		for _, pt := range points[:min(len(points), debugDumpSample)] {
+			p("  agent=%s epoch=%d round=%d", pt.AgentID, pt.Timestamp.Epoch, pt.Timestamp.Round)
+		}
+	}
+
+	p("CountEvents: %d", s.CountEvents())
+	return firstErr
+}
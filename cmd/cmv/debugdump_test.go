@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugDumpListsEveryQuery(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.RegisterAgent("alice"); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	for i := int64(1); i <= 5; i++ {
+		insertMsg(t, s, "alice", "bob", "hello", i)
+	}
+	if _, _, err := s.AcquireLock("main.go", "alice", 6, 0, true, time.Hour); err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runDebugDump(&buf, s); err != nil {
+		t.Fatalf("runDebugDump: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"ListAgents: 1 rows",
+		"MaxEventID: 5",
+		"ListEventsSinceID(0, 500): 5 rows",
+		"ListLocks: 1 rows",
+		"GetActivePointstamps: ",
+		"CountEvents: 5",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dump missing %q:\n%s", want, out)
+		}
+	}
+	// A sample of rows, not the full result.
+	if n := strings.Count(out, "alice -> bob: hello"); n != 4 { // 3 sampled + last
+		t.Errorf("expected 3 sampled events plus the last, got %d:\n%s", n, out)
+	}
+}
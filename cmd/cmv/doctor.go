@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/daviddao/clockmail/pkg/store"
+	"github.com/daviddao/clockmail_viewer/internal/datasource"
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// watchProbeTimeout is how long the doctor's watch check waits for the
+// probe file's event.
+const watchProbeTimeout = 2 * time.Second
+
+// checkResult is one line of the doctor report.
+type checkResult struct {
+	Name   string
+	OK     bool
+	Detail string // where it looked, what it found, or the error
+	Hint   string // remediation, shown on failure
+}
+
+// runDoctor implements `cmv doctor`: check that cmv can find, open, read,
+// and watch the database, print a report, and exit nonzero if any check
+// failed. Checks after a failure that they depend on are skipped.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	dbPath := fs.String("db", "", "path to clockmail.db (default: auto-discover)")
+	dbName := fs.String("db-name", "", "database file name to discover in .clockmail/ (default: clockmail.db)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dbPath != "" {
+		os.Setenv("CLOCKMAIL_DB", *dbPath)
+	}
+	if *dbName != "" {
+		os.Setenv("CLOCKMAIL_DB_NAME", *dbName)
+	}
+
+	var results []checkResult
+	path, res := checkDiscover()
+	results = append(results, res)
+	if res.OK {
+		var s *store.Store
+		s, res = checkOpen(path)
+		results = append(results, res)
+		if res.OK {
+			results = append(results, checkBuild(s))
+			s.Close()
+		}
+		results = append(results, checkWatch(filepath.Dir(path), watchProbeTimeout))
+	}
+	if !writeDoctorReport(os.Stdout, results) {
+		return 1
+	}
+	return 0
+}
+
+// checkDiscover reports whether a database can be discovered, and where.
+func checkDiscover() (string, checkResult) {
+	res := checkResult{Name: "discover"}
+	path, err := datasource.Discover()
+	if err != nil {
+		res.Detail = err.Error()
+		res.Hint = "run cmv inside a project with a .clockmail/ directory, or pass --db <path> (or set CLOCKMAIL_DB)"
+		return "", res
+	}
+	res.OK, res.Detail = true, path
+	return path, res
+}
+
+// checkOpen reports whether the database at path opens as a clockmail
+// store. The caller closes the returned store.
+func checkOpen(path string) (*store.Store, checkResult) {
+	res := checkResult{Name: "open"}
+	s, err := store.New(path)
+	if err != nil {
+		res.Detail = err.Error()
+		res.Hint = "check that the file and its directory are readable and writable (SQLite needs to create -wal/-shm files)"
+		return nil, res
+	}
+	res.OK, res.Detail = true, path
+	return s, res
+}
+
+// checkBuild reports whether a snapshot can be built from s, and what it
+// holds.
+func checkBuild(s snapshot.Reader) checkResult {
+	res := checkResult{Name: "snapshot"}
+	snap, err := snapshot.BuildWithTimeout(s, snapshotTimeout, staleAfter)
+	if err != nil {
+		res.Detail = err.Error()
+		res.Hint = "the file may not be a clockmail database, or its schema is from an incompatible clockmail version"
+		return res
+	}
+	res.OK = true
+	res.Detail = fmt.Sprintf("%d agents, %d events, %d locks", len(snap.Agents), snap.TotalEvents, len(snap.Locks))
+	return res
+}
+
+// checkWatch reports whether file events arrive from dir, by creating a
+// probe file there and waiting up to timeout for its event. Without them
+// cmv still refreshes, but only every --refresh.
+func checkWatch(dir string, timeout time.Duration) checkResult {
+	res := checkResult{
+		Name: "watch",
+		Hint: "live updates fall back to polling every --refresh; on Linux, raise fs.inotify.max_user_watches or max_user_instances, and avoid network filesystems",
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	defer w.Close()
+	if err := w.Add(dir); err != nil {
+		res.Detail = fmt.Sprintf("watch %s: %v", dir, err)
+		return res
+	}
+
+	probe, err := os.CreateTemp(dir, ".cmv-doctor-*")
+	if err != nil {
+		res.Detail = fmt.Sprintf("create probe file: %v", err)
+		return res
+	}
+	probe.Close()
+	defer os.Remove(probe.Name())
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				res.Detail = "watcher closed"
+				return res
+			}
+			if filepath.Base(ev.Name) == filepath.Base(probe.Name()) {
+				res.OK, res.Detail = true, dir
+				return res
+			}
+		case err := <-w.Errors:
+			res.Detail = err.Error()
+			return res
+		case <-deadline:
+			res.Detail = fmt.Sprintf("no event for a new file in %s within %s", dir, timeout)
+			return res
+		}
+	}
+}
+
+// writeDoctorReport prints one PASS/FAIL line per check, with the hint
+// under each failure, and reports whether every check passed.
+func writeDoctorReport(w io.Writer, results []checkResult) bool {
+	allOK := true
+	for _, r := range results {
+		status := safeStyle.Render("PASS")
+		if !r.OK {
+			status = unsafeStyle.Render("FAIL")
+			allOK = false
+		}
+		fmt.Fprintf(w, "%s  %-9s %s\n", status, r.Name, r.Detail)
+		if !r.OK && r.Hint != "" {
+			fmt.Fprintf(w, "      %s\n", dimStyle.Render("hint: "+r.Hint))
+		}
+	}
+	return allOK
+}
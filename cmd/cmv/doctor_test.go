@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDiscoverFindsDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clockmail.db")
+	s, res := checkOpen(path)
+	if !res.OK {
+		t.Fatalf("checkOpen = %+v", res)
+	}
+	s.Close()
+
+	t.Setenv("CLOCKMAIL_DB", path)
+	got, res := checkDiscover()
+	if !res.OK || got != path || res.Detail != path {
+		t.Errorf("checkDiscover = %q, %+v; want OK at %s", got, res, path)
+	}
+}
+
+func TestCheckDiscoverNoDB(t *testing.T) {
+	t.Setenv("CLOCKMAIL_DB", "")
+	t.Setenv("CLOCKMAIL_DB_NAME", "")
+	t.Chdir(t.TempDir())
+
+	_, res := checkDiscover()
+	if res.OK {
+		t.Fatalf("checkDiscover succeeded with no database: %+v", res)
+	}
+	if res.Hint == "" || !strings.Contains(res.Detail, "no clockmail database found") {
+		t.Errorf("failure should explain and hint: %+v", res)
+	}
+}
+
+func TestCheckBuild(t *testing.T) {
+	s := newTestStore(t)
+	insertMsg(t, s, "alice", "bob", "hello", 1)
+	res := checkBuild(s)
+	if !res.OK || !strings.Contains(res.Detail, "1 events") {
+		t.Errorf("checkBuild = %+v", res)
+	}
+}
+
+func TestCheckWatch(t *testing.T) {
+	dir := t.TempDir()
+	if res := checkWatch(dir, 5*time.Second); !res.OK {
+		t.Errorf("checkWatch(%s) = %+v", dir, res)
+	}
+	if res := checkWatch(filepath.Join(dir, "missing"), time.Second); res.OK || res.Hint == "" {
+		t.Errorf("checkWatch on a missing dir = %+v, want a failure with a hint", res)
+	}
+}
+
+func TestWriteDoctorReport(t *testing.T) {
+	var buf bytes.Buffer
+	ok := writeDoctorReport(&buf, []checkResult{
+		{Name: "discover", OK: true, Detail: "/x/clockmail.db"},
+		{Name: "open", Detail: "permission denied", Hint: "check permissions"},
+	})
+	out := stripAnsi(buf.String())
+	if ok {
+		t.Error("report with a failure should not pass")
+	}
+	for _, want := range []string{"PASS  discover  /x/clockmail.db", "FAIL  open      permission denied", "hint: check permissions"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report lacks %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail/pkg/store"
+)
+
+// eventLogBatch is how many events are fetched per query when catching up.
+const eventLogBatch = 500
+
+// eventLog appends every newly observed event to a file for auditing.
+//
+// Events are read incrementally by row ID (ListEventsSinceID), so nothing is
+// missed between refreshes. On open, the ID of the last logged line is read
+// back so a restarted cmv resumes where the previous run stopped instead of
+// writing duplicates.
+type eventLog struct {
+	mu     sync.Mutex
+	f      *os.File
+	lastID int64
+	err    error // first write/query failure, reported on exit
+}
+
+// openEventLog opens (or creates) the log file at path in append mode and
+// positions the cursor after the last logged event.
+func openEventLog(path string) (*eventLog, error) {
+	lastID, err := lastLoggedID(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+	return &eventLog{f: f, lastID: lastID}, nil
+}
+
+// appendNew writes all events with an ID greater than the cursor and
+// returns how many lines were appended.
+func (l *eventLog) appendNew(s *store.Store) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var n int
+	for {
+		events, err := s.ListEventsSinceID(l.lastID, eventLogBatch)
+		if err != nil {
+			return n, err
+		}
+		if len(events) == 0 {
+			return n, nil
+		}
+		var b strings.Builder
+		for _, e := range events {
+			b.WriteString(formatEventLine(e))
+			b.WriteRune('\n')
+		}
+		if _, err := io.WriteString(l.f, b.String()); err != nil {
+			return n, fmt.Errorf("write log file: %w", err)
+		}
+		l.lastID = events[len(events)-1].ID
+		n += len(events)
+		if len(events) < eventLogBatch {
+			return n, nil
+		}
+	}
+}
+
+// sync appends new events, remembering the first failure instead of
+// returning it. Used from the watcher goroutines where there is no one to
+// report to while the TUI owns the terminal.
+func (l *eventLog) sync(s *store.Store) {
+	if _, err := l.appendNew(s); err != nil {
+		l.mu.Lock()
+		if l.err == nil {
+			l.err = err
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Err returns the first failure recorded by sync, if any.
+func (l *eventLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Close closes the underlying file.
+func (l *eventLog) Close() error {
+	return l.f.Close()
+}
+
+// formatEventLine renders an event as a single plain-text line:
+//
+//	#42 2026-01-02T15:04:05Z [L:17] alice -> bob: hello
+//
+// The leading "#<id>" is what lastLoggedID parses to resume. Newlines in
+// message bodies are escaped so each event stays on one line.
+func formatEventLine(e model.Event) string {
+	var detail string
+	switch e.Kind {
+	case model.EventMsg:
+		detail = fmt.Sprintf("-> %s: %s", e.Target, strings.ReplaceAll(e.Body, "\n", `\n`))
+	case model.EventLockReq:
+		detail = "lock " + e.Target
+	case model.EventLockRel:
+		detail = "unlock " + e.Target
+	case model.EventProgress:
+		detail = fmt.Sprintf("heartbeat e%d/r%d", e.Epoch, e.Round)
+	default:
+		detail = strings.TrimSpace(fmt.Sprintf("%s %s %s", e.Kind, e.Target,
+			strings.ReplaceAll(e.Body, "\n", `\n`)))
+	}
+	return fmt.Sprintf("#%d %s [L:%d] %s %s",
+		e.ID, e.CreatedAt.Format(time.RFC3339), e.LamportTS, e.AgentID, detail)
+}
+
+// lastLoggedID returns the event ID on the last line of the log file at
+// path, or 0 if the file does not exist or holds no parseable lines.
+func lastLoggedID(path string) (int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read log file: %w", err)
+	}
+	defer f.Close()
+
+	var last int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if id, ok := parseLoggedID(line); ok {
+			last = id
+		}
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read log file: %w", err)
+		}
+	}
+}
+
+// parseLoggedID extracts the "#<id>" prefix written by formatEventLine.
+func parseLoggedID(line string) (int64, bool) {
+	if !strings.HasPrefix(line, "#") {
+		return 0, false
+	}
+	field, _, _ := strings.Cut(line[1:], " ")
+	id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
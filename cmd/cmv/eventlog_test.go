@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail/pkg/store"
+)
+
+// newTestStore creates a temporary clockmail store for testing.
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	s, err := store.New(filepath.Join(t.TempDir(), "clockmail.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// insertMsg appends a message event to the store.
+func insertMsg(t *testing.T, s *store.Store, from, to, body string, ts int64) {
+	t.Helper()
+	e := &model.Event{AgentID: from, LamportTS: ts, Kind: model.EventMsg, Target: to, Body: body, CreatedAt: time.Now()}
+	if _, err := s.InsertEvent(e); err != nil {
+		t.Fatalf("InsertEvent: %v", err)
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+func TestEventLogAppendsNewEvents(t *testing.T) {
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	insertMsg(t, s, "alice", "bob", "hello", 1)
+
+	l, err := openEventLog(path)
+	if err != nil {
+		t.Fatalf("openEventLog: %v", err)
+	}
+	defer l.Close()
+
+	if n, err := l.appendNew(s); err != nil || n != 1 {
+		t.Fatalf("appendNew = %d, %v; want 1, nil", n, err)
+	}
+
+	// Events inserted during the session are appended on the next sync.
+	insertMsg(t, s, "bob", "alice", "hi\nback", 2)
+	insertMsg(t, s, "alice", "bob", "again", 3)
+	if n, err := l.appendNew(s); err != nil || n != 2 {
+		t.Fatalf("appendNew = %d, %v; want 2, nil", n, err)
+	}
+	// Nothing new: nothing appended.
+	if n, err := l.appendNew(s); err != nil || n != 0 {
+		t.Fatalf("appendNew = %d, %v; want 0, nil", n, err)
+	}
+
+	lines := readLines(t, path)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), lines)
+	}
+	if !strings.HasPrefix(lines[0], "#1 ") || !strings.Contains(lines[0], "alice -> bob: hello") {
+		t.Errorf("unexpected first line: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `hi\nback`) {
+		t.Errorf("body newline should be escaped: %q", lines[1])
+	}
+}
+
+func TestEventLogResumesWithoutDuplicates(t *testing.T) {
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	insertMsg(t, s, "alice", "bob", "one", 1)
+	insertMsg(t, s, "bob", "alice", "two", 2)
+
+	l, err := openEventLog(path)
+	if err != nil {
+		t.Fatalf("openEventLog: %v", err)
+	}
+	if _, err := l.appendNew(s); err != nil {
+		t.Fatalf("appendNew: %v", err)
+	}
+	l.Close()
+
+	// Simulate a restart: new events arrive while cmv is down.
+	insertMsg(t, s, "alice", "bob", "three", 3)
+
+	l2, err := openEventLog(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer l2.Close()
+	if l2.lastID != 2 {
+		t.Errorf("expected resume cursor 2, got %d", l2.lastID)
+	}
+	if n, err := l2.appendNew(s); err != nil || n != 1 {
+		t.Fatalf("appendNew after restart = %d, %v; want 1, nil", n, err)
+	}
+
+	lines := readLines(t, path)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines without duplicates, got %d: %q", len(lines), lines)
+	}
+	for i, want := range []string{"#1 ", "#2 ", "#3 "} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], want)
+		}
+	}
+}
+
+func TestLastLoggedIDMissingFile(t *testing.T) {
+	id, err := lastLoggedID(filepath.Join(t.TempDir(), "nope.log"))
+	if err != nil || id != 0 {
+		t.Errorf("lastLoggedID(missing) = %d, %v; want 0, nil", id, err)
+	}
+}
+
+func TestFormatEventLine(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		e    model.Event
+		want string
+	}{
+		{model.Event{ID: 7, AgentID: "alice", LamportTS: 3, Kind: model.EventMsg, Target: "bob", Body: "hi", CreatedAt: ts},
+			"#7 2026-01-02T15:04:05Z [L:3] alice -> bob: hi"},
+		{model.Event{ID: 8, AgentID: "alice", LamportTS: 4, Kind: model.EventLockReq, Target: "main.go", CreatedAt: ts},
+			"#8 2026-01-02T15:04:05Z [L:4] alice lock main.go"},
+		{model.Event{ID: 9, AgentID: "bob", LamportTS: 5, Kind: model.EventProgress, Epoch: 1, Round: 2, CreatedAt: ts},
+			"#9 2026-01-02T15:04:05Z [L:5] bob heartbeat e1/r2"},
+	}
+	for _, tt := range tests {
+		if got := formatEventLine(tt.e); got != tt.want {
+			t.Errorf("formatEventLine = %q, want %q", got, tt.want)
+		}
+		if id, ok := parseLoggedID(formatEventLine(tt.e)); !ok || id != tt.e.ID {
+			t.Errorf("parseLoggedID round-trip = %d, %v; want %d", id, ok, tt.e.ID)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// runExport is the headless --export-interval loop: it writes the --json
+// document to output on startup, on every database change, and at least
+// once per interval (the polling fallback), until stop is closed. Only a
+// failed first export is returned; later failures (say, a busy database)
+// are reported to errs and the next change or interval retries.
+func runExport(s snapshot.Reader, changes <-chan struct{}, output string, interval time.Duration, stop <-chan struct{}, errs io.Writer) error {
+	export := func() error {
+		snap, err := snapshot.BuildWithTimeout(s, snapshotTimeout, staleAfter)
+		if err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		return writeJSONAtomic(output, buildJSONOutput(snap, jsonOptions{}))
+	}
+
+	if err := export(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil // watcher closed: keep polling
+				continue
+			}
+		case <-ticker.C:
+		}
+		if err := export(); err != nil {
+			fmt.Fprintf(errs, "cmv: %v\n", err)
+		}
+	}
+}
+
+// writeJSONAtomic encodes v as indented JSON into a temp file next to path
+// and renames it into place, so readers never observe a partial document.
+func writeJSONAtomic(path string, v any) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		tmp.Close()
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
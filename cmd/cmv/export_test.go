@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// readExport decodes the export file, failing the test on invalid JSON.
+func readExport(t *testing.T, path string) jsonOutput {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var out jsonOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("export is not valid JSON: %v\n%s", err, data)
+	}
+	return out
+}
+
+// waitForMessages polls the export file until it holds n messages.
+func waitForMessages(t *testing.T, path string, n int) jsonOutput {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			if out := readExport(t, path); len(out.Messages) == n {
+				return out
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("export never reached %d messages", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunExportWritesEachInterval(t *testing.T) {
+	s := newTestStore(t)
+	output := filepath.Join(t.TempDir(), "state.json")
+	insertMsg(t, s, "alice", "bob", "one", 1)
+
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- runExport(s, nil, output, 20*time.Millisecond, stop, io.Discard) }()
+
+	waitForMessages(t, output, 1)
+
+	// No watcher here: the interval fallback must pick up new events.
+	insertMsg(t, s, "bob", "alice", "two", 2)
+	waitForMessages(t, output, 2)
+	insertMsg(t, s, "alice", "bob", "three", 3)
+	out := waitForMessages(t, output, 3)
+	if out.Stats.TotalEvents != 3 {
+		t.Errorf("total_events = %d, want 3", out.Stats.TotalEvents)
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatalf("runExport: %v", err)
+	}
+
+	// Only the output file remains; temp files were renamed away.
+	entries, _ := os.ReadDir(filepath.Dir(output))
+	if len(entries) != 1 {
+		t.Errorf("expected only the output file, found %d entries", len(entries))
+	}
+}
+
+func TestRunExportOnChange(t *testing.T) {
+	s := newTestStore(t)
+	output := filepath.Join(t.TempDir(), "state.json")
+
+	changes := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	// An hour-long interval: only the change notification can trigger a write.
+	go func() { done <- runExport(s, changes, output, time.Hour, stop, io.Discard) }()
+
+	waitForMessages(t, output, 0)
+	insertMsg(t, s, "alice", "bob", "hello", 1)
+	changes <- struct{}{}
+	waitForMessages(t, output, 1)
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatalf("runExport: %v", err)
+	}
+}
+
+// flakyReader fails ListAgents while failing is set, like a database that
+// is briefly busy.
+type flakyReader struct {
+	snapshot.Reader
+	failing atomic.Bool
+}
+
+func (r *flakyReader) ListAgents() ([]model.Agent, error) {
+	if r.failing.Load() {
+		return nil, errors.New("database is locked")
+	}
+	return r.Reader.ListAgents()
+}
+
+func TestRunExportSurvivesFailedBuild(t *testing.T) {
+	s := newTestStore(t)
+	r := &flakyReader{Reader: s}
+	output := filepath.Join(t.TempDir(), "state.json")
+
+	changes := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	var errs bytes.Buffer
+	go func() { done <- runExport(r, changes, output, time.Hour, stop, &errs) }()
+	waitForMessages(t, output, 0)
+
+	r.failing.Store(true)
+	changes <- struct{}{}
+	// The loop takes the next change only after the failed export, so it
+	// must have survived it.
+	changes <- struct{}{}
+	r.failing.Store(false)
+	insertMsg(t, s, "alice", "bob", "after", 1)
+	changes <- struct{}{}
+	waitForMessages(t, output, 1)
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatalf("runExport: %v", err)
+	}
+	if !strings.Contains(errs.String(), "database is locked") {
+		t.Errorf("failed build not reported: %q", errs.String())
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// RenderFrames drives m through msgs and returns the full View() frame
+// after each one, for golden-style tests of whole screens.
+//
+// Frames are deterministic for a fixed model: size comes from m (start from
+// testModel, or include a tea.WindowSizeMsg), and lastRefresh is pinned to
+// the moment each frame is rendered so the status bar always reads
+// "refreshed 0s ago". Commands returned by Update are not run; feed their
+// resulting messages (e.g. snapshotReadyMsg) explicitly instead. Don't send
+// the quit key: the test model has no store or watcher to close.
+func RenderFrames(m uiModel, msgs []tea.Msg) []string {
+	frames := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		updated, _ := m.Update(msg)
+		m = updated.(uiModel)
+		m.lastRefresh = time.Now()
+		frames = append(frames, m.View())
+	}
+	return frames
+}
+
+// keyMsg builds the tea.KeyMsg for a key name as shown in help text.
+func keyMsg(s string) tea.Msg {
+	switch s {
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestRenderFramesNavigation(t *testing.T) {
+	msgs := []tea.Msg{
+		keyMsg("tab"),   // Messages
+		keyMsg("tab"),   // Locks
+		keyMsg("t"),     // Timeline
+		keyMsg("d"),     // Dashboard
+		keyMsg("down"),  // select bob
+		keyMsg("enter"), // Agent Detail for bob
+	}
+	frames := RenderFrames(testModel(), msgs)
+	if len(frames) != len(msgs) {
+		t.Fatalf("got %d frames, want %d", len(frames), len(msgs))
+	}
+
+	want := []string{
+		"2 messages (0 filtered)",
+		"Active Locks",
+		"Event Timeline",
+		"Agents",
+		"> bob",
+		"Agent: bob",
+	}
+	for i, w := range want {
+		frame := stripAnsi(frames[i])
+		if !strings.Contains(frame, w) {
+			t.Errorf("frame %d: missing %q:\n%s", i, w, frame)
+		}
+		if !strings.Contains(frame, "refreshed 0s ago") {
+			t.Errorf("frame %d: status bar not pinned:\n%s", i, frame)
+		}
+		if n := strings.Count(frame, "\n") + 1; n > 24 {
+			t.Errorf("frame %d: %d lines exceed the 24-line terminal", i, n)
+		}
+	}
+}
+
+func TestRenderFramesDeterministic(t *testing.T) {
+	m := testModel()
+	msgs := []tea.Msg{tea.WindowSizeMsg{Width: 100, Height: 30}, keyMsg("m"), keyMsg("down")}
+	a := RenderFrames(m, msgs)
+	b := RenderFrames(m, msgs)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("frame %d differs between identical runs:\n%s\n---\n%s", i, a[i], b[i])
+		}
+	}
+}
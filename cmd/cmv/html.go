@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// htmlWidth is the terminal width --html renders at.
+const htmlWidth = 120
+
+// htmlPage holds the page colors for each theme, matching the Catppuccin
+// base and text colors the styles are drawn against.
+var htmlPage = map[Theme]struct{ bg, fg string }{
+	ThemeDark:  {"#1e1e2e", "#cdd6f4"},
+	ThemeLight: {"#eff1f5", "#4c4f69"},
+}
+
+// renderHTML renders view from snap, as the TUI would draw it without
+// scrolling, and returns it as a self-contained HTML document. agentID
+// picks the agent for the Agent Detail view (default: the first agent).
+func renderHTML(snap *snapshot.DataSnapshot, view viewID, agentID, dbPath string, t Theme) string {
+	m := newModel(nil, nil, snap, dbPath)
+	m.width, m.height = htmlWidth, 1<<16
+	m.activeView = view
+	m.detailAgentID = agentID
+	if m.detailAgentID == "" && len(m.snap.Agents) > 0 {
+		m.detailAgentID = m.snap.Agents[0].ID
+	}
+	out := m.renderTitleBar() + "\n\n" + m.viewContent()
+
+	page := htmlPage[t]
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\"/>\n")
+	fmt.Fprintf(&b, "<title>cmv %s: %s</title>\n", html.EscapeString(view.String()), html.EscapeString(dbPath))
+	b.WriteString("</head>\n")
+	fmt.Fprintf(&b, "<body style=\"margin:0;background:%s;color:%s\">\n", page.bg, page.fg)
+	b.WriteString("<pre style=\"margin:0;padding:1em;font-family:ui-monospace,Menlo,Consolas,monospace\">")
+	b.WriteString(ansiToHTML(out))
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// sgrState is the text style selected by ANSI SGR sequences so far.
+type sgrState struct {
+	fg, bg                              string // CSS colors, "" for default
+	bold, faint, italic, underline, rev bool
+}
+
+// css returns the inline style for s, or "" for unstyled text.
+func (s sgrState) css() string {
+	fg, bg := s.fg, s.bg
+	if s.rev {
+		fg, bg = bg, fg
+		if fg == "" {
+			fg = "inherit"
+		}
+	}
+	var parts []string
+	if fg != "" {
+		parts = append(parts, "color:"+fg)
+	}
+	if bg != "" {
+		parts = append(parts, "background-color:"+bg)
+	}
+	if s.bold {
+		parts = append(parts, "font-weight:bold")
+	}
+	if s.faint {
+		parts = append(parts, "opacity:0.6")
+	}
+	if s.italic {
+		parts = append(parts, "font-style:italic")
+	}
+	if s.underline {
+		parts = append(parts, "text-decoration:underline")
+	}
+	return strings.Join(parts, ";")
+}
+
+// apply updates s for the parameters of one SGR sequence.
+func (s sgrState) apply(params []int) sgrState {
+	if len(params) == 0 {
+		return sgrState{}
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			s = sgrState{}
+		case p == 1:
+			s.bold = true
+		case p == 2:
+			s.faint = true
+		case p == 3:
+			s.italic = true
+		case p == 4:
+			s.underline = true
+		case p == 7:
+			s.rev = true
+		case p == 22:
+			s.bold, s.faint = false, false
+		case p == 23:
+			s.italic = false
+		case p == 24:
+			s.underline = false
+		case p == 27:
+			s.rev = false
+		case p >= 30 && p <= 37:
+			s.fg = xtermColor(p - 30)
+		case p >= 90 && p <= 97:
+			s.fg = xtermColor(p - 90 + 8)
+		case p == 39:
+			s.fg = ""
+		case p >= 40 && p <= 47:
+			s.bg = xtermColor(p - 40)
+		case p >= 100 && p <= 107:
+			s.bg = xtermColor(p - 100 + 8)
+		case p == 49:
+			s.bg = ""
+		case p == 38 || p == 48:
+			var c string
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				c = xtermColor(params[i+2])
+				i += 2
+			case i+4 < len(params) && params[i+1] == 2:
+				c = fmt.Sprintf("#%02x%02x%02x", params[i+2]&0xff, params[i+3]&0xff, params[i+4]&0xff)
+				i += 4
+			default:
+				i = len(params) // malformed: ignore the rest
+				continue
+			}
+			if p == 38 {
+				s.fg = c
+			} else {
+				s.bg = c
+			}
+		}
+	}
+	return s
+}
+
+// ansi16 is the xterm palette for the 16 basic colors.
+var ansi16 = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// xtermColor returns the CSS color for xterm 256-color index n.
+func xtermColor(n int) string {
+	switch {
+	case n < 0 || n > 255:
+		return ""
+	case n < 16:
+		return ansi16[n]
+	case n < 232:
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		n -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[n/6%6], levels[n%6])
+	}
+	g := 8 + 10*(n-232)
+	return fmt.Sprintf("#%02x%02x%02x", g, g, g)
+}
+
+// ansiToHTML converts ANSI-styled text to HTML: SGR styles become inline
+// <span style> elements, text is escaped, and every other escape sequence
+// is dropped. Spans never cross a newline, so each line stands alone.
+func ansiToHTML(s string) string {
+	var b strings.Builder
+	var cur sgrState
+	open := false
+	closeSpan := func() {
+		if open {
+			b.WriteString("</span>")
+			open = false
+		}
+	}
+	text := func(t string) {
+		if t == "" {
+			return
+		}
+		if !open {
+			if css := cur.css(); css != "" {
+				fmt.Fprintf(&b, "<span style=\"%s\">", css)
+				open = true
+			}
+		}
+		b.WriteString(html.EscapeString(t))
+	}
+
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "\x1b\n")
+		if i < 0 {
+			text(s)
+			break
+		}
+		text(s[:i])
+		s = s[i:]
+		if s[0] == '\n' {
+			closeSpan()
+			b.WriteByte('\n')
+			s = s[1:]
+			continue
+		}
+		seq, rest := splitEscape(s)
+		s = rest
+		if params, ok := strings.CutPrefix(seq, "\x1b["); ok && strings.HasSuffix(params, "m") {
+			next := cur.apply(parseSGR(strings.TrimSuffix(params, "m")))
+			if next != cur {
+				closeSpan()
+				cur = next
+			}
+		}
+	}
+	closeSpan()
+	return b.String()
+}
+
+// splitEscape splits the escape sequence at the start of s from the rest.
+// CSI sequences end at their final byte and OSC sequences at BEL or ST;
+// anything else is taken as a two-byte escape.
+func splitEscape(s string) (seq, rest string) {
+	if len(s) < 2 {
+		return s, ""
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return s[:i+1], s[i+1:]
+			}
+		}
+		return s, ""
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return s[:i+1], s[i+1:]
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return s[:i+2], s[i+2:]
+			}
+		}
+		return s, ""
+	}
+	return s[:2], s[2:]
+}
+
+// parseSGR parses the ;-separated parameters of an SGR sequence. An empty
+// parameter counts as 0.
+func parseSGR(params string) []int {
+	if params == "" {
+		return nil
+	}
+	fields := strings.Split(params, ";")
+	out := make([]int, len(fields))
+	for i, f := range fields {
+		out[i], _ = strconv.Atoi(f)
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestRenderHTMLDashboard(t *testing.T) {
+	prev := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	defer lipgloss.SetColorProfile(prev)
+
+	page := renderHTML(testSnapshot(), viewDashboard, "", "cm.db", ThemeDark)
+
+	for _, id := range []string{"alice", "bob"} {
+		span := regexp.MustCompile(`<span style="[^"]*color:#[0-9a-f]{6}[^"]*">[^<]*\b` + id + `\b`)
+		if !span.MatchString(page) {
+			t.Errorf("%s is not inside a colored span:\n%s", id, page)
+		}
+	}
+	if strings.Contains(page, "\x1b") {
+		t.Error("page still contains escape sequences")
+	}
+
+	// Well-formed: every element closes, in order.
+	dec := xml.NewDecoder(strings.NewReader(page))
+	dec.Strict = true
+	for {
+		if _, err := dec.Token(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("page is not well-formed: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestAnsiToHTML(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain <b> & text", "plain &lt;b&gt; &amp; text"},
+		{"\x1b[1;38;2;255;0;128mhot\x1b[0m cold", `<span style="color:#ff0080;font-weight:bold">hot</span> cold`},
+		{"\x1b[31mred\nstill\x1b[39m", "<span style=\"color:#cd0000\">red</span>\n<span style=\"color:#cd0000\">still</span>"},
+		{"\x1b[38;5;196mx\x1b[m", `<span style="color:#ff0000">x</span>`},
+		{"\x1b]8;;http://x\x1b\\link\x1b[2K", "link"},
+	}
+	for _, tt := range tests {
+		if got := ansiToHTML(tt.in); got != tt.want {
+			t.Errorf("ansiToHTML(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail_viewer/internal/datasource"
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// clearScreen homes the cursor and clears the terminal, as watch(1) does
+// between frames.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// runLocks implements `cmv locks`: print a table of held locks and exit,
+// or with --watch redraw it on every change until interrupted. It returns
+// the process exit code.
+func runLocks(args []string) int {
+	fs := flag.NewFlagSet("locks", flag.ContinueOnError)
+	dbPath := fs.String("db", "", "path to clockmail.db (default: auto-discover)")
+	dbName := fs.String("db-name", "", "database file name to discover in .clockmail/ (default: clockmail.db)")
+	noColor := fs.Bool("no-color", false, "disable colored output")
+	watch := fs.Bool("watch", false, "clear and redraw the table on every change until interrupted")
+	refresh := fs.Duration("refresh", 2*time.Second, "with --watch, redraw at least this often")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *noColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	if *dbPath != "" {
+		os.Setenv("CLOCKMAIL_DB", *dbPath)
+	}
+	if *dbName != "" {
+		os.Setenv("CLOCKMAIL_DB_NAME", *dbName)
+	}
+
+	s, path, err := datasource.Open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+		return 1
+	}
+	defer s.Close()
+
+	if !*watch {
+		snap, err := snapshot.Build(s, staleAfter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: snapshot: %v\n", err)
+			return 1
+		}
+		if err := formatLockTable(os.Stdout, snap.Locks, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	w, err := datasource.NewWatcher(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: watch: %v\n", err)
+		return 1
+	}
+	defer w.Close()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	stop := make(chan struct{})
+	go func() {
+		<-sig
+		close(stop)
+	}()
+	if err := watchLocks(os.Stdout, s, w.Changes(), *refresh, stop); err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// watchLocks redraws the lock table on startup, on every database change,
+// and at least once per interval (the polling fallback), until stop is
+// closed. A failed build is shown in place of the table and retried on the
+// next redraw.
+func watchLocks(w io.Writer, s snapshot.Reader, changes <-chan struct{}, interval time.Duration, stop <-chan struct{}) error {
+	draw := func() error {
+		now := time.Now()
+		fmt.Fprintf(w, "%scmv locks — %s (every %s; ctrl+c to quit)\n\n",
+			clearScreen, now.Local().Format("15:04:05"), interval)
+		snap, err := snapshot.BuildWithTimeout(s, snapshotTimeout, staleAfter)
+		if err != nil {
+			_, werr := fmt.Fprintf(w, "snapshot: %v\n", err)
+			return werr
+		}
+		return formatLockTable(w, snap.Locks, now)
+	}
+
+	if err := draw(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil // watcher closed: keep polling
+				continue
+			}
+		case <-ticker.C:
+		}
+		if err := draw(); err != nil {
+			return err
+		}
+	}
+}
+
+// formatLockTable writes locks as an aligned table sorted by path, then
+// holder. TTL is the time left before the lock expires.
+func formatLockTable(w io.Writer, locks []model.Lock, now time.Time) error {
+	sorted := make([]model.Lock, len(locks))
+	copy(sorted, locks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].AgentID < sorted[j].AgentID
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tHOLDER\tTTL\tMODE")
+	for _, l := range sorted {
+		mode := "shared"
+		if l.Exclusive {
+			mode = "excl"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", l.Path, l.AgentID, shortDuration(l.ExpiresAt.Sub(now)), mode)
+	}
+	if len(sorted) == 0 {
+		fmt.Fprintln(tw, "(no locks held)")
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+func TestFormatLockTableFromStore(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.RegisterAgent("alice"); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if _, _, err := s.AcquireLock("main.go", "alice", 1, 0, true, time.Hour); err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	snap, err := snapshot.Build(s, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := formatLockTable(&buf, snap.Locks, time.Now()); err != nil {
+		t.Fatalf("formatLockTable: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header + 1 row, got %d lines:\n%s", len(lines), buf.String())
+	}
+	if f := strings.Fields(lines[0]); strings.Join(f, " ") != "PATH HOLDER TTL MODE" {
+		t.Errorf("header = %q", lines[0])
+	}
+	f := strings.Fields(lines[1])
+	if len(f) != 4 || f[0] != "main.go" || f[1] != "alice" || f[3] != "excl" {
+		t.Errorf("row = %q, want main.go held exclusively by alice", lines[1])
+	}
+	if !strings.HasPrefix(f[2], "59m") && !strings.HasPrefix(f[2], "1h") {
+		t.Errorf("TTL = %q, want about an hour", f[2])
+	}
+}
+
+func TestFormatLockTableSortedAndEmpty(t *testing.T) {
+	now := time.Now()
+	locks := []model.Lock{
+		{Path: "z.go", AgentID: "bob", ExpiresAt: now.Add(time.Minute)},
+		{Path: "a.go", AgentID: "carol", ExpiresAt: now.Add(-time.Second)},
+	}
+	var buf bytes.Buffer
+	formatLockTable(&buf, locks, now)
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(lines[1], "a.go") || !strings.Contains(lines[1], "expired") || !strings.Contains(lines[1], "shared") {
+		t.Errorf("first row = %q, want a.go expired shared", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "z.go") {
+		t.Errorf("second row = %q, want z.go", lines[2])
+	}
+
+	buf.Reset()
+	formatLockTable(&buf, nil, now)
+	if !strings.Contains(buf.String(), "(no locks held)") {
+		t.Errorf("empty table = %q", buf.String())
+	}
+}
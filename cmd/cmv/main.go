@@ -8,26 +8,56 @@
 //	cmv                         # Auto-discover .clockmail/clockmail.db
 //	cmv --db <path>             # Use specific database path
 //	cmv --json                  # Dump current state as JSON and exit
+//	cmv --json --strict         # ... and fail on integrity problems
+//	cmv --json --agent <id>     # Dump one agent's detail as JSON and exit
+//	cmv --query 'messages from alice since 1h'
+//	                            # Print matching events and exit
+//	cmv --export-interval 10s --output state.json
+//	                            # Keep state.json updated (headless)
+//	cmv --serve :8080           # Serve /snapshot and /healthz (headless)
+//	cmv --html dashboard --output out.html
+//	                            # Render a view as an HTML page and exit
 //	cmv --agent <id>            # Focus on a specific agent on startup
 //	cmv --view dashboard        # Start in a specific view
 //	cmv --refresh 5s            # Set polling fallback interval
+//	cmv --log-file events.log   # Append every observed event to a file
+//	cmv --config cmv.json       # Load settings (e.g. staleness rules)
+//	cmv --crash-report          # On a panic, print the last frame and stack
 //	cmv --version               # Print version and exit
+//	cmv agents [--no-color]     # Print an agent table and exit
+//	cmv locks [--watch]         # Print a lock table (and keep redrawing it)
+//	cmv doctor                  # Check discovery, access, and file watching
 package main
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
 
+	"github.com/daviddao/clockmail/pkg/frontier"
 	"github.com/daviddao/clockmail/pkg/model"
 	"github.com/daviddao/clockmail/pkg/store"
 	"github.com/daviddao/clockmail_viewer/internal/datasource"
@@ -38,22 +68,35 @@ import (
 var Version = "dev"
 
 // parseViewFlag maps a --view flag string to a viewID.
+// It accepts a view's name, its shortcut key, or any of its aliases.
 func parseViewFlag(s string) (viewID, error) {
-	switch strings.ToLower(s) {
-	case "dashboard", "d":
-		return viewDashboard, nil
-	case "messages", "m":
-		return viewMessages, nil
-	case "locks", "l":
-		return viewLocks, nil
-	case "frontier", "f":
-		return viewFrontier, nil
-	case "timeline", "t":
-		return viewTimeline, nil
-	case "diagram", "s":
-		return viewDiagram, nil
-	default:
-		return 0, fmt.Errorf("unknown view %q (valid: dashboard, messages, locks, frontier, timeline, diagram)", s)
+	name := strings.ToLower(s)
+	for _, vi := range views {
+		if name == vi.name || name == vi.key || slices.Contains(vi.aliases, name) {
+			return vi.id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown view %q (valid: %s)", s, strings.Join(viewNames(), ", "))
+}
+
+// viewNames returns the --view name of every view, in tab order.
+func viewNames() []string {
+	names := make([]string, len(views))
+	for i, vi := range views {
+		names[i] = vi.name
+	}
+	return names
+}
+
+// listViews writes one line per view for --list-views: name, shortcut key
+// and aliases.
+func listViews(w io.Writer) {
+	for _, vi := range views {
+		line := fmt.Sprintf("%-10s %s", vi.name, vi.key)
+		if len(vi.aliases) > 0 {
+			line += "  (also: " + strings.Join(vi.aliases, ", ") + ")"
+		}
+		fmt.Fprintln(w, line)
 	}
 }
 
@@ -73,6 +116,10 @@ type jsonAgent struct {
 	Round        int64  `json:"round"`
 	LastSeen     string `json:"last_seen"`
 	Safe         bool   `json:"safe_to_finalize"`
+
+	// BlockedBy lists the pointstamps holding back this agent's frontier.
+	// Only filled with --json-blockers; omitted when empty.
+	BlockedBy []jsonPoint `json:"blocked_by,omitempty"`
 }
 
 type jsonLock struct {
@@ -95,6 +142,15 @@ type jsonMessage struct {
 	LamportTS int64  `json:"lamport_ts"`
 }
 
+// jsonAgentDetail is the structure for --json --agent: one agent's detail.
+// Sent and Received are newest first, as in the Agent Detail view.
+type jsonAgentDetail struct {
+	Agent    jsonAgent     `json:"agent"`
+	Locks    []jsonLock    `json:"locks_held"`
+	Sent     []jsonMessage `json:"sent"`
+	Received []jsonMessage `json:"received"`
+}
+
 type jsonStats struct {
 	ActiveAgents int `json:"active_agents"`
 	StaleAgents  int `json:"stale_agents"`
@@ -103,22 +159,90 @@ type jsonStats struct {
 }
 
 func main() {
+	// Subcommands are dispatched before flag parsing; each owns its flags.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "agents":
+			os.Exit(runAgents(os.Args[2:]))
+		case "locks":
+			os.Exit(runLocks(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		}
+	}
+
 	dbPath := flag.String("db", "", "path to clockmail.db (default: auto-discover)")
+	dbName := flag.String("db-name", "", "database file name to discover in .clockmail/ (default: clockmail.db)")
 	refreshDur := flag.Duration("refresh", 2*time.Second, "polling fallback interval")
 	jsonMode := flag.Bool("json", false, "dump current state as JSON and exit (no TUI)")
-	agentFlag := flag.String("agent", "", "highlight/focus a specific agent on startup")
-	viewFlag := flag.String("view", "", "start in specific view (dashboard|messages|locks|frontier|timeline)")
+	jsonBlockers := flag.Bool("json-blockers", false, "with --json, list the pointstamps blocking each agent's frontier")
+	strict := flag.Bool("strict", false, "with --json, exit nonzero if the snapshot fails integrity checks")
+	debugDump := flag.Bool("debug-dump", false, "print the raw result of each store query a snapshot is built from and exit")
+	query := flag.String("query", "", "print loaded events matching a query (e.g. 'messages from alice since 1h') and exit")
+	agentFlag := flag.String("agent", "", "highlight/focus a specific agent on startup; with --json, dump only that agent's detail")
+	viewFlag := flag.String("view", "", "start in specific view ("+strings.Join(viewNames(), "|")+")")
+	listViewsFlag := flag.Bool("list-views", false, "print the view names and their shortcut keys and exit")
 	versionFlag := flag.Bool("version", false, "print version and exit")
+	logFile := flag.String("log-file", "", "append every observed event to this file (resumes after restart)")
+	newWindow := flag.Duration("new-window", defaultNewAgentWindow, "flag agents registered within this window as NEW")
+	noColor := flag.Bool("no-color", false, "disable colored output")
+	themeFlag := flag.String("theme", "auto", "color theme: auto (detect terminal background), dark, or light")
+	utcFlag := flag.Bool("utc", false, "show wall-clock times in UTC instead of local time")
+	splitRatio := flag.Float64("split-ratio", defaultSplitRatio, "share of the width the Dashboard takes in split-pane mode (0.2-0.8)")
+	detailLimit := flag.Int("detail-limit", defaultDetailLimit, "show at most this many sent, received, and recent events per Agent Detail section (0 = all)")
+	diagramRows := flag.Int("diagram-rows", defaultDiagramRows, "show at most this many Lamport timestamp rows in the Diagram, newest first (0 = all)")
+	maxBody := flag.Int("max-body", defaultMaxBody, "clip message bodies longer than this many bytes until Enter expands them (0 = never)")
+	exportInterval := flag.Duration("export-interval", 0, "run headless, writing the --json document to --output on change and at least this often")
+	outputPath := flag.String("output", "", "output file for --export-interval or --html (default for --html: stdout)")
+	htmlView := flag.String("html", "", "render a view ("+strings.Join(viewNames(), "|")+") as a self-contained HTML page and exit")
+	serveAddr := flag.String("serve", "", "run headless, serving GET /snapshot and GET /healthz on this address (e.g. :8080)")
+	crashFlag := flag.Bool("crash-report", false, "on a panic, restore the terminal and print the last frame, the panic, and a stack trace to stderr")
+	configPath := flag.String("config", "", "path to config file (default: <user config dir>/cmv/config.json)")
 	flag.Parse()
 
+	if *noColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	themeAuto, theme, err := parseThemeFlag(*themeFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+		os.Exit(2)
+	}
 	if *versionFlag {
 		fmt.Printf("cmv %s\n", Version)
 		os.Exit(0)
 	}
 
+	if *listViewsFlag {
+		listViews(os.Stdout)
+		os.Exit(0)
+	}
+
+	// After the early exits, so a bad config file can't break --version.
+	if err := applyConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *splitRatio < 0.2 || *splitRatio > 0.8 {
+		fmt.Fprintln(os.Stderr, "cmv: --split-ratio must be between 0.2 and 0.8")
+		os.Exit(2)
+	}
+	if *exportInterval > 0 && *outputPath == "" {
+		fmt.Fprintln(os.Stderr, "cmv: --export-interval requires --output")
+		os.Exit(2)
+	}
+	if *exportInterval > 0 && *serveAddr != "" {
+		fmt.Fprintln(os.Stderr, "cmv: --export-interval and --serve are mutually exclusive")
+		os.Exit(2)
+	}
+
 	if *dbPath != "" {
 		os.Setenv("CLOCKMAIL_DB", *dbPath)
 	}
+	if *dbName != "" {
+		os.Setenv("CLOCKMAIL_DB_NAME", *dbName)
+	}
 
 	s, path, err := datasource.Open()
 	if err != nil {
@@ -126,22 +250,95 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --debug-dump mode: print what snapshot.Build would see, exit.
+	if *debugDump {
+		err := runDebugDump(os.Stdout, s)
+		s.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// --query mode: filter the snapshot's events, print them, exit.
+	if *query != "" {
+		snap, err := snapshot.Build(s, staleAfter)
+		s.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := runQuery(os.Stdout, snap.Events, *query); err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+			os.Exit(2)
+		}
+		os.Exit(0)
+	}
+
 	// --json mode: build snapshot, print JSON, exit.
 	if *jsonMode {
-		snap, err := snapshot.Build(s)
+		snap, err := snapshot.Build(s, staleAfter)
 		if err != nil {
 			s.Close()
 			fmt.Fprintf(os.Stderr, "cmv: snapshot: %v\n", err)
 			os.Exit(1)
 		}
 		s.Close()
-		out := buildJSONOutput(snap)
+		var out any = buildJSONOutput(snap, jsonOptions{Blockers: *jsonBlockers})
+		if *agentFlag != "" {
+			if out, err = buildAgentJSON(snap, *agentFlag, *detailLimit); err != nil {
+				fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+				os.Exit(1)
+			}
+		}
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		if err := enc.Encode(out); err != nil {
 			fmt.Fprintf(os.Stderr, "cmv: json: %v\n", err)
 			os.Exit(1)
 		}
+		issues := validateSnapshot(snap)
+		for _, issue := range issues {
+			fmt.Fprintf(os.Stderr, "cmv: integrity: %s\n", issue)
+		}
+		if *strict && len(issues) > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// --html mode: render one view to HTML, exit.
+	if *htmlView != "" {
+		v, err := parseViewFlag(*htmlView)
+		if err != nil {
+			s.Close()
+			fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+			os.Exit(2)
+		}
+		snap, err := snapshot.Build(s, staleAfter)
+		s.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		// No terminal to detect: auto means the dark theme, and colors are
+		// always emitted unless --no-color.
+		if !*noColor {
+			lipgloss.SetColorProfile(termenv.TrueColor)
+		}
+		applyTheme(themeStyles, theme)
+		applyColorEnv(themeStyles, os.Environ(), os.Stderr)
+		page := renderHTML(snap, v, *agentFlag, path, theme)
+		if *outputPath == "" {
+			_, err = io.WriteString(os.Stdout, page)
+		} else {
+			err = os.WriteFile(*outputPath, []byte(page), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: html: %v\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
@@ -152,7 +349,45 @@ func main() {
 		os.Exit(1)
 	}
 
-	snap, err := snapshot.Build(s)
+	// --export-interval mode: headless loop until SIGINT/SIGTERM.
+	if *exportInterval > 0 {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		stop := make(chan struct{})
+		go func() {
+			<-sig
+			close(stop)
+		}()
+		err := runExport(s, w.Changes(), *outputPath, *exportInterval, stop, os.Stderr)
+		w.Close()
+		s.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// --serve mode: headless HTTP server until SIGINT/SIGTERM.
+	if *serveAddr != "" {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		stop := make(chan struct{})
+		go func() {
+			<-sig
+			close(stop)
+		}()
+		err := runServe(*serveAddr, s, w.Changes(), *refreshDur, stop)
+		w.Close()
+		s.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	snap, err := snapshot.Build(s, staleAfter)
 	if err != nil {
 		w.Close()
 		s.Close()
@@ -160,8 +395,36 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --log-file: catch up on everything since the last logged event, then
+	// append incrementally on each change below.
+	var evLog *eventLog
+	if *logFile != "" {
+		evLog, err = openEventLog(*logFile)
+		if err == nil {
+			_, err = evLog.appendNew(s)
+		}
+		if err != nil {
+			w.Close()
+			s.Close()
+			fmt.Fprintf(os.Stderr, "cmv: log file: %v\n", err)
+			os.Exit(1)
+		}
+		defer evLog.Close()
+	}
+	logNew := func() {
+		if evLog != nil {
+			evLog.sync(s)
+		}
+	}
+
 	m := newModel(s, w, snap, path)
 	m.refreshInterval = *refreshDur
+	m.newAgentWindow = *newWindow
+	m.maxBody = *maxBody
+	m.diagramRows = *diagramRows
+	m.detailLimit = *detailLimit
+	m.splitRatio = *splitRatio
+	m.utc = *utcFlag
 
 	// Apply --view flag.
 	if *viewFlag != "" {
@@ -180,6 +443,7 @@ func main() {
 		for i, ag := range snap.Agents {
 			if ag.ID == *agentFlag {
 				m.selectedAgent = i
+				m.selectedAgentID = ag.ID
 				m.detailAgentID = ag.ID
 				m.activeView = viewAgentDetail
 				break
@@ -187,11 +451,29 @@ func main() {
 		}
 	}
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	// Pick the palette once, before the program owns the terminal; explicit
+	// CMV_COLOR_* overrides win over either theme.
+	applyTheme(themeStyles, resolveTheme(themeAuto, theme))
+	applyColorEnv(themeStyles, os.Environ(), os.Stderr)
+
+	// --crash-report wraps the model so a panic in Update or View quits
+	// cleanly and is reported below, once the terminal is restored.
+	var prog tea.Model = m
+	var crash *crashState
+	if *crashFlag {
+		g := newCrashGuard(m)
+		prog, crash = g, g.state
+	}
+	// Clipboard copies share the renderer's output so they can't garble a
+	// frame.
+	out := &syncOutput{File: os.Stdout}
+	copyToClipboard = termenv.NewOutput(out).Copy
+	p := tea.NewProgram(prog, tea.WithAltScreen(), tea.WithOutput(out))
 
 	// Feed DB change events into the TUI.
 	go func() {
 		for range w.Changes() {
+			logNew()
 			p.Send(dbChangedMsg{})
 		}
 	}()
@@ -201,6 +483,7 @@ func main() {
 		ticker := time.NewTicker(*refreshDur)
 		defer ticker.Stop()
 		for range ticker.C {
+			logNew()
 			p.Send(dbChangedMsg{})
 		}
 	}()
@@ -209,34 +492,34 @@ func main() {
 		fmt.Fprintf(os.Stderr, "cmv: %v\n", err)
 		os.Exit(1)
 	}
+	if crash != nil && crash.crash != nil {
+		writeCrashReport(os.Stderr, crash.crash)
+		os.Exit(1)
+	}
+	if evLog != nil {
+		if err := evLog.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "cmv: log file: %v\n", err)
+		}
+	}
+}
+
+// jsonOptions selects optional parts of the --json document. The zero
+// value produces the default output.
+type jsonOptions struct {
+	Blockers bool // --json-blockers: include each agent's blocked_by
 }
 
 // buildJSONOutput converts a snapshot into the JSON output structure.
-func buildJSONOutput(snap *snapshot.DataSnapshot) jsonOutput {
+func buildJSONOutput(snap *snapshot.DataSnapshot, opts jsonOptions) jsonOutput {
 	agents := make([]jsonAgent, len(snap.Agents))
 	for i, ag := range snap.Agents {
-		safe := false
-		if fs, ok := snap.FrontierStatus[ag.ID]; ok {
-			safe = fs.SafeToFinalize
-		}
-		agents[i] = jsonAgent{
-			ID:           ag.ID,
-			LamportClock: ag.Clock,
-			Epoch:        ag.Epoch,
-			Round:        ag.Round,
-			LastSeen:     ag.LastSeen.Format(time.RFC3339),
-			Safe:         safe,
-		}
+		fs, ok := snap.FrontierStatus[ag.ID]
+		agents[i] = toJSONAgent(ag, fs, ok, opts.Blockers)
 	}
 
 	locks := make([]jsonLock, len(snap.Locks))
 	for i, l := range snap.Locks {
-		locks[i] = jsonLock{
-			Path:      l.Path,
-			AgentID:   l.AgentID,
-			LamportTS: l.LamportTS,
-			ExpiresAt: l.ExpiresAt.Format(time.RFC3339),
-		}
+		locks[i] = toJSONLock(l)
 	}
 
 	points := make([]jsonPoint, len(snap.Frontier))
@@ -251,12 +534,7 @@ func buildJSONOutput(snap *snapshot.DataSnapshot) jsonOutput {
 	msgs := filterEvents(snap.Events, model.EventMsg)
 	messages := make([]jsonMessage, len(msgs))
 	for i, e := range msgs {
-		messages[i] = jsonMessage{
-			From:      e.AgentID,
-			To:        e.Target,
-			Body:      e.Body,
-			LamportTS: e.LamportTS,
-		}
+		messages[i] = toJSONMessage(e)
 	}
 
 	return jsonOutput{
@@ -273,6 +551,73 @@ func buildJSONOutput(snap *snapshot.DataSnapshot) jsonOutput {
 	}
 }
 
+// toJSONAgent converts an agent and its frontier status (known reports
+// whether there is one). Blockers are filled only when blockers is set.
+func toJSONAgent(ag model.Agent, fs frontier.FrontierStatus, known, blockers bool) jsonAgent {
+	out := jsonAgent{
+		ID:           ag.ID,
+		LamportClock: ag.Clock,
+		Epoch:        ag.Epoch,
+		Round:        ag.Round,
+		LastSeen:     ag.LastSeen.Format(time.RFC3339),
+		Safe:         known && fs.SafeToFinalize,
+	}
+	if blockers && known {
+		for _, bl := range fs.BlockedBy {
+			out.BlockedBy = append(out.BlockedBy, jsonPoint{
+				AgentID: bl.AgentID,
+				Epoch:   bl.Timestamp.Epoch,
+				Round:   bl.Timestamp.Round,
+			})
+		}
+	}
+	return out
+}
+
+func toJSONLock(l model.Lock) jsonLock {
+	return jsonLock{
+		Path:      l.Path,
+		AgentID:   l.AgentID,
+		LamportTS: l.LamportTS,
+		ExpiresAt: l.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+func toJSONMessage(e model.Event) jsonMessage {
+	return jsonMessage{
+		From:      e.AgentID,
+		To:        e.Target,
+		Body:      e.Body,
+		LamportTS: e.LamportTS,
+	}
+}
+
+// buildAgentJSON builds the --json --agent document: agent id's Agent
+// Detail view, with blockers always included. It fails if the agent is not
+// registered. limit caps the message lists as in the view (0 = all).
+func buildAgentJSON(snap *snapshot.DataSnapshot, id string, limit int) (jsonAgentDetail, error) {
+	d, ok := gatherAgentDetail(snap, id, limit)
+	if !ok {
+		return jsonAgentDetail{}, fmt.Errorf("agent %q not found", id)
+	}
+	out := jsonAgentDetail{
+		Agent:    toJSONAgent(d.Agent, d.Frontier, d.HasFront, true),
+		Locks:    make([]jsonLock, len(d.Locks)),
+		Sent:     make([]jsonMessage, len(d.Sent)),
+		Received: make([]jsonMessage, len(d.Received)),
+	}
+	for i, l := range d.Locks {
+		out.Locks[i] = toJSONLock(l)
+	}
+	for i, e := range d.Sent {
+		out.Sent[i] = toJSONMessage(e)
+	}
+	for i, e := range d.Received {
+		out.Received[i] = toJSONMessage(e)
+	}
+	return out, nil
+}
+
 // --- Messages ---
 
 type dbChangedMsg struct{}
@@ -280,6 +625,8 @@ type dbChangedMsg struct{}
 type snapshotReadyMsg struct {
 	snap *snapshot.DataSnapshot
 	err  error
+	seq  uint64 // refreshSnapshot call that produced it; see uiModel.buildSeq
+	took time.Duration
 }
 
 type tickMsg struct{}
@@ -296,9 +643,40 @@ type keyMap struct {
 	Enter   key.Binding
 	Esc     key.Binding
 	Filter  key.Binding
+	Search  key.Binding
+
+	Heartbeats key.Binding
+	Kind       key.Binding
+	MergeLocks key.Binding
+	Epochs     key.Binding
+	ArrowFocus key.Binding
+	NextGroup  key.Binding
+	PrevGroup  key.Binding
+	Pin        key.Binding
+	Pair       key.Binding
+	Preview    key.Binding
+	Sort       key.Binding
+	OpenDB     key.Binding
+	CopyID     key.Binding
+	Newest     key.Binding
+	SimWidth   key.Binding
+	Menu       key.Binding
+	Reset      key.Binding
+	Fold       key.Binding
+	Columns    key.Binding
+	Compact    key.Binding
+	Blocked    key.Binding
+	Wrap       key.Binding
+	Decode     key.Binding
+	Ranks      key.Binding
+	Gradient   key.Binding
+	Left       key.Binding
+	Right      key.Binding
 }
 
-var keys = keyMap{
+// defaultKeys are the built-in bindings; the config file can rebind the
+// actions in keyActions.
+var defaultKeys = keyMap{
 	Quit:    key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
 	Tab:     key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next view")),
 	Refresh: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
@@ -308,17 +686,48 @@ var keys = keyMap{
 	Enter:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select agent")),
 	Esc:     key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
 	Filter:  key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter agent")),
+	Search:  key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("ctrl+f", "search bodies")),
+
+	Heartbeats: key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "collapse heartbeats")),
+	Kind:       key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "filter event kind")),
+	MergeLocks: key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "merge lock/unlock")),
+	Epochs:     key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "epoch markers")),
+	ArrowFocus: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "selected agent's arrows only")),
+	NextGroup:  key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "next concurrent group")),
+	PrevGroup:  key.NewBinding(key.WithKeys("N"), key.WithHelp("N", "prev concurrent group")),
+	SimWidth:   key.NewBinding(key.WithKeys("W"), key.WithHelp("W", "simulate width")),
+	Menu:       key.NewBinding(key.WithKeys("0"), key.WithHelp("0", "view menu")),
+	OpenDB:     key.NewBinding(key.WithKeys("O"), key.WithHelp("O", "open DB externally")),
+	CopyID:     key.NewBinding(key.WithKeys("Y"), key.WithHelp("Y", "copy agent ID")),
+	Newest:     key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "newest message")),
+	Pin:        key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "pin agent to diagram")),
+	Sort:       key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "sort agents/columns/timeline")),
+	Pair:       key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "focus pair")),
+	Preview:    key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "last message preview")),
+	Gradient:   key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "color rows by recency")),
+	Reset:      key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "reset filters/toggles")),
+	Fold:       key.NewBinding(key.WithKeys("1", "2", "3", "4"), key.WithHelp("1-4", "fold detail section")),
+	Columns:    key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "two-column messages")),
+	Compact:    key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "compact antichain")),
+	Blocked:    key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "blocked agents only")),
+	Wrap:       key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "wrap/scroll bodies")),
+	Decode:     key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "decode base64/hex bodies")),
+	Ranks:      key.NewBinding(key.WithKeys("#"), key.WithHelp("#", "show Lamport ranks")),
+	Left:       key.NewBinding(key.WithKeys("left"), key.WithHelp("left", "pan left")),
+	Right:      key.NewBinding(key.WithKeys("right"), key.WithHelp("right", "pan right")),
 }
 
+// keys are the active bindings: defaultKeys merged with the config file.
+var keys = defaultKeys
+
 // viewKeys maps single keys to views for fast navigation.
-var viewKeys = map[string]viewID{
-	"d": viewDashboard,
-	"m": viewMessages,
-	"l": viewLocks,
-	"f": viewFrontier,
-	"t": viewTimeline,
-	"s": viewDiagram,
-}
+var viewKeys = func() map[string]viewID {
+	m := make(map[string]viewID, len(views))
+	for _, vi := range views {
+		m[vi.key] = vi.id
+	}
+	return m
+}()
 
 func (k keyMap) ShortHelp() []key.Binding {
 	return []key.Binding{k.Tab, k.Refresh, k.Help, k.Quit}
@@ -327,19 +736,31 @@ func (k keyMap) ShortHelp() []key.Binding {
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Tab, k.Refresh, k.Up, k.Down},
-		{k.Enter, k.Esc, k.Help, k.Quit},
+		{k.Enter, k.Esc, k.Reset, k.OpenDB, k.CopyID, k.Newest, k.SimWidth, k.Ranks, k.Menu, k.Help, k.Quit},
+		{k.Filter, k.Search, k.Pin, k.Pair, k.Preview, k.Gradient, k.Sort, k.Fold, k.Heartbeats, k.Kind, k.MergeLocks, k.Epochs, k.ArrowFocus, k.NextGroup, k.PrevGroup, k.Columns, k.Compact, k.Blocked, k.Wrap, k.Decode, k.Left, k.Right},
 	}
 }
 
-// contextHelp returns help text appropriate for the current view.
-func contextHelp(v viewID) string {
+// contextHelp returns help text appropriate for the current view. On the
+// Timeline, o names the order it switches to, given timelineAscending.
+func contextHelp(v viewID, timelineAscending bool) string {
 	switch v {
 	case viewDashboard:
-		return "j/k: select agent | enter: drill down | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
+		return "j/k: select agent | enter: drill down | space: pin | P: pair | p: preview | Y: copy ID | g: recency colors | o: sort | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
 	case viewAgentDetail:
-		return "j/k: scroll | esc: back to dashboard | d/m/l/f/t/s: views | ?: help | q: quit"
-	case viewMessages, viewTimeline:
-		return "j/k: scroll | /: filter agent | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
+		return "j/k: scroll | 1-4: fold sections | #: ranks | Y: copy ID | esc: back to dashboard | d/m/l/f/t/s: views | ?: help | q: quit"
+	case viewMessages:
+		return "j/k: scroll | /: filter agent | ctrl+f: search | C: columns | w: wrap | x: decode | #: ranks | enter: expand | E: newest | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
+	case viewTimeline:
+		order := "oldest first"
+		if timelineAscending {
+			order = "newest first"
+		}
+		return "j/k: scroll | /: filter agent | ctrl+f: search | K: kind | H: heartbeats | L: merge locks | e: epoch markers | o: " + order + " | n/N: concurrent groups | #: ranks | enter: expand | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
+	case viewFrontier:
+		return "j/k: scroll | c: compact antichain | b: blocked only | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
+	case viewDiagram:
+		return "j/k: scroll | left/right: pan columns | o: order by activity | a: selected agent's arrows | space on dashboard: pin columns | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
 	default:
 		return "j/k: scroll | d/m/l/f/t/s: views | tab: next | ?: help | q: quit"
 	}
@@ -360,6 +781,26 @@ const (
 	viewAgentDetail
 )
 
+// viewInfo describes a tab-bar view: the name --view takes, further names
+// it accepts, and the key that jumps to it.
+type viewInfo struct {
+	id      viewID
+	name    string
+	aliases []string
+	key     string
+}
+
+// views is the one table of view names and shortcuts, in tab order;
+// parseViewFlag, viewKeys and --list-views all read it.
+var views = []viewInfo{
+	{id: viewDashboard, name: "dashboard", key: "d"},
+	{id: viewMessages, name: "messages", key: "m"},
+	{id: viewLocks, name: "locks", key: "l"},
+	{id: viewFrontier, name: "frontier", key: "f"},
+	{id: viewTimeline, name: "timeline", key: "t"},
+	{id: viewDiagram, name: "diagram", aliases: []string{"spacetime"}, key: "s"},
+}
+
 func (v viewID) String() string {
 	switch v {
 	case viewDashboard:
@@ -386,34 +827,141 @@ type uiModel struct {
 	store   *store.Store
 	watcher *datasource.Watcher
 	snap    *snapshot.DataSnapshot
-	dbPath  string
-
-	activeView      viewID
-	prevView        viewID // for Esc navigation
-	width           int
-	height          int
-	scrollPos       int
-	selectedAgent   int
-	detailAgentID   string // agent ID for detail view
-	filterAgent     string // agent filter for Messages/Timeline ("" = all)
-	refreshInterval time.Duration
+
+	dbPath string
+
+	activeView        viewID
+	prevView          viewID // for Esc navigation
+	width             int
+	height            int
+	widthOverride     int                      // W: simulated render width for layout testing (0 = real width)
+	viewMenu          bool                     // 0: numbered view menu shown in place of the content
+	newestOverlay     bool                     // E: newest message body shown in place of the content
+	scrollPos         int                      // active view's scroll offset
+	savedScroll       [viewAgentDetail + 1]int // other views' offsets, restored on return
+	selectedAgent     int                      // index into dashboardAgents(), derived from selectedAgentID
+	selectedAgentID   string                   // the selected agent; survives re-sorts and refreshes
+	dashboardSort     agentSort                // o on Dashboard: row order
+	diagramOrder      diagramOrder             // o on Diagram: column order
+	detailAgentID     string                   // agent ID for detail view
+	filterAgent       string                   // agent filter for Messages/Timeline ("" = all)
+	focusPair         [2]string                // P on Dashboard: conversation filter; [1] is "" while choosing
+	pinned            map[string]bool          // agents pinned as Diagram columns (empty = all)
+	showPreview       bool                     // p on Dashboard: each agent's last message after its row
+	recencyRows       bool                     // g on Dashboard: row color fades with time since last seen
+	refreshInterval   time.Duration
+	newAgentWindow    time.Duration      // agents registered more recently than this are badged NEW
+	collapseBeats     bool               // Timeline: fold runs of heartbeats into one line
+	timelineKind      model.EventKind    // Timeline: show only this kind ("" = all)
+	mergeLocks        bool               // Timeline: fold each lock/unlock pair into one entry
+	epochMarkers      bool               // Timeline: divider where an agent's epoch advances
+	searching         bool               // ctrl+f: keys go to searchQuery
+	searchQuery       string             // Messages/Timeline: only events whose body contains this
+	timelineAscending bool               // Timeline: oldest first instead of newest first
+	arrowFocus        bool               // Diagram: draw only the selected agent's message arrows
+	messageColumns    bool               // Messages: two columns on wide terminals
+	noWrap            bool               // Messages: pan long bodies instead of wrapping
+	hScroll           int                // Messages: horizontal pan offset in columns (noWrap only)
+	foldedSections    [sectionCount]bool // Agent Detail: sections collapsed to their header
+	frontierCompact   bool               // Frontier: antichain summarized on one line
+	blockedOnly       bool               // Frontier: list only agents not safe to finalize
+	maxBody           int                // Messages/Timeline: clip bodies longer than this (0 = never)
+	diagramRows       int                // Diagram: newest timestamp rows shown (0 = all)
+	detailLimit       int                // Agent Detail: entries per event list (0 = all)
+	splitRatio        float64            // Dashboard split pane: left share of the width (0 = default)
+	pairCount         pairCountCache     // Timeline: unordered pair count, kept current by Update
+	utc               bool               // wall-clock times in UTC instead of local time
+	diagramCol        int                // Diagram: first agent column shown (left/right pan)
+	expandBodies      bool               // Messages/Timeline: show clipped bodies in full
+	decodeBodies      bool               // Messages: show base64/hex bodies decoded
+	showRanks         bool               // #: Lamport labels also show the timestamp's rank
+	older             []model.Event      // events loaded past the snapshot window for sparse filters
+	searchedBack      int                // events examined by the last widening, for the header note
+	widening          bool               // a widening load is in flight
+	seenEventID       [viewCount]int64   // per tab: newest event ID shown there (for unseen badges)
+	safeHistory       sampleRing         // finalizable fraction per snapshot, for the title bar sparkline
+	progress          progressTracker    // when each agent's epoch/round last changed, for STUCK badges
 
 	help     help.Model
 	showHelp bool
 
-	lastRefresh time.Time
+	lastRefresh  time.Time
+	buildErr     error         // last failed snapshot build, cleared on success
+	buildSeq     uint64        // sequence number of the last build started
+	appliedSeq   uint64        // sequence number of the last build result applied
+	building     bool          // the newest build started has not finished
+	refreshDue   bool          // a change arrived mid-build; rebuild once it finishes
+	buildTook    time.Duration // duration of the newest finished build
+	notice       string        // transient status bar message (e.g. open-DB result)
+	noticeAt     time.Time     // when notice was set; it shows for noticeTTL
+	eventDelta   int           // events gained by the last refresh, shown for eventDeltaTTL
+	eventDeltaAt time.Time
+	agentsJoined int // agents registered by the last refresh that changed the agent set
+	agentsLeft   int // agents gone in that refresh; both persist until the set changes again
 }
 
+// newModel builds the UI model. A nil snap is replaced by an empty
+// snapshot, so the model always starts with one to draw.
 func newModel(s *store.Store, w *datasource.Watcher, snap *snapshot.DataSnapshot, dbPath string) uiModel {
+	if snap == nil {
+		snap = &snapshot.DataSnapshot{FrontierStatus: map[string]frontier.FrontierStatus{}}
+	}
 	h := help.New()
-	return uiModel{
-		store:       s,
-		watcher:     w,
-		snap:        snap,
-		dbPath:      dbPath,
-		help:        h,
-		lastRefresh: time.Now(),
+	m := uiModel{
+		store:          s,
+		watcher:        w,
+		snap:           snap,
+		dbPath:         dbPath,
+		help:           h,
+		lastRefresh:    time.Now(),
+		newAgentWindow: defaultNewAgentWindow,
+		maxBody:        defaultMaxBody,
+		diagramRows:    defaultDiagramRows,
+		detailLimit:    defaultDetailLimit,
+	}
+	m = m.recordFinalizable().selectIndex(0)
+	m.progress = m.progress.observe(snap.Agents, time.Now())
+	// Everything loaded at startup counts as seen; badges show what's new.
+	latest := latestEventID(snap.Events)
+	for v := range m.seenEventID {
+		m.seenEventID[v] = latest
 	}
+	return m
+}
+
+// resetViewState returns m with every filter, toggle, and scroll offset back
+// at its default. The active view, selection, and snapshot are kept.
+func (m uiModel) resetViewState() uiModel {
+	m.scrollPos = 0
+	m.savedScroll = [viewAgentDetail + 1]int{}
+	m.filterAgent = ""
+	m.focusPair = [2]string{}
+	m.dashboardSort = sortRegistered
+	m.diagramOrder = diagramByRegistration
+	m.pinned = nil
+	m.showPreview = false
+	m.recencyRows = false
+	m.collapseBeats = false
+	m.timelineKind = ""
+	m.mergeLocks = false
+	m.epochMarkers = false
+	m.timelineAscending = false
+	m.searching = false
+	m.searchQuery = ""
+	m.arrowFocus = false
+	m.messageColumns = false
+	m.noWrap = false
+	m.hScroll = 0
+	m.foldedSections = [sectionCount]bool{}
+	m.frontierCompact = false
+	m.blockedOnly = false
+	m.expandBodies = false
+	m.decodeBodies = false
+	m.showRanks = false
+	m.diagramCol = 0
+	m.older = nil
+	m.searchedBack = 0
+	return m.reselect()
 }
 
 func (m uiModel) Init() tea.Cmd {
@@ -422,6 +970,62 @@ func (m uiModel) Init() tea.Cmd {
 	)
 }
 
+// enterView makes v the active view, parking the current scroll offset
+// and restoring the one v had when it was last left.
+func (m uiModel) enterView(v viewID) uiModel {
+	m.savedScroll[m.activeView] = m.scrollPos
+	m.activeView = v
+	m.scrollPos = m.savedScroll[v]
+	return m
+}
+
+// clearAgentFilter drops the agent filter when the active view does not
+// use it. The filtered views' remembered offsets pointed into the filtered
+// list, so they are forgotten along with it.
+func (m uiModel) clearAgentFilter() uiModel {
+	if m.activeView == viewMessages || m.activeView == viewTimeline || m.filterAgent == "" {
+		return m
+	}
+	m.filterAgent = ""
+	m.savedScroll[viewMessages] = 0
+	m.savedScroll[viewTimeline] = 0
+	return m
+}
+
+// switchView jumps to view v from anywhere, leaving Agent Detail.
+func (m uiModel) switchView(v viewID) (uiModel, tea.Cmd) {
+	m = m.enterView(v)
+	m.detailAgentID = ""
+	return m.clearAgentFilter().markViewed().widenIfSparse()
+}
+
+// updateSearch handles a key while the search prompt is open: typing and
+// backspace edit the query, enter keeps it and closes the prompt, and esc
+// clears it.
+func (m uiModel) updateSearch(msg tea.KeyMsg) (uiModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		// Only a finished query looks past the loaded events; widening on
+		// every keystroke would search back for each prefix.
+		m.searching = false
+		return m.widenIfSparse()
+	case tea.KeyEsc:
+		m.searching, m.searchQuery = false, ""
+	case tea.KeyBackspace:
+		if r := []rune(m.searchQuery); len(r) > 0 {
+			m.searchQuery = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		m.searchQuery += " "
+	case tea.KeyRunes:
+		m.searchQuery += string(msg.Runes)
+	default:
+		return m, nil
+	}
+	m.scrollPos = 0
+	return m, nil
+}
+
 func tickEvery() tea.Cmd {
 	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
 		return tickMsg{}
@@ -429,18 +1033,39 @@ func tickEvery() tea.Cmd {
 }
 
 func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd := m.update(msg)
+	return updated.(uiModel).cachePairCount(), cmd
+}
+
+func (m uiModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// The newest-message overlay closes on any key; quit also quits.
+		if m.newestOverlay {
+			m.newestOverlay = false
+			if !key.Matches(msg, keys.Quit) {
+				return m, nil
+			}
+		}
+		// The search prompt takes every key but ctrl+c while open.
+		if m.searching && msg.Type != tea.KeyCtrlC {
+			return m.updateSearch(msg)
+		}
+		// The view menu takes every key while open: a number picks a view,
+		// anything but quit just closes it.
+		if m.viewMenu {
+			m.viewMenu = false
+			if n, err := strconv.Atoi(msg.String()); err == nil && n >= 1 && n <= int(viewCount) {
+				return m.switchView(viewID(n - 1))
+			}
+			if !key.Matches(msg, keys.Quit) {
+				return m, nil
+			}
+		}
+
 		// Check single-key view shortcuts first (always available).
 		if v, ok := viewKeys[msg.String()]; ok {
-			m.activeView = v
-			m.scrollPos = 0
-			m.detailAgentID = ""
-			// Clear agent filter when leaving filterable views.
-			if v != viewMessages && v != viewTimeline {
-				m.filterAgent = ""
-			}
-			return m, nil
+			return m.switchView(v)
 		}
 
 		switch {
@@ -450,45 +1075,70 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case key.Matches(msg, keys.Esc):
-			// Back navigation from agent detail.
-			if m.activeView == viewAgentDetail {
-				m.activeView = viewDashboard
-				m.detailAgentID = ""
+			// Clear the body search, or navigate back from agent detail.
+			if m.searchQuery != "" && (m.activeView == viewMessages || m.activeView == viewTimeline) {
+				m.searchQuery = ""
 				m.scrollPos = 0
+			} else if m.activeView == viewAgentDetail {
+				m = m.enterView(viewDashboard)
+				m.detailAgentID = ""
 			}
 
 		case key.Matches(msg, keys.Enter):
+			// Expand or re-clip long message bodies.
+			if m.activeView == viewMessages || m.activeView == viewTimeline {
+				m.expandBodies = !m.expandBodies
+			}
 			// Drill into agent detail from dashboard.
 			if m.activeView == viewDashboard && len(m.snap.Agents) > 0 {
-				if m.selectedAgent >= 0 && m.selectedAgent < len(m.snap.Agents) {
-					m.detailAgentID = m.snap.Agents[m.selectedAgent].ID
+				if ag, ok := m.selectedRow(); ok {
+					m.detailAgentID = ag.ID
 					m.prevView = m.activeView
-					m.activeView = viewAgentDetail
-					m.scrollPos = 0
+					m = m.enterView(viewAgentDetail)
+					m.scrollPos = 0 // a different agent than last time, perhaps
 				}
 			}
 
 		case key.Matches(msg, keys.Tab):
 			if m.activeView == viewAgentDetail {
 				// Tab from agent detail goes back to dashboard
-				m.activeView = viewDashboard
+				m = m.enterView(viewDashboard)
 				m.detailAgentID = ""
 			} else {
-				m.activeView = (m.activeView + 1) % viewCount
-			}
-			// Clear filter when leaving filterable views.
-			if m.activeView != viewMessages && m.activeView != viewTimeline {
-				m.filterAgent = ""
+				m = m.enterView((m.activeView + 1) % viewCount)
 			}
-			m.scrollPos = 0
+			return m.clearAgentFilter().markViewed().widenIfSparse()
 
 		case key.Matches(msg, keys.Refresh):
-			return m, m.refreshSnapshot()
+			return m.refreshSnapshot()
+
+		case key.Matches(msg, keys.Search):
+			if m.activeView == viewMessages || m.activeView == viewTimeline {
+				m.searching = true
+			}
+
+		case key.Matches(msg, keys.Reset):
+			m = m.resetViewState()
+
+		case key.Matches(msg, keys.OpenDB):
+			return m, openDBCmd(m.dbPath)
+
+		case key.Matches(msg, keys.CopyID):
+			return m, copyAgentIDCmd(m.currentAgentID())
+
+		case key.Matches(msg, keys.SimWidth):
+			m.widthOverride = nextSimWidth(m.widthOverride)
+
+		case key.Matches(msg, keys.Menu):
+			m.viewMenu = true
+
+		case key.Matches(msg, keys.Newest):
+			m.newestOverlay = true
 
 		case key.Matches(msg, keys.Up):
 			if m.activeView == viewDashboard {
 				if m.selectedAgent > 0 {
-					m.selectedAgent--
+					m = m.selectIndex(m.selectedAgent - 1)
 				}
 			} else {
 				if m.scrollPos > 0 {
@@ -499,7 +1149,7 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, keys.Down):
 			if m.activeView == viewDashboard {
 				if m.selectedAgent < len(m.snap.Agents)-1 {
-					m.selectedAgent++
+					m = m.selectIndex(m.selectedAgent + 1)
 				}
 			} else {
 				// Estimate max scroll generously. Each event may produce
@@ -537,77 +1187,299 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 				m.scrollPos = 0
+				return m.widenIfSparse()
 			}
 
-		case key.Matches(msg, keys.Help):
-			m.showHelp = !m.showHelp
-		}
-
-	case tea.WindowSizeMsg:
-		m.width = msg.Width
-		m.height = msg.Height
-		m.help.Width = msg.Width
+		case key.Matches(msg, keys.Pin):
+			if ag, ok := m.selectedRow(); ok && m.activeView == viewDashboard {
+				m.pinned = togglePin(m.pinned, ag.ID)
+			}
 
-	case dbChangedMsg:
-		return m, m.refreshSnapshot()
+		case key.Matches(msg, keys.Preview):
+			if m.activeView == viewDashboard {
+				m.showPreview = !m.showPreview
+			}
 
-	case snapshotReadyMsg:
-		if msg.err == nil && msg.snap != nil {
-			m.snap = msg.snap
-			m.lastRefresh = time.Now()
-			// Clamp selectedAgent to avoid index-out-of-bounds after agent
-			// count changes between snapshots (adventure4-cah).
-			if len(m.snap.Agents) == 0 {
-				m.selectedAgent = 0
-			} else if m.selectedAgent >= len(m.snap.Agents) {
-				m.selectedAgent = len(m.snap.Agents) - 1
+		case key.Matches(msg, keys.Gradient):
+			if m.activeView == viewDashboard {
+				m.recencyRows = !m.recencyRows
 			}
-		}
 
-	case tickMsg:
-		return m, tickEvery()
-	}
+		case key.Matches(msg, keys.Pair):
+			if ag, ok := m.selectedRow(); ok && m.activeView == viewDashboard {
+				m.focusPair = choosePair(m.focusPair, ag.ID)
+			}
 
-	return m, nil
-}
+		case key.Matches(msg, keys.Sort):
+			switch m.activeView {
+			case viewDashboard:
+				if ag, ok := m.selectedRow(); ok {
+					m.selectedAgentID = ag.ID
+				}
+				m.dashboardSort = (m.dashboardSort + 1) % agentSortCount
+				m = m.reselect()
+			case viewDiagram:
+				m.diagramOrder = 1 - m.diagramOrder
+			case viewTimeline:
+				m.timelineAscending = !m.timelineAscending
+				m.scrollPos = 0
+			}
 
-func (m uiModel) refreshSnapshot() tea.Cmd {
-	s := m.store
-	return func() tea.Msg {
-		snap, err := snapshot.Build(s)
-		return snapshotReadyMsg{snap: snap, err: err}
-	}
-}
+		case key.Matches(msg, keys.Fold):
+			if m.activeView == viewAgentDetail {
+				sec := detailSection(msg.String()[0] - '1')
+				m.foldedSections[sec] = !m.foldedSections[sec]
+			}
 
-// --- Styles ---
+		case key.Matches(msg, keys.Heartbeats):
+			if m.activeView == viewTimeline {
+				m.collapseBeats = !m.collapseBeats
+				m.scrollPos = 0
+			}
 
-var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#7C3AED")).
-			Background(lipgloss.Color("#1E1E2E")).
-			Padding(0, 1)
+		case key.Matches(msg, keys.NextGroup):
+			if m.activeView == viewTimeline {
+				for _, pos := range m.concurrentGroupLines() {
+					if pos > m.scrollPos {
+						m.scrollPos = pos
+						break
+					}
+				}
+			}
 
-	tabActiveStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#CDD6F4")).
-			Background(lipgloss.Color("#7C3AED")).
-			Padding(0, 1)
+		case key.Matches(msg, keys.PrevGroup):
+			if m.activeView == viewTimeline {
+				lines := m.concurrentGroupLines()
+				for i := len(lines) - 1; i >= 0; i-- {
+					if lines[i] < m.scrollPos {
+						m.scrollPos = lines[i]
+						break
+					}
+				}
+			}
 
-	tabInactiveStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#6C7086")).
-				Background(lipgloss.Color("#313244")).
-				Padding(0, 1)
+		case key.Matches(msg, keys.MergeLocks):
+			if m.activeView == viewTimeline {
+				m.mergeLocks = !m.mergeLocks
+			}
 
-	headerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#89B4FA"))
+		case key.Matches(msg, keys.Epochs):
+			if m.activeView == viewTimeline {
+				m.epochMarkers = !m.epochMarkers
+			}
 
-	agentActiveStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#A6E3A1"))
+		case key.Matches(msg, keys.ArrowFocus):
+			if m.activeView == viewDiagram {
+				m.arrowFocus = !m.arrowFocus
+			}
 
-	agentStaleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#F38BA8"))
+		case key.Matches(msg, keys.Kind):
+			if m.activeView == viewTimeline {
+				m.timelineKind = nextTimelineKind(m.timelineKind)
+				m.scrollPos = 0
+				return m.widenIfSparse()
+			}
+
+		case key.Matches(msg, keys.Columns):
+			if m.activeView == viewMessages {
+				m.messageColumns = !m.messageColumns
+				m.scrollPos = 0
+			}
+
+		case key.Matches(msg, keys.Compact):
+			if m.activeView == viewFrontier {
+				m.frontierCompact = !m.frontierCompact
+			}
+
+		case key.Matches(msg, keys.Blocked):
+			if m.activeView == viewFrontier {
+				m.blockedOnly = !m.blockedOnly
+				m.scrollPos = 0
+			}
+
+		case key.Matches(msg, keys.Ranks):
+			m.showRanks = !m.showRanks
+
+		case key.Matches(msg, keys.Decode):
+			if m.activeView == viewMessages {
+				m.decodeBodies = !m.decodeBodies
+			}
+
+		case key.Matches(msg, keys.Wrap):
+			if m.activeView == viewMessages {
+				m.noWrap = !m.noWrap
+				m.hScroll = 0
+			}
+
+		case key.Matches(msg, keys.Left):
+			if m.activeView == viewMessages && m.noWrap {
+				m.hScroll = max(0, m.hScroll-hScrollStep)
+			}
+			if m.activeView == viewDiagram {
+				m = m.panDiagram(-1)
+			}
+
+		case key.Matches(msg, keys.Right):
+			if m.activeView == viewMessages && m.noWrap {
+				m.hScroll += hScrollStep
+			}
+			if m.activeView == viewDiagram {
+				m = m.panDiagram(1)
+			}
+
+		case key.Matches(msg, keys.Help):
+			m.showHelp = !m.showHelp
+		}
+
+	case tea.WindowSizeMsg:
+		// A width change reflows wrapped message bodies, so a raw line offset
+		// would land somewhere else. Anchor the Timeline scroll to the event
+		// at the top of the viewport and restore it after the reflow.
+		var anchor int64
+		if m.activeView == viewTimeline && m.scrollPos > 0 && m.width > 0 && msg.Width != m.width {
+			_, owners := m.timelineLayout()
+			anchor = lineOwner(owners, m.scrollPos)
+		}
+		m.width = msg.Width
+		m.height = msg.Height
+		m.help.Width = msg.Width
+		if anchor != 0 {
+			_, owners := m.timelineLayout()
+			if pos := firstLineOf(owners, anchor); pos >= 0 {
+				m.scrollPos = pos
+			}
+		}
+
+	case dbChangedMsg:
+		// Coalesce: while a build is running, any number of changes add up
+		// to one rebuild when it finishes, instead of builds piling up.
+		if m.building {
+			m.refreshDue = true
+			return m, nil
+		}
+		return m.refreshSnapshot()
+
+	case noticeMsg:
+		m.notice = msg.text
+		m.noticeAt = time.Now()
+
+	case olderEventsMsg:
+		m.widening = false
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("load older events: %v", msg.err)
+			m.noticeAt = time.Now()
+			break
+		}
+		merged, ok := m.snap.WithOlder(msg.events)
+		if !ok {
+			// A rebuild moved the window while these loaded.
+			m.older, m.searchedBack = nil, 0
+			break
+		}
+		m.older = append(msg.events, m.older...)
+		m.searchedBack = msg.searched
+		m.snap = merged
+
+	case snapshotReadyMsg:
+		if msg.seq < m.appliedSeq {
+			break // a newer build already landed
+		}
+		m.appliedSeq = msg.seq
+		m.buildTook = msg.took
+		if msg.seq == m.buildSeq {
+			m.building = false
+		}
+		m.buildErr = msg.err
+		if msg.err == nil && msg.snap != nil {
+			if m.snap != nil {
+				if d := msg.snap.TotalEvents - m.snap.TotalEvents; d > 0 {
+					m.eventDelta, m.eventDeltaAt = d, time.Now()
+				}
+				if joined, left := agentSetDelta(m.snap.Agents, msg.snap.Agents); joined+left > 0 {
+					m.agentsJoined, m.agentsLeft = joined, left
+				}
+			}
+			m.snap = msg.snap
+			m.progress = m.progress.observe(msg.snap.Agents, time.Now())
+			m = m.recordFinalizable()
+			if len(m.older) > 0 {
+				// Once the window moves past the widened events they no
+				// longer join it; drop them rather than show a hole.
+				merged, ok := m.snap.WithOlder(m.older)
+				if !ok {
+					m.older, m.searchedBack = nil, 0
+				}
+				m.snap = merged
+			}
+			m.lastRefresh = time.Now()
+			m = m.markViewed()
+			m = m.reselect()
+		}
+		if m.refreshDue && !m.building {
+			m.refreshDue = false
+			return m.refreshSnapshot()
+		}
+
+	case tickMsg:
+		return m, tickEvery()
+	}
+
+	return m, nil
+}
+
+// snapshotTimeout bounds each refresh so a store held by a long writer
+// doesn't leave the UI waiting indefinitely.
+const snapshotTimeout = 2 * time.Second
+
+// refreshSnapshot starts a build tagged with the next sequence number.
+// Builds run concurrently and can finish out of order; Update drops any
+// result older than the last one applied.
+func (m uiModel) refreshSnapshot() (uiModel, tea.Cmd) {
+	m.buildSeq++
+	m.building = true
+	s, seq := m.store, m.buildSeq
+	return m, func() tea.Msg {
+		start := time.Now()
+		snap, err := snapshot.BuildWithTimeout(s, snapshotTimeout, staleAfter)
+		return snapshotReadyMsg{snap: snap, err: err, seq: seq, took: time.Since(start)}
+	}
+}
+
+// slowBuild reports whether the last build took longer than the polling
+// interval, so refreshes arrive faster than they can be served.
+func (m uiModel) slowBuild() bool {
+	return m.refreshInterval > 0 && m.buildTook > m.refreshInterval
+}
+
+// --- Styles ---
+
+var (
+	titleStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#7C3AED")).
+			Background(lipgloss.Color("#1E1E2E")).
+			Padding(0, 1)
+
+	tabActiveStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#CDD6F4")).
+			Background(lipgloss.Color("#7C3AED")).
+			Padding(0, 1)
+
+	tabInactiveStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#6C7086")).
+				Background(lipgloss.Color("#313244")).
+				Padding(0, 1)
+
+	headerStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#89B4FA"))
+
+	agentActiveStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#A6E3A1"))
+
+	agentStaleStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F38BA8"))
 
 	lockStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#FAB387"))
@@ -633,14 +1505,119 @@ var (
 	statusBarStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#CDD6F4")).
 			Background(lipgloss.Color("#1E1E2E"))
+
+	newBadgeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#94E2D5")).
+			Bold(true)
+
+	pinBadgeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F5C2E7")).
+			Bold(true)
 )
 
+// themeStyles names the styles whose foreground can be overridden at
+// startup with CMV_COLOR_<NAME>=#RRGGBB environment variables.
+var themeStyles = map[string]*lipgloss.Style{
+	"TITLE":            &titleStyle,
+	"TAB_ACTIVE":       &tabActiveStyle,
+	"TAB_INACTIVE":     &tabInactiveStyle,
+	"HEADER":           &headerStyle,
+	"AGENT_ACTIVE":     &agentActiveStyle,
+	"AGENT_STALE":      &agentStaleStyle,
+	"LOCK":             &lockStyle,
+	"SAFE":             &safeStyle,
+	"UNSAFE":           &unsafeStyle,
+	"DIM":              &dimStyle,
+	"MSG_FROM":         &msgFromStyle,
+	"MSG_TO":           &msgToStyle,
+	"STATUS_BAR":       &statusBarStyle,
+	"NEW_BADGE":        &newBadgeStyle,
+	"PIN_BADGE":        &pinBadgeStyle,
+	"CONCURRENT":       &concurrentStyle,
+	"CONCURRENT_PAIR":  &concurrentPairStyle,
+	"CONCURRENT_HEAVY": &concurrentHeavyStyle,
+	"CAUSAL":           &causalStyle,
+	"EPOCH_MARKER":     &epochMarkerStyle,
+	"SEARCH_MATCH":     &searchMatchStyle,
+	"DIAGRAM_LINE":     &diagramLineStyle,
+	"DIAGRAM_EVENT":    &diagramEventStyle,
+	"DIAGRAM_MSG":      &diagramMsgStyle,
+	"DETAIL_HEADER":    &detailHeaderStyle,
+	"DETAIL_SECTION":   &detailSectionStyle,
+}
+
+// colorEnvPrefix is the environment variable prefix for color overrides.
+const colorEnvPrefix = "CMV_COLOR_"
+
+// applyColorEnv applies CMV_COLOR_<NAME> overrides from environ (as returned
+// by os.Environ) to the named styles. Unknown names and values that are not
+// #RGB or #RRGGBB hex colors are skipped with a warning.
+func applyColorEnv(styles map[string]*lipgloss.Style, environ []string, warn io.Writer) {
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(k, colorEnvPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(k, colorEnvPrefix)
+		st, known := styles[name]
+		if !known {
+			fmt.Fprintf(warn, "cmv: ignoring %s: unknown style %q\n", k, name)
+			continue
+		}
+		if !isHexColor(v) {
+			fmt.Fprintf(warn, "cmv: ignoring %s: %q is not a hex color\n", k, v)
+			continue
+		}
+		*st = st.Foreground(lipgloss.Color(v))
+	}
+}
+
+// isHexColor reports whether s is a #RGB or #RRGGBB color.
+func isHexColor(s string) bool {
+	if len(s) != 4 && len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
 // --- View rendering ---
 
+// viewContent renders the active view in full, before scrolling.
+func (m uiModel) viewContent() string {
+	switch m.activeView {
+	case viewDashboard:
+		return m.renderDashboard()
+	case viewMessages:
+		if m.messageColumnCount() == 2 {
+			return m.renderMessageColumns()
+		}
+		return m.renderMessages()
+	case viewLocks:
+		return m.renderLocks()
+	case viewFrontier:
+		return m.renderFrontier()
+	case viewTimeline:
+		return m.renderTimeline()
+	case viewDiagram:
+		return m.renderDiagram()
+	case viewAgentDetail:
+		return m.renderAgentDetailFor(m.detailAgentID)
+	}
+	return ""
+}
+
 func (m uiModel) View() string {
 	if m.width == 0 {
 		return "Loading..."
 	}
+	if m.widthOverride > 0 {
+		m.width = m.widthOverride // every renderer below sees the simulated width
+	}
 
 	var b strings.Builder
 
@@ -658,38 +1635,37 @@ func (m uiModel) View() string {
 	if m.showHelp {
 		contentHeight -= 3
 	}
+	if banner := m.renderGapBanner(); banner != "" {
+		b.WriteString(banner)
+		b.WriteRune('\n')
+		contentHeight--
+	}
+	if banner := m.renderFreshBanner(); banner != "" {
+		b.WriteString(banner)
+		b.WriteRune('\n')
+		contentHeight--
+	}
+	strip := m.renderActivityStrip()
+	if strip != "" {
+		contentHeight--
+	}
 
 	var content string
 
 	// Split-pane: Dashboard + Agent Detail side by side on wide terminals.
-	if m.activeView == viewDashboard && m.width >= 120 && m.detailAgentID == "" &&
-		len(m.snap.Agents) > 0 && m.selectedAgent < len(m.snap.Agents) {
+	if m.snap == nil {
+		content = noData()
+	} else if m.dashboardSplit() {
 		// Auto-split: show dashboard left, selected agent detail right.
-		leftWidth := m.width/2 - 1
-		rightWidth := m.width - leftWidth - 3 // 3 for separator
+		sel, _ := m.selectedRow()
+		leftWidth, rightWidth, _ := splitWidths(m.width, m.splitRatio)
 
 		left := m.renderDashboard()
-		agentID := m.snap.Agents[m.selectedAgent].ID
-		right := m.renderAgentDetailFor(agentID)
+		right := m.renderAgentDetailFor(sel.ID)
 
 		content = renderSplitPane(left, right, leftWidth, rightWidth, contentHeight)
 	} else {
-		switch m.activeView {
-		case viewDashboard:
-			content = m.renderDashboard()
-		case viewMessages:
-			content = m.renderMessages()
-		case viewLocks:
-			content = m.renderLocks()
-		case viewFrontier:
-			content = m.renderFrontier()
-		case viewTimeline:
-			content = m.renderTimeline()
-		case viewDiagram:
-			content = m.renderDiagram()
-		case viewAgentDetail:
-			content = m.renderAgentDetailFor(m.detailAgentID)
-		}
+		content = m.viewContent()
 
 		// Apply scroll using a local variable. View() is a value receiver
 		// so mutating m.scrollPos here would be dead code (adventure4-ihh).
@@ -707,6 +1683,14 @@ func (m uiModel) View() string {
 		content = strings.Join(lines, "\n")
 	}
 
+	if m.viewMenu {
+		content = m.renderViewMenu()
+	}
+	if m.newestOverlay {
+		lines := strings.Split(m.renderNewestMessage(), "\n")
+		content = strings.Join(lines[:min(len(lines), max(contentHeight, 1))], "\n")
+	}
+
 	// Truncate each line to terminal width so content doesn't wrap
 	// on resize. Uses ANSI-aware width measurement.
 	content = truncateLines(content, m.width)
@@ -714,11 +1698,26 @@ func (m uiModel) View() string {
 	b.WriteString(content)
 
 	// Pad to fill screen.
+	bottom := m.height - 2
+	if strip != "" {
+		bottom--
+	}
 	rendered := strings.Count(b.String(), "\n")
-	for rendered < m.height-2 {
+	for rendered < bottom {
 		b.WriteRune('\n')
 		rendered++
 	}
+	if strip != "" {
+		b.WriteString(truncateLines(strip, m.width))
+		b.WriteRune('\n')
+	}
+
+	// Quiet hours: fade everything but the status bar while nothing happens.
+	if m.idleFor() >= idleThreshold {
+		frame := dimFrame(b.String())
+		b.Reset()
+		b.WriteString(frame)
+	}
 
 	// Help / status bar.
 	if m.showHelp {
@@ -732,163 +1731,1036 @@ func (m uiModel) View() string {
 
 func (m uiModel) renderTitleBar() string {
 	title := titleStyle.Render("clockmail viewer")
+	if m.snap == nil {
+		return title + " " + dimStyle.Render("no data")
+	}
+	trend := ""
+	if m.agentsJoined+m.agentsLeft > 0 {
+		trend = fmt.Sprintf(" (+%d -%d)", m.agentsJoined, m.agentsLeft)
+	}
 	stats := dimStyle.Render(fmt.Sprintf(
-		"%d agents | %d locks | %d events",
+		"%d agents%s | %d locks | %d events",
 		m.snap.ActiveAgents+m.snap.StaleAgents,
+		trend,
 		m.snap.ActiveLocks,
 		m.snap.TotalEvents,
 	))
+	if safe, total := finalizable(m.snap.FrontierStatus); total > 0 && usesEpochs(m.snap.Agents) {
+		stats += dimStyle.Render(fmt.Sprintf(" | finalizable: %d/%d ", safe, total)) +
+			safeStyle.Render(sparkline(m.safeHistory.values()))
+		if id, n := rootBlocker(m.snap.FrontierStatus); n > 0 {
+			stats += dimStyle.Render(" | ") + unsafeStyle.Render(fmt.Sprintf("root blocker: %s (%d)", id, n))
+		}
+	}
 	gap := strings.Repeat(" ", max(0, m.width-lipgloss.Width(title)-lipgloss.Width(stats)-2))
 	return title + gap + stats
 }
 
-func (m uiModel) renderTabBar() string {
-	var tabs []string
-	for i := viewID(0); i < viewCount; i++ {
-		if i == m.activeView {
-			tabs = append(tabs, tabActiveStyle.Render(i.String()))
-		} else {
-			tabs = append(tabs, tabInactiveStyle.Render(i.String()))
-		}
+// renderActivityStrip returns a one-line heat strip of event density per
+// activityBucket, or "" when the loaded events span a single bucket.
+//
+// The store can only list events, not count them by time, so the strip
+// covers the loaded events (the snapshot window, which already includes
+// anything widened in) and says so when that is less than the whole session.
+func (m uiModel) renderActivityStrip() string {
+	if m.snap == nil {
+		return ""
 	}
-	// Show Agent Detail as active tab when drilled in.
-	if m.activeView == viewAgentDetail {
-		tabs = append(tabs, tabActiveStyle.Render("Agent: "+m.detailAgentID))
+	events := m.snap.Events
+	start, counts := activityBuckets(events, activityBucket)
+	if len(counts) < 2 {
+		return ""
 	}
-	return strings.Join(tabs, " ")
-}
-
-func (m uiModel) renderStatusBar() string {
-	ago := time.Since(m.lastRefresh).Truncate(time.Second)
-	left := fmt.Sprintf(" %s", contextHelp(m.activeView))
-	right := fmt.Sprintf("refreshed %s ago ", ago)
-	gap := strings.Repeat(" ", max(0, m.width-len(left)-len(right)))
-	return statusBarStyle.Render(left + gap + right)
-}
-
-// --- Dashboard view ---
 
-func (m uiModel) renderDashboard() string {
-	var b strings.Builder
+	note := ""
+	if loaded := len(events); loaded < m.snap.TotalEvents {
+		note = fmt.Sprintf(" (%d of %d events)", loaded, m.snap.TotalEvents)
+	}
+	end := start.Add(time.Duration(len(counts)) * activityBucket)
+	// Keep the newest buckets when the session is wider than the screen.
+	label := "activity/5m "
+	span := fmt.Sprintf(" %s–%s", start.Local().Format("15:04"), end.Local().Format("15:04"))
+	if room := m.width - ansi.StringWidth(label+span+note); len(counts) > room && room > 0 {
+		drop := len(counts) - room
+		counts = counts[drop:]
+		start = start.Add(time.Duration(drop) * activityBucket)
+		span = fmt.Sprintf(" %s–%s", start.Local().Format("15:04"), end.Local().Format("15:04"))
+	}
 
-	// Agents table.
-	b.WriteString(headerStyle.Render("Agents"))
-	b.WriteRune('\n')
-	b.WriteString(dimStyle.Render(fmt.Sprintf("  %-16s %-10s %-14s %-12s %s",
-		"ID", "Lamport", "Progress", "Last Seen", "Frontier")))
-	b.WriteRune('\n')
+	peak := 0
+	for _, c := range counts {
+		peak = max(peak, c)
+	}
+	var strip strings.Builder
+	for _, c := range counts {
+		if c == 0 {
+			strip.WriteRune(' ')
+			continue
+		}
+		strip.WriteString(sparkline([]float64{float64(c) / float64(peak)}))
+	}
+	return dimStyle.Render(label) + safeStyle.Render(strip.String()) + dimStyle.Render(span+note)
+}
 
-	for i, ag := range m.snap.Agents {
-		stale := time.Since(ag.LastSeen) > 10*time.Minute
-		style := agentActiveStyle
-		if stale {
-			style = agentStaleStyle
+// agentSetDelta counts the agent IDs in cur but not prev (joined) and in
+// prev but not cur (left).
+func agentSetDelta(prev, cur []model.Agent) (joined, left int) {
+	before := make(map[string]bool, len(prev))
+	for _, ag := range prev {
+		before[ag.ID] = true
+	}
+	for _, ag := range cur {
+		if before[ag.ID] {
+			delete(before, ag.ID)
+		} else {
+			joined++
 		}
+	}
+	return joined, len(before)
+}
 
-		// Frontier status for this agent.
-		fStr := ""
-		if fs, ok := m.snap.FrontierStatus[ag.ID]; ok {
-			if fs.SafeToFinalize {
-				fStr = safeStyle.Render("SAFE")
-			} else {
-				blockers := make([]string, 0, len(fs.BlockedBy))
-				for _, bl := range fs.BlockedBy {
-					blockers = append(blockers, bl.AgentID)
-				}
-				fStr = unsafeStyle.Render("BLOCKED by " + strings.Join(blockers, ","))
-			}
+// finalizable counts the agents whose frontier status is safe to finalize,
+// out of all agents with a status.
+func finalizable(statuses map[string]frontier.FrontierStatus) (safe, total int) {
+	for _, fs := range statuses {
+		if fs.SafeToFinalize {
+			safe++
 		}
+	}
+	return safe, len(statuses)
+}
 
-		seenAgo := shortDuration(time.Since(ag.LastSeen))
-		cursor := "  "
-		if i == m.selectedAgent {
-			cursor = "> "
+// rootBlocker returns the agent that appears in the most other agents'
+// BlockedBy lists, and how many agents it blocks. Ties go to the blocker
+// at the lowest (epoch, round), then to the smaller ID, so the laggard
+// holding everything back wins. It returns "", 0 when no one is blocked.
+func rootBlocker(status map[string]frontier.FrontierStatus) (string, int) {
+	counts := make(map[string]int)
+	lowest := make(map[string]model.Timestamp)
+	for id, fs := range status {
+		for _, bl := range fs.BlockedBy {
+			if bl.AgentID == id {
+				continue
+			}
+			if t, ok := lowest[bl.AgentID]; !ok || lexLess(bl.Timestamp, t) {
+				lowest[bl.AgentID] = bl.Timestamp
+			}
+			counts[bl.AgentID]++
 		}
-		progress := fmt.Sprintf("e%d/r%d", ag.Epoch, ag.Round)
-		line := fmt.Sprintf("%s%-16s %-10d %-14s %-12s %s",
-			cursor, ag.ID, ag.Clock, progress, seenAgo, fStr)
-		if i == m.selectedAgent {
-			b.WriteString(style.Bold(true).Render(line))
-		} else {
-			b.WriteString(style.Render(line))
+	}
+	best, n := "", 0
+	for id, c := range counts {
+		switch {
+		case c > n,
+			c == n && lexLess(lowest[id], lowest[best]),
+			c == n && lowest[id] == lowest[best] && id < best:
+			best, n = id, c
 		}
-		b.WriteRune('\n')
 	}
+	return best, n
+}
 
-	if len(m.snap.Agents) == 0 {
-		b.WriteString(dimStyle.Render("  (no agents registered)"))
-		b.WriteRune('\n')
+// lexLess orders timestamps by epoch, then round. Unlike Timestamp.Less,
+// which is Naiad's partial order, it ranks every pair.
+func lexLess(a, b model.Timestamp) bool {
+	if a.Epoch != b.Epoch {
+		return a.Epoch < b.Epoch
 	}
+	return a.Round < b.Round
+}
 
-	b.WriteRune('\n')
+// rootBlockerLabel renders "root blocker: bob @ e0/r0 (blocking 5 agents)",
+// or "" when nothing is blocked.
+func (m uiModel) rootBlockerLabel() string {
+	id, n := rootBlocker(m.snap.FrontierStatus)
+	if n == 0 {
+		return ""
+	}
+	label := "root blocker: " + id
+	if ag, ok := findAgent(m.snap.Agents, id); ok {
+		label += fmt.Sprintf(" @ e%d/r%d", ag.Epoch, ag.Round)
+	}
+	noun := "agents"
+	if n == 1 {
+		noun = "agent"
+	}
+	return label + fmt.Sprintf(" (blocking %d %s)", n, noun)
+}
 
-	// Lock summary.
-	b.WriteString(headerStyle.Render("Locks"))
-	b.WriteRune('\n')
-	if len(m.snap.Locks) > 0 {
-		for _, l := range m.snap.Locks {
-			remaining := shortDuration(time.Until(l.ExpiresAt))
-			line := fmt.Sprintf("  %-30s held by %-12s L:%-4d expires in %s",
-				l.Path, l.AgentID, l.LamportTS, remaining)
-			b.WriteString(lockStyle.Render(line))
-			b.WriteRune('\n')
-		}
-	} else {
-		b.WriteString(dimStyle.Render("  (no active locks)"))
-		b.WriteRune('\n')
+// recordFinalizable appends the current finalizable fraction to the
+// session history.
+func (m uiModel) recordFinalizable() uiModel {
+	if safe, total := finalizable(m.snap.FrontierStatus); total > 0 {
+		m.safeHistory = m.safeHistory.push(float64(safe) / float64(total))
 	}
+	return m
+}
 
-	b.WriteRune('\n')
+// sampleRingSize is how many samples the title bar sparkline keeps.
+const sampleRingSize = 20
 
-	// Frontier summary.
-	b.WriteString(headerStyle.Render("Frontier"))
-	b.WriteRune('\n')
-	if len(m.snap.Frontier) > 0 {
-		for _, p := range m.snap.Frontier {
-			line := fmt.Sprintf("  %s @ epoch=%d round=%d", p.AgentID, p.Timestamp.Epoch, p.Timestamp.Round)
-			b.WriteString(dimStyle.Render(line))
-			b.WriteRune('\n')
-		}
+// sampleRing is a fixed-size ring of the most recent samples. It is a
+// value type, so pushing never aliases an earlier model's history.
+type sampleRing struct {
+	buf   [sampleRingSize]float64
+	start int // index of the oldest sample
+	n     int
+}
+
+// push returns r with v appended, dropping the oldest sample when full.
+func (r sampleRing) push(v float64) sampleRing {
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = v
+		r.n++
 	} else {
-		b.WriteString(dimStyle.Render("  (no active pointstamps)"))
+		r.buf[r.start] = v
+		r.start = (r.start + 1) % len(r.buf)
+	}
+	return r
+}
+
+// values returns the samples oldest first.
+func (r sampleRing) values() []float64 {
+	out := make([]float64, r.n)
+	for i := range out {
+		out[i] = r.buf[(r.start+i)%len(r.buf)]
+	}
+	return out
+}
+
+// sparkline renders values in [0, 1] as block characters, one per value.
+func sparkline(values []float64) string {
+	const bars = "\u2581\u2582\u2583\u2584\u2585\u2586\u2587\u2588"
+	levels := []rune(bars)
+	var b strings.Builder
+	for _, v := range values {
+		i := int(v * float64(len(levels)-1))
+		b.WriteRune(levels[max(0, min(i, len(levels)-1))])
+	}
+	return b.String()
+}
+
+// renderTabBar renders one line of tabs. When the full names do not fit,
+// inactive tabs shrink to their shortcut key; if even that overflows, the
+// line is cut to the width.
+func (m uiModel) renderTabBar() string {
+	bar := m.tabBar(false)
+	if m.width > 0 && ansi.StringWidth(bar) > m.width {
+		bar = ansi.Truncate(m.tabBar(true), m.width, "\u2026")
+	}
+	return bar
+}
+
+// tabBar joins the tabs, with inactive ones as shortcut keys if compact.
+func (m uiModel) tabBar(compact bool) string {
+	var tabs []string
+	for _, vi := range views {
+		name := vi.id.String()
+		if compact {
+			name = vi.key
+		}
+		if vi.id == m.activeView {
+			tabs = append(tabs, tabActiveStyle.Render(vi.id.String()))
+		} else if n := m.unseenCount(vi.id); n > 0 {
+			tabs = append(tabs, tabInactiveStyle.Render(fmt.Sprintf("%s(%d)", name, n)))
+		} else {
+			tabs = append(tabs, tabInactiveStyle.Render(name))
+		}
+	}
+	// Show Agent Detail as active tab when drilled in.
+	if m.activeView == viewAgentDetail {
+		tabs = append(tabs, tabActiveStyle.Render("Agent: "+m.detailAgentID))
+	}
+	return strings.Join(tabs, " ")
+}
+
+// viewRelevant reports whether e is news for view v: the events that change
+// what v shows. The Dashboard tracks agents, not events, so it never badges.
+func viewRelevant(v viewID, e model.Event) bool {
+	switch v {
+	case viewMessages:
+		return e.Kind == model.EventMsg
+	case viewLocks:
+		return e.Kind == model.EventLockReq || e.Kind == model.EventLockRel
+	case viewFrontier:
+		return e.Kind == model.EventProgress
+	case viewTimeline, viewDiagram:
+		return true
+	}
+	return false
+}
+
+// unseenCount returns how many loaded events relevant to v arrived after v
+// was last on screen.
+func (m uiModel) unseenCount(v viewID) int {
+	if m.snap == nil {
+		return 0
+	}
+	var n int
+	for _, e := range m.snap.Events {
+		if e.ID > m.seenEventID[v] && viewRelevant(v, e) {
+			n++
+		}
+	}
+	return n
+}
+
+// latestEventID returns the highest event ID in events, or 0.
+func latestEventID(events []model.Event) int64 {
+	var id int64
+	for _, e := range events {
+		id = max(id, e.ID)
+	}
+	return id
+}
+
+// markViewed records that the active view has shown every loaded event,
+// clearing its tab badge.
+func (m uiModel) markViewed() uiModel {
+	if m.activeView < viewCount {
+		m.seenEventID[m.activeView] = latestEventID(m.snap.Events)
+	}
+	return m
+}
+
+// simWidths are the render widths W cycles through; they straddle the
+// layout breakpoints (split pane, message columns).
+var simWidths = []int{60, 80, 120, 160}
+
+// nextSimWidth returns the width override after cur: the next of
+// simWidths, then 0 (the real width) after the last.
+func nextSimWidth(cur int) int {
+	if cur == 0 {
+		return simWidths[0]
+	}
+	for i, w := range simWidths {
+		if w == cur && i+1 < len(simWidths) {
+			return simWidths[i+1]
+		}
+	}
+	return 0
+}
+
+// noticeTTL is how long a notice replaces the status bar's refresh info.
+const noticeTTL = 5 * time.Second
+
+// idleThreshold is how long the session must go without a new event
+// before the UI dims.
+const idleThreshold = 5 * time.Minute
+
+// idleFor is sessionIdleFor of the loaded events, or 0 with no snapshot.
+func (m uiModel) idleFor() time.Duration {
+	if m.snap == nil {
+		return 0
+	}
+	return sessionIdleFor(m.snap.Events, time.Now())
+}
+
+// sessionIdleFor returns how long before now the newest event was created,
+// or 0 if there are no timestamped events.
+func sessionIdleFor(events []model.Event, now time.Time) time.Duration {
+	var newest time.Time
+	for _, e := range events {
+		if e.CreatedAt.After(newest) {
+			newest = e.CreatedAt
+		}
+	}
+	if newest.IsZero() {
+		return 0
+	}
+	return max(0, now.Sub(newest))
+}
+
+// idleLabel formats an idle duration to the minute: "12m", "2h5m".
+func idleLabel(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// dimFrame re-renders rendered output in dimStyle, dropping its colors.
+func dimFrame(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = ansi.Strip(l)
+		if lines[i] != "" {
+			lines[i] = dimStyle.Render(lines[i])
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// eventDeltaTTL is how long the "+N events" readout stays after a refresh;
+// the one-second tick re-renders the status bar once it has passed.
+const eventDeltaTTL = 2 * time.Second
+
+func (m uiModel) renderStatusBar() string {
+	ago := time.Since(m.lastRefresh).Truncate(time.Second)
+	left := fmt.Sprintf(" %s", contextHelp(m.activeView, m.timelineAscending))
+	if m.viewMenu {
+		left = fmt.Sprintf(" 1-%d: switch view | any other key: close", viewCount)
+	}
+	if m.newestOverlay {
+		left = " any key: close"
+	}
+	right := fmt.Sprintf("refreshed %s ago ", ago)
+	if label := pairLabel(m.focusPair); label != "" {
+		right = label + " | " + right
+	}
+	if idle := m.idleFor(); idle >= idleThreshold {
+		right = fmt.Sprintf("idle for %s (no new events) | ", idleLabel(idle)) + right
+	}
+	if m.eventDelta > 0 && time.Since(m.eventDeltaAt) < eventDeltaTTL {
+		right = fmt.Sprintf("+%d events | ", m.eventDelta) + right
+	}
+	if m.widthOverride > 0 {
+		right = fmt.Sprintf("sim width %d | ", m.widthOverride) + right
+	}
+	if m.slowBuild() {
+		right = fmt.Sprintf("build %s > --refresh %s: raise --refresh | ",
+			m.buildTook.Round(time.Millisecond), m.refreshInterval) + right
+	}
+	if m.notice != "" && time.Since(m.noticeAt) < noticeTTL {
+		right = m.notice + " "
+	} else if errors.Is(m.buildErr, snapshot.ErrBuildTimeout) {
+		right = fmt.Sprintf("snapshot timed out (last good %s ago) ", ago)
+	} else if m.buildErr != nil {
+		right = fmt.Sprintf("snapshot error: %v ", m.buildErr)
+	}
+	gap := strings.Repeat(" ", max(0, m.width-ansi.StringWidth(left)-ansi.StringWidth(right)))
+	return statusBarStyle.Render(left + gap + right)
+}
+
+// renderViewMenu lists the views by number, with their letter shortcut,
+// for picking one without knowing the letters.
+func (m uiModel) renderViewMenu() string {
+	letters := make(map[viewID]string, len(viewKeys))
+	for k, v := range viewKeys {
+		letters[v] = k
+	}
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("Views"))
+	b.WriteRune('\n')
+	for v := viewID(0); v < viewCount; v++ {
+		name := fmt.Sprintf("%d  %-10s", v+1, v)
+		if v == m.activeView {
+			name = tabActiveStyle.Render(name)
+		}
+		b.WriteString("  " + name + " " + dimStyle.Render("("+letters[v]+")"))
 		b.WriteRune('\n')
 	}
+	return b.String()
+}
 
+// renderNewestMessage shows the newest loaded message with its body
+// expanded in full, whatever the current view, scroll, or filter. It
+// follows refreshes while open.
+func (m uiModel) renderNewestMessage() string {
+	if m.snap == nil {
+		return noData()
+	}
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("Newest Message"))
+	b.WriteRune('\n')
+	var newest *model.Event
+	for i := len(m.snap.Events) - 1; i >= 0; i-- {
+		if m.snap.Events[i].Kind == model.EventMsg {
+			newest = &m.snap.Events[i]
+			break
+		}
+	}
+	if newest == nil {
+		b.WriteString(dimStyle.Render("  (no messages yet)"))
+		b.WriteRune('\n')
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("  %s %s -> %s",
+		dimStyle.Render(lamportTag(newest.LamportTS, m.ranks(), 0)),
+		msgFromStyle.Render(newest.AgentID),
+		msgToStyle.Render(newest.Target)))
+	if !newest.CreatedAt.IsZero() {
+		b.WriteString(dimStyle.Render("  " + m.clockTime(newest.CreatedAt).Format("15:04:05")))
+	}
+	b.WriteString("\n\n")
+	expanded := m
+	expanded.expandBodies = true
+	for _, line := range wrapText(expanded.bodyText(newest.Body), max(20, m.width-5)) {
+		b.WriteString("    " + line + "\n")
+	}
 	return b.String()
 }
 
+// noData is what the content renderers show when there is no snapshot to
+// draw from, instead of dereferencing a nil one.
+func noData() string {
+	return dimStyle.Render("  (no data: no snapshot loaded)")
+}
+
+// --- Dashboard view ---
+
+func (m uiModel) renderDashboard() string {
+	if m.snap == nil {
+		return noData()
+	}
+	var b strings.Builder
+
+	// Agents table.
+	// Sessions without Naiad epochs/rounds get a pure Lamport table: every
+	// progress would read e0/r0 and every agent SAFE.
+	epochs := usesEpochs(m.snap.Agents)
+
+	b.WriteString(headerStyle.Render("Agents"))
+	if m.dashboardSort != sortRegistered {
+		b.WriteString(dimStyle.Render(" (sorted by " + m.dashboardSort.String() + ")"))
+	}
+	b.WriteRune('\n')
+	if epochs {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  %-16s %-10s %-4s %-14s %-12s %s",
+			"ID", "Lamport", "Msg", "Progress", "Last Seen", "Frontier")))
+	} else {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  %-16s %-10s %-4s %s",
+			"ID", "Lamport", "Msg", "Last Seen")))
+	}
+	b.WriteRune('\n')
+
+	for i, ag := range m.dashboardAgents() {
+		stale := isStale(ag, time.Now())
+		style := agentActiveStyle
+		if stale {
+			style = agentStaleStyle
+		}
+		if m.recencyRows {
+			style = style.Foreground(recencyColor(time.Since(ag.LastSeen)))
+		}
+
+		// Frontier status for this agent.
+		fStr := ""
+		if fs, ok := m.snap.FrontierStatus[ag.ID]; ok {
+			if fs.SafeToFinalize {
+				fStr = safeStyle.Render("SAFE")
+			} else {
+				blockers := make([]string, 0, len(fs.BlockedBy))
+				for _, bl := range fs.BlockedBy {
+					blockers = append(blockers, bl.AgentID)
+				}
+				fStr = unsafeStyle.Render("BLOCKED by " + strings.Join(blockers, ","))
+			}
+		}
+
+		seenAgo := shortDuration(time.Since(ag.LastSeen))
+		cursor := "  "
+		if i == m.selectedAgent {
+			cursor = "> "
+		}
+		progress := fmt.Sprintf("e%d/r%d", ag.Epoch, ag.Round)
+		dir := string(directionBalance(m.snap.Events, ag.ID))
+		clock := strconv.FormatInt(ag.Clock, 10)
+		if lag := clockLag(ag, m.snap.Agents); lag > 0 {
+			clock += fmt.Sprintf(" -%d", lag)
+		}
+		id := ellipsizeMiddle(ag.ID, agentIDWidth)
+		line := fmt.Sprintf("%s%-16s %-10s %-4s %-14s %-12s %s",
+			cursor, id, clock, dir, progress, seenAgo, fStr)
+		if !epochs {
+			line = fmt.Sprintf("%s%-16s %-10s %-4s %-12s", cursor, id, clock, dir, seenAgo)
+		}
+		if isNewAgent(ag, m.newAgentWindow, time.Now()) {
+			line += " " + newBadgeStyle.Render("NEW")
+		}
+		line += m.stuckBadge(ag.ID)
+		if m.pinned[ag.ID] {
+			line += " " + pinBadgeStyle.Render("PIN")
+		}
+		if m.showPreview {
+			line += m.previewFor(ag.ID, m.dashboardWidth()-ansi.StringWidth(line))
+		}
+		if i == m.selectedAgent {
+			b.WriteString(style.Bold(true).Render(line))
+		} else {
+			b.WriteString(style.Render(line))
+		}
+		b.WriteRune('\n')
+	}
+
+	if len(m.snap.Agents) == 0 {
+		b.WriteString(dimStyle.Render("  (no agents registered)"))
+		b.WriteRune('\n')
+	} else if n := distinctPairs(m.snap.Events, false); n > 0 {
+		noun := "pairs"
+		if n == 1 {
+			noun = "pair"
+		}
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  connections: %d %s (%d directed)",
+			n, noun, distinctPairs(m.snap.Events, true))))
+		b.WriteRune('\n')
+		sent, recv := messageCounts(m.snap.Events)
+		b.WriteString(dimStyle.Render("  top senders:   " + formatRanked(topN(sent, topTalkers))))
+		b.WriteRune('\n')
+		b.WriteString(dimStyle.Render("  top receivers: " + formatRanked(topN(recv, topTalkers))))
+		b.WriteRune('\n')
+	}
+
+	b.WriteRune('\n')
+
+	// Lock summary.
+	b.WriteString(headerStyle.Render("Locks"))
+	b.WriteRune('\n')
+	if len(m.snap.Locks) > 0 {
+		for _, l := range m.snap.Locks {
+			remaining := shortDuration(time.Until(l.ExpiresAt))
+			line := fmt.Sprintf("  %-30s held by %-12s L:%-4d expires in %s",
+				l.Path, l.AgentID, l.LamportTS, remaining)
+			b.WriteString(lockStyle.Render(line))
+			b.WriteRune('\n')
+		}
+	} else {
+		b.WriteString(dimStyle.Render("  (no active locks)"))
+		b.WriteRune('\n')
+	}
+
+	b.WriteRune('\n')
+
+	// Frontier summary.
+	b.WriteString(headerStyle.Render("Frontier"))
+	b.WriteRune('\n')
+	if !m.snap.FrontierAvailable {
+		b.WriteString(dimStyle.Render("  (frontier unavailable)"))
+		b.WriteRune('\n')
+	} else if len(m.snap.Frontier) > 0 {
+		for _, p := range m.snap.Frontier {
+			line := fmt.Sprintf("  %s @ epoch=%d round=%d", p.AgentID, p.Timestamp.Epoch, p.Timestamp.Round)
+			b.WriteString(dimStyle.Render(line))
+			b.WriteRune('\n')
+		}
+	} else {
+		b.WriteString(dimStyle.Render("  (no active pointstamps)"))
+		b.WriteRune('\n')
+	}
+
+	return b.String()
+}
+
+// stuckBadge returns " STUCK 5m" for a stuck agent, or "".
+func (m uiModel) stuckBadge(id string) string {
+	d, stuck := m.progress.stuckFor(id, time.Now())
+	if !stuck {
+		return ""
+	}
+	return " " + unsafeStyle.Bold(true).Render("STUCK "+idleLabel(d))
+}
+
+// dashboardSplit reports whether the Dashboard shares the screen with the
+// selected agent's detail pane. It needs a terminal at least 120 columns
+// wide, and --split-ratio must leave both panes minSplitPane columns.
+func (m uiModel) dashboardSplit() bool {
+	_, ok := m.selectedRow()
+	_, _, fits := splitWidths(m.width, m.splitRatio)
+	return ok && m.activeView == viewDashboard && m.width >= 120 && m.detailAgentID == "" && fits
+}
+
+// dashboardWidth is the width the Dashboard is rendered into.
+func (m uiModel) dashboardWidth() int {
+	if m.dashboardSplit() {
+		left, _, _ := splitWidths(m.width, m.splitRatio)
+		return left
+	}
+	return m.width
+}
+
+const (
+	defaultSplitRatio = 0.5 // --split-ratio: left pane share of the width
+	minSplitPane      = 40  // narrowest pane a split may leave
+)
+
+// splitWidths divides width into left and right panes around the
+// three-column separator, giving the left pane ratio of the width (0 means
+// defaultSplitRatio). ok is false when either pane would be narrower than
+// minSplitPane, and the view should not split.
+func splitWidths(width int, ratio float64) (left, right int, ok bool) {
+	if ratio <= 0 {
+		ratio = defaultSplitRatio
+	}
+	left = int(float64(width)*ratio) - 1
+	right = width - left - 3 // 3 for separator
+	return left, right, left >= minSplitPane && right >= minSplitPane
+}
+
+// minPreviewWidth is the least room a last-message preview needs to be
+// worth showing.
+const minPreviewWidth = 12
+
+// previewFor renders id's newest message body on one line, cut to fit in
+// room columns, or "" if it has sent none or there is too little room.
+func (m uiModel) previewFor(id string, room int) string {
+	e, ok := lastMessageFor(m.snap.Events, id)
+	if !ok || room < minPreviewWidth {
+		return ""
+	}
+	text := fmt.Sprintf("\u201c%s\u201d", strings.Join(strings.Fields(e.Body), " "))
+	return "  " + dimStyle.Render(ansi.Truncate(text, room-2, "\u2026"))
+}
+
+// lastMessageFor returns the newest message sent by id.
+func lastMessageFor(events []model.Event, id string) (model.Event, bool) {
+	for i := len(events) - 1; i >= 0; i-- {
+		if e := events[i]; e.Kind == model.EventMsg && e.AgentID == id {
+			return e, true
+		}
+	}
+	return model.Event{}, false
+}
+
+// agentSort is a Dashboard row order.
+type agentSort int
+
+const (
+	sortRegistered agentSort = iota // store order
+	sortClock                       // highest Lamport clock first
+	sortLastSeen                    // most recently seen first
+	sortID                          // alphabetical
+	agentSortCount
+)
+
+func (s agentSort) String() string {
+	switch s {
+	case sortClock:
+		return "clock"
+	case sortLastSeen:
+		return "last seen"
+	case sortID:
+		return "ID"
+	}
+	return "registration"
+}
+
+// dashboardAgents returns the agents in Dashboard row order. selectedAgent
+// indexes this slice.
+func (m uiModel) dashboardAgents() []model.Agent {
+	if m.dashboardSort == sortRegistered {
+		return m.snap.Agents
+	}
+	agents := slices.Clone(m.snap.Agents)
+	sort.SliceStable(agents, func(i, j int) bool {
+		a, b := agents[i], agents[j]
+		switch m.dashboardSort {
+		case sortClock:
+			return a.Clock > b.Clock
+		case sortLastSeen:
+			return a.LastSeen.After(b.LastSeen)
+		}
+		return a.ID < b.ID
+	})
+	return agents
+}
+
+// selectedRow returns the agent under the Dashboard cursor.
+func (m uiModel) selectedRow() (model.Agent, bool) {
+	agents := m.dashboardAgents()
+	if m.selectedAgent < 0 || m.selectedAgent >= len(agents) {
+		return model.Agent{}, false
+	}
+	return agents[m.selectedAgent], true
+}
+
+// currentAgentID is the agent Y copies: the one shown in Agent Detail, or
+// the selected Dashboard row. Other views have none.
+func (m uiModel) currentAgentID() string {
+	switch m.activeView {
+	case viewAgentDetail:
+		return m.detailAgentID
+	case viewDashboard:
+		if ag, ok := m.selectedRow(); ok {
+			return ag.ID
+		}
+	}
+	return ""
+}
+
+// selectIndex moves the cursor to row i and remembers that agent's ID.
+func (m uiModel) selectIndex(i int) uiModel {
+	m.selectedAgent = i
+	if ag, ok := m.selectedRow(); ok {
+		m.selectedAgentID = ag.ID
+	}
+	return m
+}
+
+// reselect re-derives selectedAgent from selectedAgentID after the row
+// order or agent list changed, falling back to the first row if the agent
+// is gone. Without a remembered ID the index is only clamped.
+func (m uiModel) reselect() uiModel {
+	agents := m.dashboardAgents()
+	if m.selectedAgentID != "" {
+		for i, ag := range agents {
+			if ag.ID == m.selectedAgentID {
+				m.selectedAgent = i
+				return m
+			}
+		}
+		return m.selectIndex(0)
+	}
+	// Clamp to avoid index-out-of-bounds after the agent count changes
+	// between snapshots (adventure4-cah).
+	m.selectedAgent = max(0, min(m.selectedAgent, len(agents)-1))
+	return m
+}
+
 // --- Messages view ---
 
-func (m uiModel) renderMessages() string {
+// messageColumnsMinWidth is the narrowest terminal that gets the two-column
+// Messages layout when it is enabled.
+const messageColumnsMinWidth = 140
+
+// messageColumnCount returns how many columns the Messages view renders.
+func (m uiModel) messageColumnCount() int {
+	if m.messageColumns && m.width >= messageColumnsMinWidth {
+		return 2
+	}
+	return 1
+}
+
+func (m uiModel) renderMessagesHeader() string {
+	if m.snap == nil {
+		return ""
+	}
 	var b strings.Builder
+	b.WriteString(headerStyle.Render("Messages"))
 	if m.filterAgent != "" {
-		b.WriteString(headerStyle.Render("Messages"))
 		b.WriteString(dimStyle.Render(" "))
 		b.WriteString(msgFromStyle.Render(fmt.Sprintf("[filter: %s]", m.filterAgent)))
-	} else {
-		b.WriteString(headerStyle.Render("Messages"))
 	}
+	b.WriteString(m.searchTag())
+	b.WriteString(m.searchedBackNote())
+	b.WriteString(m.coverageNote())
 	b.WriteRune('\n')
+	b.WriteString(m.pingPongNote())
+	return b.String()
+}
 
-	msgs := filterEvents(m.snap.Events, model.EventMsg)
-	// Apply agent filter.
-	if m.filterAgent != "" {
-		var filtered []model.Event
-		for _, e := range msgs {
-			if eventMatchesAgent(e, m.filterAgent) {
-				filtered = append(filtered, e)
+// pingPongNote returns one warning line per ping-pong loop among the
+// loaded events, for the Messages header.
+func (m uiModel) pingPongNote() string {
+	var b strings.Builder
+	for _, p := range detectPingPong(m.snap.Events, pingPongMinRun) {
+		b.WriteString(unsafeStyle.Render("  " + p.String()))
+		b.WriteRune('\n')
+	}
+	return b.String()
+}
+
+// searchTag is the header indicator for the body search: the query, with
+// a cursor while it is being typed, or "" when there is none.
+func (m uiModel) searchTag() string {
+	if !m.searching && m.searchQuery == "" {
+		return ""
+	}
+	cursor := ""
+	if m.searching {
+		cursor = "_"
+	}
+	return dimStyle.Render(" ") + msgFromStyle.Render(fmt.Sprintf("[search: %s%s]", m.searchQuery, cursor))
+}
+
+func (m uiModel) renderMessages() string {
+	if m.snap == nil {
+		return noData()
+	}
+	var b strings.Builder
+	b.WriteString(m.renderMessagesHeader())
+	for _, block := range m.renderMessageBlocks() {
+		b.WriteString(block)
+	}
+	b.WriteString(m.messagesFooter())
+	return b.String()
+}
+
+// visibleMessages returns the messages that pass the current agent filter,
+// body search, and focus pair.
+func (m uiModel) visibleMessages() []model.Event {
+	msgs := m.pairEvents(filterEvents(m.snap.Events, model.EventMsg))
+	if m.filterAgent == "" && m.searchQuery == "" {
+		return msgs
+	}
+	var filtered []model.Event
+	for _, e := range msgs {
+		if eventMatchesAgent(e, m.filterAgent) && bodyMatches(e, m.searchQuery) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// Adaptive event window: a snapshot holds only the newest events, so a
+// tight filter can come up empty while matches exist further back. When
+// the filtered Messages or Timeline result is sparse, older events are
+// loaded in chunks until enough match or the search cap is reached.
+const (
+	minFilteredMatches = 3    // fewer matches than this triggers widening
+	olderEventsChunk   = 500  // events per LoadOlder query
+	maxSearchBack      = 5000 // hard cap on events examined per widening
+)
+
+// olderEventsMsg delivers events loaded past the snapshot window.
+type olderEventsMsg struct {
+	events   []model.Event
+	searched int // events examined, including those loaded earlier
+	err      error
+}
+
+// filterMatcher returns the predicate behind the active view's filtered
+// result and true, or false if the view is unfiltered.
+func (m uiModel) filterMatcher() (func(model.Event) bool, bool) {
+	if m.activeView != viewMessages && m.activeView != viewTimeline {
+		return nil, false
+	}
+	a, bID, paired := m.pair()
+	filtered := m.filterAgent != "" || m.searchQuery != "" || paired
+	kind := model.EventMsg // Messages lists nothing else
+	if m.activeView == viewTimeline {
+		kind = m.timelineKind
+		filtered = filtered || kind != ""
+	}
+	if !filtered {
+		return nil, false
+	}
+	agent, query := m.filterAgent, m.searchQuery
+	return func(e model.Event) bool {
+		if kind != "" && e.Kind != kind {
+			return false
+		}
+		if !bodyMatches(e, query) {
+			return false
+		}
+		if paired && !eventInvolvesPair(e, a, bID) {
+			return false
+		}
+		return eventMatchesAgent(e, agent)
+	}, true
+}
+
+// widenIfSparse starts loading older events if the active view's filter
+// matches fewer than minFilteredMatches of the loaded events and there is
+// older history to search.
+func (m uiModel) widenIfSparse() (uiModel, tea.Cmd) {
+	match, ok := m.filterMatcher()
+	if !ok || m.widening || m.store == nil || len(m.snap.Events) == 0 {
+		return m, nil
+	}
+	found := 0
+	for _, e := range m.snap.Events {
+		if match(e) {
+			found++
+		}
+	}
+	oldest := m.snap.Events[0].ID
+	if found >= minFilteredMatches || oldest <= 1 || m.searchedBack >= maxSearchBack {
+		return m, nil
+	}
+	m.widening = true
+	s, searched := m.store, len(m.snap.Events)
+	return m, func() tea.Msg {
+		var loaded []model.Event
+		for found < minFilteredMatches && oldest > 1 && searched < maxSearchBack {
+			chunk, err := snapshot.LoadOlder(s, oldest, olderEventsChunk)
+			if err != nil {
+				return olderEventsMsg{err: err}
+			}
+			if len(chunk) == 0 {
+				break
+			}
+			for _, e := range chunk {
+				if match(e) {
+					found++
+				}
 			}
+			loaded = append(chunk, loaded...)
+			oldest = chunk[0].ID
+			searched += len(chunk)
 		}
-		msgs = filtered
+		return olderEventsMsg{events: loaded, searched: searched}
 	}
+}
+
+// searchedBackNote is the header note shown after a widening load.
+func (m uiModel) searchedBackNote() string {
+	if m.searchedBack == 0 {
+		return ""
+	}
+	if _, ok := m.filterMatcher(); !ok {
+		return ""
+	}
+	return dimStyle.Render(fmt.Sprintf(" (searched %d events back)", m.searchedBack))
+}
+
+// coverageNote tells the event views that the snapshot holds only part of
+// the log: " (showing events #4501–#5000 of 5000)", or "" when it holds
+// every event.
+func (m uiModel) coverageNote() string {
+	if m.snap.MaxLoadedID == 0 || len(m.snap.Events) >= m.snap.TotalEvents {
+		return ""
+	}
+	return dimStyle.Render(fmt.Sprintf(" (showing events #%d\u2013#%d of %d)",
+		m.snap.MinLoadedID, m.snap.MaxLoadedID, m.snap.TotalEvents))
+}
+
+// messagesFooter reports how many messages are shown and how many the
+// agent filter hides.
+func (m uiModel) messagesFooter() string {
+	total := len(filterEvents(m.snap.Events, model.EventMsg))
+	shown := len(m.visibleMessages())
+	return renderFooter(fmt.Sprintf("%d messages (%d filtered)", shown, total-shown))
+}
+
+// renderMessageColumns lays the message blocks out in two balanced columns
+// using renderSplitPane. Blocks run oldest first, like a page of text: the
+// older half fills the left column and the newer half continues in the
+// right one. A block is never split across columns.
+func (m uiModel) renderMessageColumns() string {
+	if m.snap == nil {
+		return noData()
+	}
+	leftWidth, rightWidth, _ := splitWidths(m.width, defaultSplitRatio)
+
+	// Render each column at its own width so bodies wrap to fit.
+	narrow := m
+	narrow.width = leftWidth
+	blocks := narrow.renderMessageBlocks()
+	slices.Reverse(blocks)
+
+	total := 0
+	for _, bl := range blocks {
+		total += strings.Count(bl, "\n")
+	}
+	split, lines := 0, 0
+	for split < len(blocks) && lines < (total+1)/2 {
+		lines += strings.Count(blocks[split], "\n")
+		split++
+	}
+
+	left := strings.TrimSuffix(strings.Join(blocks[:split], ""), "\n")
+	right := strings.TrimSuffix(strings.Join(blocks[split:], ""), "\n")
+	return m.renderMessagesHeader() +
+		renderSplitPane(left, right, leftWidth, rightWidth, max(total, 1)) +
+		m.messagesFooter()
+}
+
+// renderMessageBlocks renders each message (header line plus wrapped body)
+// as one newline-terminated block, newest first. When there is nothing to
+// show, the single block is the empty-state note.
+func (m uiModel) renderMessageBlocks() []string {
+	if m.snap == nil {
+		return nil
+	}
+	msgs := m.visibleMessages()
 	if len(msgs) == 0 {
+		if m.searchQuery != "" {
+			return []string{dimStyle.Render(fmt.Sprintf("  (no messages containing %q)", m.searchQuery)) + "\n"}
+		}
 		if m.filterAgent != "" {
-			b.WriteString(dimStyle.Render(fmt.Sprintf("  (no messages involving %s)", m.filterAgent)))
-		} else {
-			b.WriteString(dimStyle.Render("  (no messages)"))
+			return []string{dimStyle.Render(fmt.Sprintf("  (no messages involving %s)", m.filterAgent)) + "\n"}
 		}
-		b.WriteRune('\n')
-		return b.String()
+		return []string{dimStyle.Render("  (no messages)") + "\n"}
 	}
 
 	// Available width for message body wrapping.
@@ -898,26 +2770,65 @@ func (m uiModel) renderMessages() string {
 		bodyWidth = 20
 	}
 
+	unknown := unknownTargets(msgs, m.snap.Agents)
+	ranks := m.ranks()
+	// Pair against every loaded event so an ack hidden by the filter still
+	// counts.
+	markers := ackMarkers(m.snap.Events, isAck)
+	now := time.Now()
+	blocks := make([]string, 0, len(msgs))
 	for i := len(msgs) - 1; i >= 0; i-- {
+		var b strings.Builder
 		e := msgs[i]
 		from := msgFromStyle.Render(e.AgentID)
-		to := msgToStyle.Render(e.Target)
-		ts := dimStyle.Render(fmt.Sprintf("[L:%d]", e.LamportTS))
-		b.WriteString(fmt.Sprintf("  %s %s -> %s\n", ts, from, to))
+		to := renderTarget(e.Target, unknown[e.ID])
+		ts := dimStyle.Render(lamportTag(e.LamportTS, ranks, 0))
+		raw, tag := e.Body, ""
+		if m.decodeBodies {
+			if decoded, ok := maybeDecodeBody(raw); ok {
+				raw, tag = decoded, " "+dimStyle.Render("(decoded)")
+			}
+		}
+		if mark, ok := markers[e.ID]; ok {
+			tag = " " + mark + tag
+		}
+		// The wall-clock time is extra: drop it rather than wrap the line.
+		if !e.CreatedAt.IsZero() {
+			at := dimStyle.Render(m.wallClock(e.CreatedAt, now))
+			if lipgloss.Width(fmt.Sprintf("  %s %s %s -> %s%s", ts, at, from, to, tag)) <= m.width {
+				ts += " " + at
+			}
+		}
+		b.WriteString(fmt.Sprintf("  %s %s -> %s%s\n", ts, from, to, tag))
+		body := m.bodyText(raw)
+		if m.noWrap {
+			// Keep each body line intact and show the panned window of it.
+			for _, line := range strings.Split(body, "\n") {
+				b.WriteString(bodyIndent)
+				b.WriteString(highlightMatches(hSlice(line, m.hScroll, bodyWidth), m.searchQuery))
+				b.WriteRune('\n')
+			}
+			blocks = append(blocks, b.String())
+			continue
+		}
 		// Wrap message body to terminal width.
-		for _, line := range wrapText(e.Body, bodyWidth) {
+		for _, line := range wrapText(body, bodyWidth) {
 			b.WriteString(bodyIndent)
-			b.WriteString(line)
+			b.WriteString(highlightMatches(line, m.searchQuery))
 			b.WriteRune('\n')
 		}
+		blocks = append(blocks, b.String())
 	}
 
-	return b.String()
+	return blocks
 }
 
 // --- Locks view ---
 
 func (m uiModel) renderLocks() string {
+	if m.snap == nil {
+		return noData()
+	}
 	var b strings.Builder
 	b.WriteString(headerStyle.Render("Active Locks"))
 	b.WriteRune('\n')
@@ -925,6 +2836,7 @@ func (m uiModel) renderLocks() string {
 	if len(m.snap.Locks) == 0 {
 		b.WriteString(dimStyle.Render("  (no active locks)"))
 		b.WriteRune('\n')
+		b.WriteString(renderFooter("0 locks (0 expired)"))
 		return b.String()
 	}
 
@@ -932,36 +2844,121 @@ func (m uiModel) renderLocks() string {
 		"Path", "Agent", "Lamport", "Epoch", "TTL Remaining")))
 	b.WriteRune('\n')
 
+	var expired int
 	for _, l := range m.snap.Locks {
 		remaining := time.Until(l.ExpiresAt)
 		ttlStr := shortDuration(remaining)
 		if remaining < 0 {
 			ttlStr = unsafeStyle.Render("EXPIRED")
+			expired++
 		}
 		line := fmt.Sprintf("  %-32s %-14s %-8d %-8d %s",
 			l.Path, l.AgentID, l.LamportTS, l.Epoch, ttlStr)
 		b.WriteString(lockStyle.Render(line))
+		if cur, ok := holderEpochAhead(l, m.snap.Agents); ok {
+			b.WriteString("  ")
+			b.WriteString(concurrentStyle.Render(fmt.Sprintf("held at e%d, holder now at e%d", l.Epoch, cur)))
+		}
 		b.WriteRune('\n')
 	}
 
+	// Locks whose holder is gone and never released them. TTL expiry will
+	// eventually clean these up, but until then they block other agents.
+	now := time.Now()
+	var leaked []model.Lock
+	for _, l := range m.snap.Locks {
+		if isLeakedLock(l, m.snap.Agents, m.snap.Events, now) {
+			leaked = append(leaked, l)
+		}
+	}
+	if len(leaked) > 0 {
+		b.WriteRune('\n')
+		b.WriteString(headerStyle.Render("Leaked Locks"))
+		b.WriteRune('\n')
+		for _, l := range leaked {
+			holder := "holder gone"
+			if ag, ok := findAgent(m.snap.Agents, l.AgentID); ok {
+				holder = fmt.Sprintf("holder last seen %s ago", shortDuration(now.Sub(ag.LastSeen)))
+			}
+			b.WriteString(unsafeStyle.Render(fmt.Sprintf("  %-32s %-14s %s, no release",
+				l.Path, l.AgentID, holder)))
+			b.WriteRune('\n')
+		}
+	}
+
+	b.WriteString(renderFooter(fmt.Sprintf("%d locks (%d expired)", len(m.snap.Locks), expired)))
 	return b.String()
 }
 
+// holderEpochAhead returns the holder's current epoch when it has moved past
+// the epoch the lock was acquired in. Such a lock may be logically stale even
+// though its TTL has not run out.
+func holderEpochAhead(l model.Lock, agents []model.Agent) (int64, bool) {
+	ag, ok := findAgent(agents, l.AgentID)
+	if !ok || ag.Epoch <= l.Epoch {
+		return 0, false
+	}
+	return ag.Epoch, true
+}
+
+// isLeakedLock reports whether l looks abandoned: its holder is stale (or no
+// longer registered) and has logged no release of the path since acquiring
+// it. A held lock with an active holder is never considered leaked.
+func isLeakedLock(l model.Lock, agents []model.Agent, events []model.Event, now time.Time) bool {
+	if ag, ok := findAgent(agents, l.AgentID); ok && !isStale(ag, now) {
+		return false
+	}
+	for _, e := range events {
+		if e.Kind == model.EventLockRel && e.AgentID == l.AgentID &&
+			e.Target == l.Path && e.LamportTS >= l.LamportTS {
+			return false
+		}
+	}
+	return true
+}
+
 // --- Frontier view ---
 
 func (m uiModel) renderFrontier() string {
+	if m.snap == nil {
+		return noData()
+	}
 	var b strings.Builder
 	b.WriteString(headerStyle.Render("Naiad Frontier"))
 	b.WriteRune('\n')
+	if !m.snap.FrontierAvailable {
+		b.WriteString(dimStyle.Render("  Frontier unavailable: this clockmail store does not report pointstamps."))
+		b.WriteRune('\n')
+		return b.String()
+	}
+	if !usesEpochs(m.snap.Agents) {
+		b.WriteString(dimStyle.Render("  All agents are at e0/r0: this session does not use epochs/rounds."))
+		b.WriteRune('\n')
+	} else if label := m.rootBlockerLabel(); label != "" {
+		b.WriteString(unsafeStyle.Bold(true).Render("  \u26a0 " + label))
+		b.WriteRune('\n')
+	}
+	orphans, missing := frontierConsistency(m.snap.Pointstamps, m.snap.Agents)
+	for _, id := range orphans {
+		b.WriteString(unsafeStyle.Render(fmt.Sprintf("  orphan pointstamp: %s (no such agent)", id)))
+		b.WriteRune('\n')
+	}
+	for _, id := range missing {
+		b.WriteString(unsafeStyle.Render(fmt.Sprintf("  agent %s has no active pointstamp", id)))
+		b.WriteRune('\n')
+	}
 	b.WriteRune('\n')
 
 	// Global frontier.
 	b.WriteString(headerStyle.Render("  Global Antichain"))
 	b.WriteRune('\n')
-	if len(m.snap.Frontier) > 0 {
+	if m.frontierCompact {
+		b.WriteString(dimStyle.Render("    " + summarizeFrontier(m.snap.Frontier)))
+		b.WriteRune('\n')
+	} else if len(m.snap.Frontier) > 0 {
 		for _, p := range m.snap.Frontier {
 			line := fmt.Sprintf("    %s @ epoch=%d round=%d",
-				p.AgentID, p.Timestamp.Epoch, p.Timestamp.Round)
+				ellipsizeMiddle(p.AgentID, agentIDWidth), p.Timestamp.Epoch, p.Timestamp.Round)
 			b.WriteString(dimStyle.Render(line))
 			b.WriteRune('\n')
 		}
@@ -973,32 +2970,112 @@ func (m uiModel) renderFrontier() string {
 	b.WriteRune('\n')
 
 	// Per-agent status.
-	b.WriteString(headerStyle.Render("  Per-Agent Status"))
+	var safe, blocked int
+	for _, ag := range m.snap.Agents {
+		fs, ok := m.snap.FrontierStatus[ag.ID]
+		if !ok {
+			continue
+		}
+		if fs.SafeToFinalize {
+			safe++
+		} else {
+			blocked++
+		}
+	}
+	header := "  Per-Agent Status"
+	if m.blockedOnly {
+		header += fmt.Sprintf(" (%d blocked)", blocked)
+	}
+	b.WriteString(headerStyle.Render(header))
 	b.WriteRune('\n')
+	if m.blockedOnly && blocked == 0 {
+		b.WriteString(safeStyle.Render("    all agents safe to finalize"))
+		b.WriteRune('\n')
+	}
 	for _, ag := range m.snap.Agents {
 		fs, ok := m.snap.FrontierStatus[ag.ID]
 		if !ok {
 			continue
 		}
 		if fs.SafeToFinalize {
+			if m.blockedOnly {
+				continue
+			}
 			b.WriteString(fmt.Sprintf("    %s: %s (epoch=%d round=%d)\n",
-				agentActiveStyle.Render(ag.ID),
+				agentActiveStyle.Render(ellipsizeMiddle(ag.ID, agentIDWidth)),
 				safeStyle.Render("SAFE"),
 				ag.Epoch, ag.Round))
 		} else {
 			blockers := make([]string, 0, len(fs.BlockedBy))
 			for _, bl := range fs.BlockedBy {
 				blockers = append(blockers, fmt.Sprintf("%s@e%d/r%d",
-					bl.AgentID, bl.Timestamp.Epoch, bl.Timestamp.Round))
+					ellipsizeMiddle(bl.AgentID, agentIDWidth), bl.Timestamp.Epoch, bl.Timestamp.Round))
 			}
 			b.WriteString(fmt.Sprintf("    %s: %s by %s\n",
-				agentStaleStyle.Render(ag.ID),
+				agentStaleStyle.Render(ellipsizeMiddle(ag.ID, agentIDWidth)),
 				unsafeStyle.Render("BLOCKED"),
 				strings.Join(blockers, ", ")))
 		}
 	}
 
-	return b.String()
+	b.WriteString(renderFooter(fmt.Sprintf("%d safe / %d blocked", safe, blocked)))
+	return b.String()
+}
+
+// frontierConsistency cross-references the store's active pointstamps
+// with its agents: orphans are pointstamp holders that are not registered
+// agents, missing are agents holding no pointstamp. Either means the
+// frontier was computed from a different set of agents than the rest of
+// the snapshot shows. Both lists are sorted and free of duplicates.
+func frontierConsistency(points []model.Pointstamp, agents []model.Agent) (orphans, missing []string) {
+	known := make(map[string]bool, len(agents))
+	for _, ag := range agents {
+		known[ag.ID] = true
+	}
+	holders := make(map[string]bool, len(points))
+	for _, p := range points {
+		if !known[p.AgentID] && !holders[p.AgentID] {
+			orphans = append(orphans, p.AgentID)
+		}
+		holders[p.AgentID] = true
+	}
+	for _, ag := range agents {
+		if !holders[ag.ID] {
+			missing = append(missing, ag.ID)
+		}
+	}
+	sort.Strings(orphans)
+	sort.Strings(missing)
+	return orphans, missing
+}
+
+// summarizeFrontier renders the antichain on one line, grouping agents by
+// epoch: "e0: bob,carol | e1: alice (3 points)".
+func summarizeFrontier(points []model.Pointstamp) string {
+	if len(points) == 0 {
+		return "(empty)"
+	}
+	byEpoch := make(map[int64][]string)
+	var epochs []int64
+	for _, p := range points {
+		if _, ok := byEpoch[p.Timestamp.Epoch]; !ok {
+			epochs = append(epochs, p.Timestamp.Epoch)
+		}
+		byEpoch[p.Timestamp.Epoch] = append(byEpoch[p.Timestamp.Epoch], p.AgentID)
+	}
+	sortInt64s(epochs)
+
+	parts := make([]string, 0, len(epochs))
+	for _, ep := range epochs {
+		ids := byEpoch[ep]
+		sort.Strings(ids)
+		parts = append(parts, fmt.Sprintf("e%d: %s", ep, strings.Join(ids, ",")))
+	}
+	noun := "points"
+	if len(points) == 1 {
+		noun = "point"
+	}
+	return fmt.Sprintf("%s (%d %s)", strings.Join(parts, " | "), len(points), noun)
 }
 
 // --- Timeline view ---
@@ -1008,6 +3085,29 @@ var concurrentStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.Color("#F9E2AF")).
 	Bold(true)
 
+// concurrentPairStyle marks two-way concurrent groups, the common case.
+var concurrentPairStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#F9E2AF")).
+	Faint(true)
+
+// concurrentHeavyStyle marks groups of four or more concurrent events.
+var concurrentHeavyStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#F38BA8")).
+	Bold(true)
+
+// concurrentStyleFor returns the bracket style for a concurrent group of n
+// events, intensifying with size: 2 dim yellow, 3 bright yellow, 4+ red.
+func concurrentStyleFor(n int) lipgloss.Style {
+	switch {
+	case n >= 4:
+		return concurrentHeavyStyle
+	case n == 3:
+		return concurrentStyle
+	default:
+		return concurrentPairStyle
+	}
+}
+
 // causalStyle highlights causal link markers.
 var causalStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.Color("#A6E3A1"))
@@ -1051,6 +3151,29 @@ func isConcurrentGroup(g timelineGroup) bool {
 	return false
 }
 
+// concurrencyStats returns the mean and largest number of events per
+// Lamport timestamp: a rough measure of how much concurrency the system
+// exhibits (1.0 means fully sequential).
+func concurrencyStats(groups []timelineGroup) (avg float64, max int) {
+	if len(groups) == 0 {
+		return 0, 0
+	}
+	total := 0
+	for _, g := range groups {
+		total += len(g.events)
+		if len(g.events) > max {
+			max = len(g.events)
+		}
+	}
+	return float64(total) / float64(len(groups)), max
+}
+
+// concurrencyLine renders concurrencyStats as a dim stats line.
+func concurrencyLine(groups []timelineGroup) string {
+	avg, peak := concurrencyStats(groups)
+	return dimStyle.Render(fmt.Sprintf("  avg concurrency %.1f, max %d", avg, peak))
+}
+
 // buildCausalSet returns the set of event IDs that are message sends.
 // Message sends establish causal ordering: if A sends to B at TS=N,
 // then B's receipt (and all subsequent events) must have TS > N.
@@ -1064,41 +3187,272 @@ func buildCausalSet(events []model.Event) map[int64]bool {
 	return causal
 }
 
+// timelineKey is everything the Timeline's event list depends on: the
+// snapshot (replaced on each build and each widening load) and the
+// filters applied in timelineEvents.
+type timelineKey struct {
+	snap          *snapshot.DataSnapshot
+	focusPair     [2]string
+	kind          model.EventKind
+	agent, query  string
+	mergeLocks    bool
+	collapseBeats bool
+}
+
+func (m uiModel) timelineKey() timelineKey {
+	return timelineKey{
+		snap:          m.snap,
+		focusPair:     m.focusPair,
+		kind:          m.timelineKind,
+		agent:         m.filterAgent,
+		query:         m.searchQuery,
+		mergeLocks:    m.mergeLocks,
+		collapseBeats: m.collapseBeats,
+	}
+}
+
+// pairCountCache holds unorderedPairCount for the Timeline events of key.
+type pairCountCache struct {
+	key   timelineKey
+	n     int
+	valid bool
+}
+
+// cachePairCount recounts the Timeline's unordered pairs if the snapshot
+// or a filter changed since the last count. The count is quadratic in the
+// loaded events, so Update keeps it rather than every View recomputing it.
+func (m uiModel) cachePairCount() uiModel {
+	if m.snap == nil {
+		return m
+	}
+	key := m.timelineKey()
+	if m.pairCount.valid && m.pairCount.key == key {
+		return m
+	}
+	events, _ := m.timelineEvents()
+	m.pairCount = pairCountCache{key: key, n: unorderedPairCount(events), valid: true}
+	return m
+}
+
+// unorderedPairs returns unorderedPairCount(events) for the Timeline's
+// events, from the cache when it is current.
+func (m uiModel) unorderedPairs(events []model.Event) int {
+	if m.pairCount.valid && m.pairCount.key == m.timelineKey() {
+		return m.pairCount.n
+	}
+	return unorderedPairCount(events)
+}
+
+// unorderedPairCount counts pairs of events by different agents that no
+// chain of messages orders either way. Within one agent, events are ordered
+// by Lamport time; a message orders its send before the receive its target
+// is assumed to make (its next event, as in sendReceives) and so before
+// everything the target does after. Any cross-agent pair not connected by
+// such a chain is concurrent or of unknown order.
+func unorderedPairCount(events []model.Event) int {
+	sorted := slices.Clone(events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].LamportTS != sorted[j].LamportTS {
+			return sorted[i].LamportTS < sorted[j].LamportTS
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	sendsTo := make(map[int64][]int64) // receive event ID -> send event IDs
+	for send, recv := range sendReceives(sorted) {
+		sendsTo[recv.ID] = append(sendsTo[recv.ID], send)
+	}
+
+	// Vector clocks: vc[id][agent] counts the agent's events that
+	// happen-before-or-equal the event.
+	vc := make(map[int64]map[string]int, len(sorted))
+	latest := make(map[string]map[string]int)
+	for _, e := range sorted {
+		clock := maps.Clone(latest[e.AgentID])
+		if clock == nil {
+			clock = make(map[string]int)
+		}
+		for _, send := range sendsTo[e.ID] {
+			for a, n := range vc[send] {
+				clock[a] = max(clock[a], n)
+			}
+		}
+		clock[e.AgentID]++
+		latest[e.AgentID] = clock
+		vc[e.ID] = clock
+	}
+
+	before := func(e, f model.Event) bool { return vc[e.ID][e.AgentID] <= vc[f.ID][e.AgentID] }
+	n := 0
+	for i, e := range sorted {
+		for _, f := range sorted[i+1:] {
+			if e.AgentID != f.AgentID && !before(e, f) && !before(f, e) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// timelineItem is one entry in the Timeline: either a single event, or a
+// collapsed run of consecutive heartbeats from one agent.
+type timelineItem struct {
+	event model.Event   // the event, or the newest heartbeat of a run
+	run   []model.Event // non-nil for a collapsed heartbeat run (len >= 2)
+}
+
+// collapseHeartbeats folds runs of consecutive EventProgress events from the
+// same agent into single items. Any other event — including a heartbeat from
+// a different agent — ends the run. Input order is preserved.
+func collapseHeartbeats(events []model.Event) []timelineItem {
+	var items []timelineItem
+	for i := 0; i < len(events); {
+		e := events[i]
+		j := i + 1
+		if e.Kind == model.EventProgress {
+			for j < len(events) && events[j].Kind == model.EventProgress && events[j].AgentID == e.AgentID {
+				j++
+			}
+		}
+		if j-i > 1 {
+			items = append(items, timelineItem{event: events[j-1], run: events[i:j]})
+		} else {
+			items = append(items, timelineItem{event: e})
+		}
+		i = j
+	}
+	return items
+}
+
 func (m uiModel) renderTimeline() string {
-	var b strings.Builder
-	if m.filterAgent != "" {
-		b.WriteString(headerStyle.Render("Event Timeline"))
-		b.WriteString(dimStyle.Render(" "))
-		b.WriteString(msgFromStyle.Render(fmt.Sprintf("[filter: %s]", m.filterAgent)))
-	} else {
-		b.WriteString(headerStyle.Render("Event Timeline"))
+	if m.snap == nil {
+		return noData()
 	}
-	b.WriteRune('\n')
+	content, _ := m.timelineLayout()
+	return content
+}
 
-	// Apply agent filter.
-	events := m.snap.Events
-	if m.filterAgent != "" {
+// timelineEvents returns the events the Timeline shows, in Lamport order:
+// the snapshot's events after the focus pair, kind, agent and body search
+// filters and, when collapseBeats is on, with each heartbeat run replaced
+// by its newest event. When mergeLocks is on, each released lock is likewise represented
+// by its unlock, with the run holding [lock, unlock]. runs maps those
+// representative event IDs to their full run.
+//
+// Every filter is applied here, before grouping: concurrency brackets are
+// computed from exactly this slice, so they never span a hidden event.
+func (m uiModel) timelineEvents() (events []model.Event, runs map[int64][]model.Event) {
+	events = m.pairEvents(m.snap.Events)
+	if m.timelineKind != "" {
+		events = filterEvents(events, m.timelineKind)
+	}
+	if m.filterAgent != "" || m.searchQuery != "" {
 		var filtered []model.Event
 		for _, e := range events {
-			if eventMatchesAgent(e, m.filterAgent) {
+			if eventMatchesAgent(e, m.filterAgent) && bodyMatches(e, m.searchQuery) {
 				filtered = append(filtered, e)
 			}
 		}
 		events = filtered
 	}
 
+	runs = make(map[int64][]model.Event)
+	if m.mergeLocks {
+		acquires := pairLockEvents(events)
+		merged := make([]model.Event, 0, len(events))
+		released := make(map[int64]bool, len(acquires))
+		for _, acq := range acquires {
+			released[acq.ID] = true
+		}
+		for _, e := range events {
+			if released[e.ID] {
+				continue // shown at its unlock
+			}
+			if acq, ok := acquires[e.ID]; ok {
+				runs[e.ID] = []model.Event{acq, e}
+			}
+			merged = append(merged, e)
+		}
+		events = merged
+	}
+
+	// Collapse heartbeat runs: each run is represented by its newest event,
+	// which keeps the stream sorted for grouping.
+	if m.collapseBeats {
+		items := collapseHeartbeats(events)
+		events = make([]model.Event, 0, len(items))
+		for _, it := range items {
+			if it.run != nil {
+				runs[it.event.ID] = it.run
+			}
+			events = append(events, it.event)
+		}
+	}
+	return events, runs
+}
+
+// concurrentGroupLines returns the first rendered Timeline line of every
+// concurrent group, top to bottom in the current order, for n/N navigation.
+func (m uiModel) concurrentGroupLines() []int {
+	_, owners := m.timelineLayout()
+	events, _ := m.timelineEvents()
+	groups := groupByLamport(events)
+	var lines []int
+	// Walk the groups in the order timelineLayout renders them.
+	for i := range groups {
+		g := groups[len(groups)-1-i]
+		if m.timelineAscending {
+			g = groups[i]
+		}
+		if !isConcurrentGroup(g) {
+			continue
+		}
+		if pos := firstLineOf(owners, g.events[0].ID); pos >= 0 {
+			lines = append(lines, pos)
+		}
+	}
+	return lines
+}
+
+// timelineLayout renders the Timeline and reports, for each output line, the
+// ID of the event that produced it (0 for the header and legend). The line
+// owners let a scroll position be anchored to an event across reflows.
+func (m uiModel) timelineLayout() (string, []int64) {
+	var b strings.Builder
+	if m.filterAgent != "" {
+		b.WriteString(headerStyle.Render("Event Timeline"))
+		b.WriteString(dimStyle.Render(" "))
+		b.WriteString(msgFromStyle.Render(fmt.Sprintf("[filter: %s]", m.filterAgent)))
+	} else {
+		b.WriteString(headerStyle.Render("Event Timeline"))
+	}
+	if m.timelineKind != "" {
+		b.WriteString(dimStyle.Render(" "))
+		b.WriteString(msgFromStyle.Render(fmt.Sprintf("[kind: %s]", m.timelineKind)))
+	}
+	b.WriteString(m.searchTag())
+	b.WriteString(m.searchedBackNote())
+	b.WriteString(m.coverageNote())
+	b.WriteRune('\n')
+
+	events, runs := m.timelineEvents()
 	if len(events) == 0 {
-		if m.filterAgent != "" {
+		if m.searchQuery != "" {
+			b.WriteString(dimStyle.Render(fmt.Sprintf("  (no events containing %q)", m.searchQuery)))
+		} else if m.filterAgent != "" {
 			b.WriteString(dimStyle.Render(fmt.Sprintf("  (no events involving %s)", m.filterAgent)))
 		} else {
 			b.WriteString(dimStyle.Render("  (no events)"))
 		}
 		b.WriteRune('\n')
-		return b.String()
+		return b.String(), nil
 	}
 
 	// Legend explaining Lamport ordering vs causality.
-	b.WriteString(dimStyle.Render("  Sorted by Lamport clock (L). Only messages ("))
+	order := "newest"
+	if m.timelineAscending {
+		order = "oldest"
+	}
+	b.WriteString(dimStyle.Render("  Sorted by Lamport clock (L), " + order + " first. Only messages ("))
 	b.WriteString(causalStyle.Render("\u2192"))
 	b.WriteString(dimStyle.Render(") prove causal ordering."))
 	b.WriteRune('\n')
@@ -1108,11 +3462,25 @@ func (m uiModel) renderTimeline() string {
 	b.WriteRune('\n')
 	b.WriteString(dimStyle.Render("  Other cross-agent events may also be concurrent (L order \u2260 causal order)."))
 	b.WriteRune('\n')
-	b.WriteRune('\n')
+	if n := m.unorderedPairs(events); n > 0 {
+		noun := "pairs"
+		if n == 1 {
+			noun = "pair"
+		}
+		b.WriteString(concurrentStyle.Render(fmt.Sprintf("  %d event %s with no proven ordering", n, noun)))
+		b.WriteRune('\n')
+	}
 
 	// Group events by Lamport timestamp.
 	groups := groupByLamport(events)
+	b.WriteString(concurrencyLine(groups))
+	b.WriteRune('\n')
+	b.WriteRune('\n')
 	causalIDs := buildCausalSet(events)
+	unknown := unknownTargets(events, m.snap.Agents)
+	// Pair against every loaded event so a reply hidden by the filter still
+	// counts as one.
+	receives := sendReceives(m.snap.Events)
 
 	// Body lines use a modest indent to show they belong to the message above
 	// without wasting horizontal space on deep alignment.
@@ -1122,24 +3490,42 @@ func (m uiModel) renderTimeline() string {
 		bodyWidth = 20
 	}
 
-	// Show most recent first.
-	for gi := len(groups) - 1; gi >= 0; gi-- {
-		g := groups[gi]
-		concurrent := isConcurrentGroup(g)
+	owners := make([]int64, strings.Count(b.String(), "\n"))
 
+	// Wall-clock dividers: Lamport order says nothing about real elapsed
+	// time, so mark where consecutive entries fall in different minutes.
+	now := time.Now()
+	var prevBucket time.Time
+	ranks := m.ranks()
+
+	// Epoch transitions come from every loaded event, so a filter can't
+	// make an old epoch look like a new advance.
+	var advances map[int64]int64
+	if m.epochMarkers {
+		advances = epochTransitions(m.snap.Events)
+	}
+
+	// Most recent first, unless o flipped the order.
+	for i := range groups {
+		g := groups[len(groups)-1-i]
+		if m.timelineAscending {
+			g = groups[i]
+		}
+		concurrent := isConcurrentGroup(g)
+		bracketStyle := concurrentStyleFor(len(g.events))
 		for ei, e := range g.events {
-			ts := dimStyle.Render(fmt.Sprintf("[L:%-4d]", e.LamportTS))
+			ts := dimStyle.Render(lamportTag(e.LamportTS, ranks, 4))
 			agent := msgFromStyle.Render(e.AgentID)
 
 			// Concurrency marker: show bracket for multi-agent groups.
 			marker := "   "
 			if concurrent {
 				if ei == 0 {
-					marker = concurrentStyle.Render(" \u2553 ") // ╓ top
+					marker = bracketStyle.Render(" \u2553 ") // ╓ top
 				} else if ei == len(g.events)-1 {
-					marker = concurrentStyle.Render(" \u2559 ") // ╙ bottom
+					marker = bracketStyle.Render(" \u2559 ") // ╙ bottom
 				} else {
-					marker = concurrentStyle.Render(" \u2551 ") // ║ middle
+					marker = bracketStyle.Render(" \u2551 ") // ║ middle
 				}
 			}
 
@@ -1149,34 +3535,452 @@ func (m uiModel) renderTimeline() string {
 				causalMark = causalStyle.Render("\u2192 ") // →
 			}
 
-			switch e.Kind {
-			case model.EventMsg:
-				// Header line: timestamp, markers, agent, and target.
-				b.WriteString(fmt.Sprintf("  %s%s%s%s -> %s\n",
-					ts, marker, causalMark, agent, msgToStyle.Render(e.Target)))
-				// Body wrapped below with indent.
-				for _, line := range wrapText(e.Body, bodyWidth) {
-					b.WriteString(bodyIndent)
-					b.WriteString(line)
-					b.WriteRune('\n')
-				}
-			case model.EventLockReq:
-				b.WriteString(fmt.Sprintf("  %s%s%s%s %s\n",
-					ts, marker, causalMark, agent, lockStyle.Render("lock "+e.Target)))
-			case model.EventLockRel:
-				b.WriteString(fmt.Sprintf("  %s%s%s%s %s\n",
-					ts, marker, causalMark, agent, dimStyle.Render("unlock "+e.Target)))
-			case model.EventProgress:
-				b.WriteString(fmt.Sprintf("  %s%s%s%s %s\n",
-					ts, marker, causalMark, agent, dimStyle.Render(fmt.Sprintf("heartbeat e%d/r%d", e.Epoch, e.Round))))
-			default:
-				b.WriteString(fmt.Sprintf("  %s%s%s%s %s %s %s\n",
-					ts, marker, causalMark, agent, string(e.Kind), e.Target, e.Body))
+			if !e.CreatedAt.IsZero() {
+				bucket := e.CreatedAt.Truncate(timelineBucket)
+				if !prevBucket.IsZero() && !bucket.Equal(prevBucket) {
+					b.WriteString(dimStyle.Render("  \u2500\u2500\u2500 " + agoLabel(now.Sub(bucket)) + " \u2500\u2500\u2500"))
+					b.WriteRune('\n')
+					owners = append(owners, 0)
+				}
+				prevBucket = bucket
+			}
+
+			if epoch, ok := advances[e.ID]; ok {
+				b.WriteString(epochMarkerStyle.Render(fmt.Sprintf("  \u2500\u2500 %s advanced to epoch %d \u2500\u2500", e.AgentID, epoch)))
+				b.WriteRune('\n')
+				owners = append(owners, e.ID)
+			}
+
+			// Count what the group shows after filtering; singletons need no
+			// header. It belongs to the first event so n/N lands on it.
+			if ei == 0 && len(g.events) > 1 {
+				b.WriteString(dimStyle.Render(fmt.Sprintf("  L:%d \u2014 %d events", g.lamportTS, len(g.events))))
+				b.WriteRune('\n')
+				owners = append(owners, e.ID)
+			}
+
+			prefix := fmt.Sprintf("  %s%s%s%s", ts, marker, causalMark, agent)
+			var eb strings.Builder
+			e.Body = m.bodyText(e.Body)
+			var reply string
+			if e.Kind == model.EventMsg && !unknown[e.ID] {
+				next, ok := receives[e.ID]
+				reply = replyNote(e, next, ok)
+			}
+			writeTimelineEntry(&eb, e, runs[e.ID], unknown[e.ID], reply, prefix, bodyIndent, bodyWidth)
+			chunk := eb.String()
+			if m.searchQuery != "" && e.Kind == model.EventMsg {
+				// Body lines are the entry's only unstyled ones, so only
+				// they pick up highlights.
+				lines := strings.Split(chunk, "\n")
+				for i := range lines {
+					lines[i] = highlightMatches(lines[i], m.searchQuery)
+				}
+				chunk = strings.Join(lines, "\n")
+			}
+			b.WriteString(chunk)
+			for n := strings.Count(chunk, "\n"); n > 0; n-- {
+				owners = append(owners, e.ID)
+			}
+		}
+	}
+
+	return b.String(), owners
+}
+
+var epochMarkerStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#F9E2AF")).
+	Bold(true)
+
+var searchMatchStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#F9E2AF")).
+	Bold(true).
+	Underline(true)
+
+// epochTransitions returns, for each heartbeat that moved its agent to a
+// higher epoch than its previous heartbeat, the new epoch. An agent's
+// first heartbeat among events only sets its baseline. Events must be in
+// Lamport order.
+func epochTransitions(events []model.Event) map[int64]int64 {
+	last := make(map[string]int64)
+	out := make(map[int64]int64)
+	for _, e := range events {
+		if e.Kind != model.EventProgress {
+			continue
+		}
+		prev, seen := last[e.AgentID]
+		if seen && e.Epoch > prev {
+			out[e.ID] = e.Epoch
+		}
+		if !seen || e.Epoch > prev {
+			last[e.AgentID] = e.Epoch
+		}
+	}
+	return out
+}
+
+// sendReceives pairs each message with the receive heuristic: the
+// target's next event in Lamport order after the send. Messages whose
+// target has done nothing since are absent.
+func sendReceives(events []model.Event) map[int64]model.Event {
+	byAgent := make(map[string][]model.Event)
+	for _, e := range events {
+		byAgent[e.AgentID] = append(byAgent[e.AgentID], e)
+	}
+	for _, evs := range byAgent {
+		sort.SliceStable(evs, func(i, j int) bool { return evs[i].LamportTS < evs[j].LamportTS })
+	}
+	out := make(map[int64]model.Event)
+	for _, e := range events {
+		if e.Kind != model.EventMsg || e.Target == "" || e.Target == e.AgentID {
+			continue
+		}
+		evs := byAgent[e.Target]
+		i := sort.Search(len(evs), func(i int) bool { return evs[i].LamportTS > e.LamportTS })
+		if i < len(evs) {
+			out[e.ID] = evs[i]
+		}
+	}
+	return out
+}
+
+// replyNote annotates a send with what its target did next: answered it
+// (a message straight back), moved on to something else, or nothing yet.
+func replyNote(send, next model.Event, ok bool) string {
+	switch {
+	case !ok:
+		return dimStyle.Render(fmt.Sprintf("(reply expected from %s)", send.Target))
+	case next.Kind == model.EventMsg && next.Target == send.AgentID:
+		return causalStyle.Render(fmt.Sprintf("\u21a9 answered at L:%d", next.LamportTS))
+	}
+	return dimStyle.Render(fmt.Sprintf("(%s next active at L:%d)", send.Target, next.LamportTS))
+}
+
+// unknownTargets returns the IDs of message events whose target is not a
+// registered agent, usually a typo or an agent that never joined.
+func unknownTargets(events []model.Event, agents []model.Agent) map[int64]bool {
+	known := make(map[string]bool, len(agents))
+	for _, ag := range agents {
+		known[ag.ID] = true
+	}
+	out := make(map[int64]bool)
+	for _, e := range events {
+		if e.Kind == model.EventMsg && e.Target != "" && !known[e.Target] {
+			out[e.ID] = true
+		}
+	}
+	return out
+}
+
+// lamportGapThreshold is the jump between consecutive Lamport values
+// (across all agents) above which the session is flagged as having a gap.
+const lamportGapThreshold = 100
+
+// lamportGaps returns the [from, to] pairs of consecutive distinct Lamport
+// timestamps that are more than threshold apart, in ascending order. A
+// large jump usually means a lost segment of history or a misbehaving
+// clock.
+func lamportGaps(events []model.Event, threshold int64) [][2]int64 {
+	ts := make([]int64, 0, len(events))
+	for _, e := range events {
+		ts = append(ts, e.LamportTS)
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	var gaps [][2]int64
+	for i := 1; i < len(ts); i++ {
+		if ts[i]-ts[i-1] > threshold {
+			gaps = append(gaps, [2]int64{ts[i-1], ts[i]})
+		}
+	}
+	return gaps
+}
+
+// lamportRanks maps each distinct Lamport timestamp in events to its
+// 1-based position in ascending order. Equal timestamps share a rank, and
+// gaps between values leave no gaps between ranks: {3, 3, 10, 50} ranks
+// as 3→1, 10→2, 50→3.
+func lamportRanks(events []model.Event) map[int64]int {
+	seen := make(map[int64]bool, len(events))
+	ts := make([]int64, 0, len(events))
+	for _, e := range events {
+		if !seen[e.LamportTS] {
+			seen[e.LamportTS] = true
+			ts = append(ts, e.LamportTS)
+		}
+	}
+	slices.Sort(ts)
+	ranks := make(map[int64]int, len(ts))
+	for i, t := range ts {
+		ranks[t] = i + 1
+	}
+	return ranks
+}
+
+// ranks returns lamportRanks of the loaded events when # is on, else nil.
+func (m uiModel) ranks() map[int64]int {
+	if !m.showRanks {
+		return nil
+	}
+	return lamportRanks(m.snap.Events)
+}
+
+// lamportTag formats a Lamport label, "[L:42]" or with ranks "[L:42 (#7)]",
+// left-aligning the inside to pad columns.
+func lamportTag(ts int64, ranks map[int64]int, pad int) string {
+	inner := strconv.FormatInt(ts, 10)
+	if r, ok := ranks[ts]; ok {
+		inner += fmt.Sprintf(" (#%d)", r)
+	}
+	return fmt.Sprintf("[L:%-*s]", pad, inner)
+}
+
+// renderGapBanner returns a one-line warning naming the first Lamport gap,
+// or "" if there is none.
+func (m uiModel) renderGapBanner() string {
+	if m.snap == nil {
+		return ""
+	}
+	gaps := lamportGaps(m.snap.Events, lamportGapThreshold)
+	if len(gaps) == 0 {
+		return ""
+	}
+	text := fmt.Sprintf("\u26a0 Lamport gap: L:%d \u2192 L:%d with nothing between", gaps[0][0], gaps[0][1])
+	if len(gaps) > 1 {
+		text += fmt.Sprintf(" (+%d more)", len(gaps)-1)
+	}
+	return unsafeStyle.Render(text)
+}
+
+// renderFreshBanner explains an empty session: a database with no agents
+// and no events is fresh, not broken. It disappears once data appears.
+func (m uiModel) renderFreshBanner() string {
+	if m.snap == nil {
+		return ""
+	}
+	if m.snap.TotalEvents > 0 || len(m.snap.Agents) > 0 {
+		return ""
+	}
+	return safeStyle.Render(fmt.Sprintf("connected to %s \u2014 no activity yet, waiting\u2026", m.dbPath))
+}
+
+// renderTarget renders a message recipient, marking unregistered ones.
+func renderTarget(target string, unknown bool) string {
+	if unknown {
+		return msgToStyle.Render(target) + " " + unsafeStyle.Render("\u26a0 unknown target")
+	}
+	return msgToStyle.Render(target)
+}
+
+// defaultMaxBody is the default --max-body: bodies longer than this many
+// bytes are clipped until expanded, so one huge message can't flood the
+// wrapper with thousands of lines.
+const defaultMaxBody = 2048
+
+// clipBody returns the first max bytes of body (backed off to a rune
+// boundary) and the number of characters cut. Bodies within max, or a max
+// of 0, are returned whole.
+func clipBody(body string, max int) (string, int) {
+	if max <= 0 || len(body) <= max {
+		return body, 0
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return body[:cut], utf8.RuneCountInString(body[cut:])
+}
+
+// bodyText is the message body as rendered by Messages and Timeline: clipped
+// to maxBody with a note, unless bodies are expanded.
+func (m uiModel) bodyText(body string) string {
+	if m.expandBodies {
+		if pretty, ok := prettyIfJSON(body); ok {
+			return pretty
+		}
+		return body
+	}
+	clipped, hidden := clipBody(body, m.maxBody)
+	if hidden == 0 {
+		return body
+	}
+	return fmt.Sprintf("%s\n\u2026 (+%d chars, press Enter to expand)", clipped, hidden)
+}
+
+// prettyIfJSON returns body indented two spaces per level when it is a
+// JSON object or array. Scalars and anything that fails to parse are
+// returned unchanged with ok false.
+func prettyIfJSON(s string) (string, bool) {
+	t := strings.TrimSpace(s)
+	if t == "" || (t[0] != '{' && t[0] != '[') {
+		return s, false
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(t), "", "  "); err != nil {
+		return s, false
+	}
+	return buf.String(), true
+}
+
+// minEncodedLen is the shortest body maybeDecodeBody will decode; shorter
+// ones are too often ordinary words that happen to be valid base64.
+const minEncodedLen = 16
+
+// maybeDecodeBody returns the decoded form of a body that looks like hex or
+// base64 (standard or URL alphabet, padded or not) and decodes to readable
+// UTF-8 text. Anything else is returned unchanged with ok false.
+func maybeDecodeBody(s string) (string, bool) {
+	t := strings.TrimSpace(s)
+	if len(t) < minEncodedLen || strings.ContainsAny(t, " \t\r\n") {
+		return s, false
+	}
+	var data []byte
+	if len(t)%2 == 0 && strings.Trim(t, "0123456789abcdefABCDEF") == "" {
+		data, _ = hex.DecodeString(t)
+	} else {
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+			if d, err := enc.DecodeString(t); err == nil {
+				data = d
+				break
+			}
+		}
+	}
+	if len(data) == 0 || !utf8.Valid(data) {
+		return s, false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return s, false
+		}
+	}
+	return string(data), true
+}
+
+// clockTime returns t in the zone wall-clock times are shown in: local
+// time, or UTC with --utc.
+func (m uiModel) clockTime(t time.Time) time.Time {
+	if m.utc {
+		return t.UTC()
+	}
+	return t.Local()
+}
+
+// wallClock labels when a message was sent for its Messages header:
+// relative ("3m ago") within the last hour, else the time of day.
+func (m uiModel) wallClock(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < 0 || d >= time.Hour:
+		return m.clockTime(t).Format("15:04:05")
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	default:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	}
+}
+
+// timelineBucket is the wall-clock granularity of Timeline dividers.
+const timelineBucket = time.Minute
+
+// agoLabel renders an elapsed duration for Timeline dividers, e.g.
+// "2 minutes ago" or "3 hours ago".
+func agoLabel(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < 2*time.Minute:
+		return "1 minute ago"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(d.Minutes()))
+	case d < 2*time.Hour:
+		return "1 hour ago"
+	default:
+		return fmt.Sprintf("%d hours ago", int(d.Hours()))
+	}
+}
+
+// writeTimelineEntry writes one Timeline entry after its prefix (timestamp,
+// markers, agent): the event itself, or a summary when run is a collapsed
+// heartbeat run. unknown flags a message to an unregistered target.
+func writeTimelineEntry(b *strings.Builder, e model.Event, run []model.Event, unknown bool, reply, prefix, bodyIndent string, bodyWidth int) {
+	if run != nil && run[0].Kind == model.EventLockReq {
+		acq := run[0]
+		b.WriteString(fmt.Sprintf("%s %s\n", prefix, lockStyle.Render(fmt.Sprintf("held %s (L:%d\u2013%d, %d ticks)",
+			e.Target, acq.LamportTS, e.LamportTS, e.LamportTS-acq.LamportTS))))
+		return
+	}
+	if run != nil {
+		b.WriteString(fmt.Sprintf("%s: %s\n", prefix, dimStyle.Render(fmt.Sprintf("%d heartbeats (L:%d\u2013%d)",
+			len(run), run[0].LamportTS, run[len(run)-1].LamportTS))))
+		return
+	}
+
+	switch e.Kind {
+	case model.EventMsg:
+		// Header line: timestamp, markers, agent, and target.
+		b.WriteString(fmt.Sprintf("%s -> %s", prefix, renderTarget(e.Target, unknown)))
+		if reply != "" {
+			b.WriteString(" " + reply)
+		}
+		b.WriteRune('\n')
+		// Body wrapped below with indent.
+		for _, line := range wrapText(e.Body, bodyWidth) {
+			b.WriteString(bodyIndent)
+			b.WriteString(line)
+			b.WriteRune('\n')
+		}
+	case model.EventLockReq:
+		b.WriteString(fmt.Sprintf("%s %s\n", prefix, lockStyle.Render("lock "+e.Target)))
+	case model.EventLockRel:
+		b.WriteString(fmt.Sprintf("%s %s\n", prefix, dimStyle.Render("unlock "+e.Target)))
+	case model.EventProgress:
+		b.WriteString(fmt.Sprintf("%s %s\n", prefix, dimStyle.Render(fmt.Sprintf("heartbeat e%d/r%d", e.Epoch, e.Round))))
+	default:
+		b.WriteString(fmt.Sprintf("%s %s %s %s\n", prefix, string(e.Kind), e.Target, e.Body))
+	}
+}
+
+// pairLockEvents matches each unlock in events to the latest earlier lock
+// of the same target by the same agent, returning the lock keyed by the
+// unlock's ID. Locks never released are left out.
+func pairLockEvents(events []model.Event) map[int64]model.Event {
+	open := make(map[[2]string]model.Event)
+	pairs := make(map[int64]model.Event)
+	for _, e := range events {
+		k := [2]string{e.AgentID, e.Target}
+		switch e.Kind {
+		case model.EventLockReq:
+			open[k] = e
+		case model.EventLockRel:
+			if acq, ok := open[k]; ok {
+				pairs[e.ID] = acq
+				delete(open, k)
 			}
 		}
 	}
+	return pairs
+}
 
-	return b.String()
+// lineOwner returns the event ID owning line pos of a timelineLayout, or 0
+// if the line belongs to no event. Positions past the end are clamped.
+func lineOwner(owners []int64, pos int) int64 {
+	if len(owners) == 0 || pos < 0 {
+		return 0
+	}
+	if pos >= len(owners) {
+		pos = len(owners) - 1
+	}
+	return owners[pos]
+}
+
+// firstLineOf returns the first line of a timelineLayout owned by event id,
+// or -1 if the event is not rendered.
+func firstLineOf(owners []int64, id int64) int {
+	for i, o := range owners {
+		if o == id {
+			return i
+		}
+	}
+	return -1
 }
 
 // --- Lamport Diagram view ---
@@ -1202,6 +4006,40 @@ var diagramEventStyle = lipgloss.NewStyle().
 var diagramMsgStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.Color("#F9E2AF"))
 
+// defaultGlyphs are the built-in Diagram cell labels per event kind.
+var defaultGlyphs = map[model.EventKind]string{
+	model.EventMsg:      ">",
+	model.EventLockReq:  "L",
+	model.EventLockRel:  "U",
+	model.EventProgress: "*",
+}
+
+// defaultOtherGlyph labels event kinds without a glyph.
+const defaultOtherGlyph = "?"
+
+// diagramGlyphs and otherGlyph are the labels in effect: the defaults,
+// overridden by the config file's "glyphs".
+var (
+	diagramGlyphs = defaultGlyphs
+	otherGlyph    = defaultOtherGlyph
+)
+
+// kindLabel returns the Diagram cell label for events of kind k.
+func kindLabel(k model.EventKind) string {
+	if g, ok := diagramGlyphs[k]; ok {
+		return g
+	}
+	return otherGlyph
+}
+
+// diagramOrder is the left-to-right order of Diagram columns.
+type diagramOrder int
+
+const (
+	diagramByRegistration diagramOrder = iota
+	diagramByActivity                  // most events first
+)
+
 // diagramRow represents one Lamport timestamp row in the diagram.
 type diagramRow struct {
 	lamportTS int64
@@ -1223,11 +4061,27 @@ type diagramMsg struct {
 }
 
 // buildDiagramData constructs the row/column data for the space-time diagram.
-func buildDiagramData(agents []model.Agent, events []model.Event) ([]string, []diagramRow) {
+// If allowed is non-empty, only those agents become columns: events of other
+// agents are dropped, and so are message arrows to them.
+func buildDiagramData(agents []model.Agent, events []model.Event, allowed map[string]bool, order diagramOrder) ([]string, []diagramRow) {
+	visible := func(id string) bool { return len(allowed) == 0 || allowed[id] }
+
 	// Collect unique agent IDs in registration order.
 	agentOrder := make([]string, 0, len(agents))
 	for _, ag := range agents {
-		agentOrder = append(agentOrder, ag.ID)
+		if visible(ag.ID) {
+			agentOrder = append(agentOrder, ag.ID)
+		}
+	}
+	if order == diagramByActivity {
+		counts := make(map[string]int, len(agentOrder))
+		for _, e := range events {
+			counts[e.AgentID]++
+		}
+		// Stable, so equally busy agents keep registration order.
+		sort.SliceStable(agentOrder, func(i, j int) bool {
+			return counts[agentOrder[i]] > counts[agentOrder[j]]
+		})
 	}
 
 	// Build rows indexed by Lamport timestamp.
@@ -1235,6 +4089,9 @@ func buildDiagramData(agents []model.Agent, events []model.Event) ([]string, []d
 	var timestamps []int64
 
 	for _, e := range events {
+		if !visible(e.AgentID) {
+			continue
+		}
 		ts := e.LamportTS
 		row, ok := rowMap[ts]
 		if !ok {
@@ -1246,25 +4103,10 @@ func buildDiagramData(agents []model.Agent, events []model.Event) ([]string, []d
 			timestamps = append(timestamps, ts)
 		}
 
-		// Determine cell label.
-		var label string
-		switch e.Kind {
-		case model.EventMsg:
-			label = ">"
-		case model.EventLockReq:
-			label = "L"
-		case model.EventLockRel:
-			label = "U"
-		case model.EventProgress:
-			label = "*"
-		default:
-			label = "?"
-		}
-
-		row.cells[e.AgentID] = diagramCell{event: e, label: label}
+		row.cells[e.AgentID] = diagramCell{event: e, label: kindLabel(e.Kind)}
 
 		// Track messages for arrows.
-		if e.Kind == model.EventMsg && e.Target != "" {
+		if e.Kind == model.EventMsg && e.Target != "" && visible(e.Target) {
 			row.messages = append(row.messages, diagramMsg{
 				fromAgent: e.AgentID,
 				toAgent:   e.Target,
@@ -1284,6 +4126,75 @@ func buildDiagramData(agents []model.Agent, events []model.Event) ([]string, []d
 	return agentOrder, rows
 }
 
+// diagramColWidth is the width of each agent column in the Diagram.
+const diagramColWidth = 14
+
+// diagramData returns the Diagram's columns and rows after pins and the
+// focus pair.
+func (m uiModel) diagramData() ([]string, []diagramRow) {
+	allowed := m.pinned
+	if a, b, ok := m.pair(); ok {
+		allowed = map[string]bool{a: true, b: true}
+	}
+	return buildDiagramData(m.snap.Agents, m.pairEvents(m.snap.Events), allowed, m.diagramOrder)
+}
+
+// diagramWindow returns the [start, end) range of agent columns drawn when
+// panned offset columns right: as many as fit beside the timestamp column
+// (at least one), with offset clamped so the last column stays reachable
+// and no space is wasted past it.
+func diagramWindow(total, offset, width, tsColWidth int) (start, end int) {
+	fit := total
+	if width > 0 {
+		fit = max(1, (width-2-tsColWidth)/diagramColWidth)
+	}
+	start = min(max(0, offset), max(0, total-fit))
+	return start, min(total, start+fit)
+}
+
+// panDiagram moves the Diagram's column window by delta, keeping it in
+// range.
+func (m uiModel) panDiagram(delta int) uiModel {
+	agentOrder, rows := m.diagramData()
+	rows, _, _, _ = capDiagramRows(rows, m.diagramRows)
+	if len(rows) == 0 {
+		m.diagramCol = 0
+		return m
+	}
+	tsColWidth := len(strconv.FormatInt(rows[len(rows)-1].lamportTS, 10)) + 2
+	m.diagramCol, _ = diagramWindow(len(agentOrder), m.diagramCol+delta, m.width, tsColWidth)
+	return m
+}
+
+// defaultDiagramRows is the default --diagram-rows.
+const defaultDiagramRows = 200
+
+// capDiagramRows keeps the newest max rows. hidden is how many older rows
+// were dropped; first and last are their Lamport range. A max of 0 keeps
+// every row.
+func capDiagramRows(rows []diagramRow, max int) (kept []diagramRow, hidden int, first, last int64) {
+	if max <= 0 || len(rows) <= max {
+		return rows, 0, 0, 0
+	}
+	hidden = len(rows) - max
+	return rows[hidden:], hidden, rows[0].lamportTS, rows[hidden-1].lamportTS
+}
+
+// togglePin returns a copy of pinned with id added or removed. The copy
+// keeps earlier uiModel values unaffected, matching Update's value semantics.
+func togglePin(pinned map[string]bool, id string) map[string]bool {
+	out := make(map[string]bool, len(pinned)+1)
+	for k := range pinned {
+		out[k] = true
+	}
+	if pinned[id] {
+		delete(out, id)
+	} else {
+		out[id] = true
+	}
+	return out
+}
+
 // sortInt64s sorts a slice of int64 in ascending order.
 func sortInt64s(s []int64) {
 	for i := 1; i < len(s); i++ {
@@ -1304,6 +4215,9 @@ func agentIndex(agents []string, id string) int {
 }
 
 func (m uiModel) renderDiagram() string {
+	if m.snap == nil {
+		return noData()
+	}
 	var b strings.Builder
 
 	b.WriteString(headerStyle.Render("Lamport Space-Time Diagram"))
@@ -1319,37 +4233,70 @@ func (m uiModel) renderDiagram() string {
 	b.WriteString(dimStyle.Render("  Processes as columns, Lamport time increasing downward (cf. Lamport 1978, Fig 1)."))
 	b.WriteRune('\n')
 	b.WriteString(dimStyle.Render("  "))
-	b.WriteString(diagramEventStyle.Render(">"))
+	b.WriteString(diagramEventStyle.Render(kindLabel(model.EventMsg)))
 	b.WriteString(dimStyle.Render("=msg "))
-	b.WriteString(diagramEventStyle.Render("*"))
+	b.WriteString(diagramEventStyle.Render(kindLabel(model.EventProgress)))
 	b.WriteString(dimStyle.Render("=heartbeat "))
-	b.WriteString(diagramEventStyle.Render("L"))
+	b.WriteString(diagramEventStyle.Render(kindLabel(model.EventLockReq)))
 	b.WriteString(dimStyle.Render("=lock "))
-	b.WriteString(diagramEventStyle.Render("U"))
+	b.WriteString(diagramEventStyle.Render(kindLabel(model.EventLockRel)))
 	b.WriteString(dimStyle.Render("=unlock "))
 	b.WriteString(diagramMsgStyle.Render("~~~>"))
 	b.WriteString(dimStyle.Render("=message arrow"))
 	b.WriteRune('\n')
 	b.WriteRune('\n')
 
-	agentOrder, rows := buildDiagramData(m.snap.Agents, m.snap.Events)
+	agentOrder, rows := m.diagramData()
+	if m.diagramOrder == diagramByActivity {
+		b.WriteString(dimStyle.Render("  Columns ordered by activity (o to restore registration order)"))
+		b.WriteRune('\n')
+	}
+	if label := pairLabel(m.focusPair); label != "" && m.focusPair[1] != "" {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Focus %s (P on Dashboard to change)", label)))
+		b.WriteRune('\n')
+	} else if len(m.pinned) > 0 {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Pinned: showing %d of %d agents (space on Dashboard to change)",
+			len(agentOrder), len(m.snap.Agents))))
+		b.WriteRune('\n')
+	}
+	// Arrow focus: every column stays, but only messages to or from the
+	// Dashboard's selected agent get arrows.
+	arrowAgent := ""
+	if m.arrowFocus && m.selectedAgentID != "" {
+		arrowAgent = m.selectedAgentID
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Arrows: only messages to or from %s (a to show all)", arrowAgent)))
+		b.WriteRune('\n')
+	}
 	if len(agentOrder) == 0 || len(rows) == 0 {
 		b.WriteString(dimStyle.Render("  (no data)"))
 		b.WriteRune('\n')
 		return b.String()
 	}
-
-	// Compute column widths.
-	colWidth := 14  // width per agent column
-	tsColWidth := 7 // width for the timestamp label
+	b.WriteString(concurrencyLine(groupByLamport(m.pairEvents(m.snap.Events))))
+	b.WriteRune('\n')
+	rows, hidden, first, last := capDiagramRows(rows, m.diagramRows)
+
+	// Compute column widths. The timestamp column fits the largest Lamport
+	// value (rows are ascending, so it is the last) plus a two-space gap, and
+	// labels are right-aligned so agent columns line up at every magnitude.
+	colWidth := diagramColWidth
+	tsDigits := len(strconv.FormatInt(rows[len(rows)-1].lamportTS, 10))
+	tsColWidth := tsDigits + 2
+
+	// Pan: draw only the agent columns that fit, keeping L pinned on the
+	// left.
+	start, end := diagramWindow(len(agentOrder), m.diagramCol, m.width, tsColWidth)
+	if start > 0 || end < len(agentOrder) {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Columns %d–%d of %d (left/right to pan)",
+			start+1, end, len(agentOrder))))
+		b.WriteRune('\n')
+		agentOrder = agentOrder[start:end]
+	}
 
 	// Header row: agent names.
-	b.WriteString(dimStyle.Render(fmt.Sprintf("  %-*s", tsColWidth, "L")))
+	b.WriteString(dimStyle.Render(fmt.Sprintf("  %*s  ", tsDigits, "L")))
 	for _, ag := range agentOrder {
-		name := ag
-		if len(name) > colWidth-2 {
-			name = name[:colWidth-2]
-		}
+		name := ellipsizeMiddle(ag, colWidth-2)
 		b.WriteString(headerStyle.Render(fmt.Sprintf("%-*s", colWidth, name)))
 	}
 	b.WriteRune('\n')
@@ -1361,12 +4308,22 @@ func (m uiModel) renderDiagram() string {
 	}
 	b.WriteRune('\n')
 
+	if hidden > 0 {
+		noun := "rows"
+		if hidden == 1 {
+			noun = "row"
+		}
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  \u22ee (L:%d\u2013%d, %d older %s hidden; --diagram-rows to show more)",
+			first, last, hidden, noun)))
+		b.WriteRune('\n')
+	}
+
 	// Render rows with time increasing downward (Lamport 1978, Fig 1).
 	for ri := 0; ri < len(rows); ri++ {
 		row := rows[ri]
 
 		// Timestamp label.
-		b.WriteString(dimStyle.Render(fmt.Sprintf("  %-*d", tsColWidth, row.lamportTS)))
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  %*d  ", tsDigits, row.lamportTS)))
 
 		// Agent columns.
 		for _, ag := range agentOrder {
@@ -1376,7 +4333,7 @@ func (m uiModel) renderDiagram() string {
 				stale := false
 				for _, a := range m.snap.Agents {
 					if a.ID == ag {
-						stale = time.Since(a.LastSeen) > 10*time.Minute
+						stale = isStale(a, time.Now())
 						break
 					}
 				}
@@ -1386,8 +4343,8 @@ func (m uiModel) renderDiagram() string {
 				}
 
 				marker := style.Bold(true).Render(cell.label)
-				// Pad to column width (marker is 1 visible char).
-				b.WriteString(fmt.Sprintf("%s%-*s", marker, colWidth-1, ""))
+				// Pad to column width (glyphs are 1 or 2 cells wide).
+				b.WriteString(fmt.Sprintf("%s%-*s", marker, colWidth-ansi.StringWidth(cell.label), ""))
 			} else {
 				// Empty column — show the process line.
 				b.WriteString(diagramLineStyle.Render(fmt.Sprintf("%-*s", colWidth, "\u2502")))
@@ -1397,6 +4354,9 @@ func (m uiModel) renderDiagram() string {
 
 		// Render message arrows below the event row.
 		for _, msg := range row.messages {
+			if arrowAgent != "" && msg.fromAgent != arrowAgent && msg.toAgent != arrowAgent {
+				continue
+			}
 			fromIdx := agentIndex(agentOrder, msg.fromAgent)
 			toIdx := agentIndex(agentOrder, msg.toAgent)
 			if fromIdx < 0 || toIdx < 0 {
@@ -1444,6 +4404,7 @@ func (m uiModel) renderDiagram() string {
 		}
 	}
 
+	b.WriteString(renderFooter(fmt.Sprintf("%d rows, %d agents", len(rows), len(agentOrder))))
 	return b.String()
 }
 
@@ -1460,25 +4421,85 @@ var (
 				MarginTop(1)
 )
 
-func (m uiModel) renderAgentDetailFor(agentID string) string {
-	var b strings.Builder
+// defaultDetailLimit is the default --detail-limit: how many entries each
+// Agent Detail list (sent, received, recent activity) shows, newest first.
+const defaultDetailLimit = 20
+
+// agentDetail is the data behind the Agent Detail view, shared with
+// --json --agent. Event lists are newest first.
+type agentDetail struct {
+	Agent    model.Agent
+	Frontier frontier.FrontierStatus
+	HasFront bool // Frontier is known for this agent
+	Locks    []model.Lock
+	Sent     []model.Event
+	Received []model.Event
+	Activity []model.Event
+
+	// Entries beyond the limit, left out of the lists above.
+	MoreSent, MoreReceived, MoreActivity int
+}
 
-	// Find agent.
-	var agent *model.Agent
-	for i := range m.snap.Agents {
-		if m.snap.Agents[i].ID == agentID {
-			agent = &m.snap.Agents[i]
-			break
+// gatherAgentDetail collects agent id's detail from snap, keeping at most
+// limit entries per event list (0 = all), or reports false if no such
+// agent is registered.
+func gatherAgentDetail(snap *snapshot.DataSnapshot, id string, limit int) (agentDetail, bool) {
+	ag, ok := findAgent(snap.Agents, id)
+	if !ok {
+		return agentDetail{}, false
+	}
+	d := agentDetail{Agent: ag}
+	d.Frontier, d.HasFront = snap.FrontierStatus[id]
+	for _, l := range snap.Locks {
+		if l.AgentID == id {
+			d.Locks = append(d.Locks, l)
+		}
+	}
+	keep := func(list *[]model.Event, more *int, e model.Event) {
+		if limit > 0 && len(*list) >= limit {
+			*more++
+			return
 		}
+		*list = append(*list, e)
 	}
+	for i := len(snap.Events) - 1; i >= 0; i-- {
+		e := snap.Events[i]
+		if e.Kind == model.EventMsg && e.AgentID == id {
+			keep(&d.Sent, &d.MoreSent, e)
+		}
+		if e.Kind == model.EventMsg && e.Target == id {
+			keep(&d.Received, &d.MoreReceived, e)
+		}
+		if e.AgentID == id {
+			keep(&d.Activity, &d.MoreActivity, e)
+		}
+	}
+	return d, true
+}
+
+// moreNote returns the "(+N more)" line closing a truncated list, or "".
+func moreNote(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return dimStyle.Render(fmt.Sprintf("  (+%d more)", n)) + "\n"
+}
 
-	if agent == nil {
+func (m uiModel) renderAgentDetailFor(agentID string) string {
+	if m.snap == nil {
+		return noData()
+	}
+	var b strings.Builder
+
+	d, ok := gatherAgentDetail(m.snap, agentID, m.detailLimit)
+	if !ok {
 		b.WriteString(dimStyle.Render(fmt.Sprintf("  Agent %q not found", agentID)))
 		return b.String()
 	}
+	agent := &d.Agent
 
 	// Header.
-	stale := time.Since(agent.LastSeen) > 10*time.Minute
+	stale := isStale(*agent, time.Now())
 	statusBadge := safeStyle.Render("ACTIVE")
 	if stale {
 		statusBadge = unsafeStyle.Render("STALE")
@@ -1487,13 +4508,34 @@ func (m uiModel) renderAgentDetailFor(agentID string) string {
 	b.WriteString(detailHeaderStyle.Render(fmt.Sprintf("Agent: %s", agent.ID)))
 	b.WriteString("  ")
 	b.WriteString(statusBadge)
+	if isNewAgent(*agent, m.newAgentWindow, time.Now()) {
+		b.WriteString(" ")
+		b.WriteString(newBadgeStyle.Render("NEW"))
+	}
+	b.WriteString(m.stuckBadge(agent.ID))
 	b.WriteRune('\n')
-	b.WriteString(dimStyle.Render(fmt.Sprintf("  Lamport clock: %d | Progress: e%d/r%d | Last seen: %s ago",
-		agent.Clock, agent.Epoch, agent.Round, shortDuration(time.Since(agent.LastSeen)))))
+	epochs := usesEpochs(m.snap.Agents)
+	clock := strconv.FormatInt(agent.Clock, 10)
+	if lag := clockLag(*agent, m.snap.Agents); lag > 0 {
+		clock += fmt.Sprintf(" (%d behind)", lag)
+	}
+	if epochs {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Lamport clock: %s | Progress: e%d/r%d | Last seen: %s ago",
+			clock, agent.Epoch, agent.Round, shortDuration(time.Since(agent.LastSeen)))))
+	} else {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Lamport clock: %s | Last seen: %s ago",
+			clock, shortDuration(time.Since(agent.LastSeen)))))
+	}
 	b.WriteRune('\n')
+	if !agent.Registered.IsZero() {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Registered: %s (%s ago) | Uptime: %s",
+			agent.Registered.Local().Format("2006-01-02 15:04:05"),
+			shortDuration(time.Since(agent.Registered)), shortDuration(agentUptime(*agent, time.Now())))))
+		b.WriteRune('\n')
+	}
 
 	// Frontier status.
-	if fs, ok := m.snap.FrontierStatus[agentID]; ok {
+	if fs := d.Frontier; d.HasFront && epochs {
 		if fs.SafeToFinalize {
 			b.WriteString(fmt.Sprintf("  Frontier: %s (epoch=%d round=%d)\n",
 				safeStyle.Render("SAFE"), agent.Epoch, agent.Round))
@@ -1508,88 +4550,74 @@ func (m uiModel) renderAgentDetailFor(agentID string) string {
 		}
 	}
 
+	// Who this agent exchanges messages with.
+	if rels := agentRelationships(m.snap.Events, agentID); len(rels) > 0 {
+		peers := make([]string, 0, len(rels))
+		for id := range rels {
+			peers = append(peers, id)
+		}
+		sort.Strings(peers)
+		b.WriteString("  Talks with:\n")
+		for _, id := range peers {
+			r := rels[id]
+			b.WriteString(fmt.Sprintf("    \u2194 %s: %s\n", msgToStyle.Render(id),
+				dimStyle.Render(fmt.Sprintf("%d sent, %d recv", r.Sent, r.Recv))))
+		}
+	}
+
 	b.WriteRune('\n')
 
 	// Locks held by this agent.
-	b.WriteString(detailSectionStyle.Render("Locks Held"))
-	b.WriteRune('\n')
-	var agentLocks int
-	for _, l := range m.snap.Locks {
-		if l.AgentID == agentID {
-			remaining := shortDuration(time.Until(l.ExpiresAt))
-			b.WriteString(lockStyle.Render(fmt.Sprintf("  %s  (L:%d, expires in %s)",
-				l.Path, l.LamportTS, remaining)))
-			b.WriteRune('\n')
-			agentLocks++
-		}
-	}
-	if agentLocks == 0 {
-		b.WriteString(dimStyle.Render("  (none)"))
-		b.WriteRune('\n')
+	var sec strings.Builder
+	for _, l := range d.Locks {
+		remaining := shortDuration(time.Until(l.ExpiresAt))
+		sec.WriteString(lockStyle.Render(fmt.Sprintf("  %s  (L:%d, expires in %s)",
+			l.Path, l.LamportTS, remaining)))
+		sec.WriteRune('\n')
 	}
+	m.writeDetailSection(&b, sectionLocks, len(d.Locks), sec.String())
 
 	b.WriteRune('\n')
 
 	// Messages sent by this agent.
-	b.WriteString(detailSectionStyle.Render("Messages Sent"))
-	b.WriteRune('\n')
-	var sentCount int
-	for i := len(m.snap.Events) - 1; i >= 0 && sentCount < 15; i-- {
-		e := m.snap.Events[i]
-		if e.Kind == model.EventMsg && e.AgentID == agentID {
-			body := e.Body
-			if len(body) > 80 {
-				body = body[:80] + "..."
-			}
-			b.WriteString(fmt.Sprintf("  %s -> %s: %s\n",
-				dimStyle.Render(fmt.Sprintf("[L:%d]", e.LamportTS)),
-				msgToStyle.Render(e.Target),
-				body))
-			sentCount++
-		}
-	}
-	if sentCount == 0 {
-		b.WriteString(dimStyle.Render("  (none)"))
-		b.WriteRune('\n')
+	ranks := m.ranks()
+	sec.Reset()
+	for _, e := range d.Sent {
+		body := e.Body
+		if len(body) > 80 {
+			body = body[:80] + "..."
+		}
+		sec.WriteString(fmt.Sprintf("  %s -> %s: %s\n",
+			dimStyle.Render(lamportTag(e.LamportTS, ranks, 0)),
+			msgToStyle.Render(e.Target),
+			body))
 	}
+	sec.WriteString(moreNote(d.MoreSent))
+	m.writeDetailSection(&b, sectionSent, len(d.Sent)+d.MoreSent, sec.String())
 
 	b.WriteRune('\n')
 
 	// Messages received by this agent.
-	b.WriteString(detailSectionStyle.Render("Messages Received"))
-	b.WriteRune('\n')
-	var recvCount int
-	for i := len(m.snap.Events) - 1; i >= 0 && recvCount < 15; i-- {
-		e := m.snap.Events[i]
-		if e.Kind == model.EventMsg && e.Target == agentID {
-			body := e.Body
-			if len(body) > 80 {
-				body = body[:80] + "..."
-			}
-			b.WriteString(fmt.Sprintf("  %s %s: %s\n",
-				dimStyle.Render(fmt.Sprintf("[L:%d]", e.LamportTS)),
-				msgFromStyle.Render(e.AgentID),
-				body))
-			recvCount++
-		}
-	}
-	if recvCount == 0 {
-		b.WriteString(dimStyle.Render("  (none)"))
-		b.WriteRune('\n')
+	sec.Reset()
+	for _, e := range d.Received {
+		body := e.Body
+		if len(body) > 80 {
+			body = body[:80] + "..."
+		}
+		sec.WriteString(fmt.Sprintf("  %s %s: %s\n",
+			dimStyle.Render(lamportTag(e.LamportTS, ranks, 0)),
+			msgFromStyle.Render(e.AgentID),
+			body))
 	}
+	sec.WriteString(moreNote(d.MoreReceived))
+	m.writeDetailSection(&b, sectionReceived, len(d.Received)+d.MoreReceived, sec.String())
 
 	b.WriteRune('\n')
 
 	// Recent events (all kinds) by this agent.
-	b.WriteString(detailSectionStyle.Render("Recent Activity"))
-	b.WriteRune('\n')
-	var actCount int
-	for i := len(m.snap.Events) - 1; i >= 0 && actCount < 20; i-- {
-		e := m.snap.Events[i]
-		if e.AgentID != agentID {
-			continue
-		}
-		ts := dimStyle.Render(fmt.Sprintf("[L:%-4d]", e.LamportTS))
+	sec.Reset()
+	for _, e := range d.Activity {
+		ts := dimStyle.Render(lamportTag(e.LamportTS, ranks, 4))
 		var detail string
 		switch e.Kind {
 		case model.EventMsg:
@@ -1603,15 +4631,209 @@ func (m uiModel) renderAgentDetailFor(agentID string) string {
 		default:
 			detail = fmt.Sprintf("%s %s", e.Kind, e.Target)
 		}
-		b.WriteString(fmt.Sprintf("  %s %s\n", ts, detail))
-		actCount++
+		sec.WriteString(fmt.Sprintf("  %s %s\n", ts, detail))
+	}
+	sec.WriteString(moreNote(d.MoreActivity))
+	m.writeDetailSection(&b, sectionActivity, len(d.Activity)+d.MoreActivity, sec.String())
+
+	return b.String()
+}
+
+// clockLag is how far agent's Lamport clock trails the highest clock among
+// agents; the leader's lag is 0. Logical time only advances through events,
+// so a large lag marks an agent that has fallen out of the conversation.
+func clockLag(agent model.Agent, agents []model.Agent) int64 {
+	top := agent.Clock
+	for _, ag := range agents {
+		top = max(top, ag.Clock)
+	}
+	return top - agent.Clock
+}
+
+// usesEpochs reports whether any agent has advanced past epoch 0 / round 0.
+// If none has, the session isn't using Naiad progress tracking and the
+// progress and frontier displays carry no information.
+func usesEpochs(agents []model.Agent) bool {
+	for _, ag := range agents {
+		if ag.Epoch != 0 || ag.Round != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// relationship counts the messages an agent exchanged with one peer.
+type relationship struct {
+	Sent, Recv int
+}
+
+// agentRelationships returns, for every agent id has messaged or been
+// messaged by, how many messages went each way.
+func agentRelationships(events []model.Event, id string) map[string]relationship {
+	rels := make(map[string]relationship)
+	for _, e := range events {
+		if e.Kind != model.EventMsg {
+			continue
+		}
+		switch {
+		case e.AgentID == id && e.Target != "" && e.Target != id:
+			r := rels[e.Target]
+			r.Sent++
+			rels[e.Target] = r
+		case e.Target == id && e.AgentID != id:
+			r := rels[e.AgentID]
+			r.Recv++
+			rels[e.AgentID] = r
+		}
+	}
+	return rels
+}
+
+// directionBalance summarizes which way id's messages flow: '↑' if it sends
+// at least twice as many as it receives, '↓' for the reverse, '↔' when
+// balanced, and ' ' if it has exchanged none. One-way traffic often means
+// an agent is shouting into the void or not answering.
+func directionBalance(events []model.Event, id string) rune {
+	var sent, recv int
+	for _, r := range agentRelationships(events, id) {
+		sent += r.Sent
+		recv += r.Recv
+	}
+	switch {
+	case sent == 0 && recv == 0:
+		return ' '
+	case sent >= 2*recv:
+		return '\u2191'
+	case recv >= 2*sent:
+		return '\u2193'
+	}
+	return '\u2194'
+}
+
+// topTalkers is how many agents the Dashboard ranks by messages sent and
+// received.
+const topTalkers = 3
+
+// messageCounts tallies messages sent and received per agent. Messages
+// without a target count only for their sender.
+func messageCounts(events []model.Event) (sent, recv map[string]int) {
+	sent, recv = make(map[string]int), make(map[string]int)
+	for _, e := range events {
+		if e.Kind != model.EventMsg {
+			continue
+		}
+		sent[e.AgentID]++
+		if e.Target != "" {
+			recv[e.Target]++
+		}
+	}
+	return sent, recv
+}
+
+// rankedCount is one entry of a topN ranking.
+type rankedCount struct {
+	ID string
+	N  int
+}
+
+// topN returns the n IDs with the highest counts, highest first. Ties are
+// broken by ID so the ranking does not shuffle between refreshes.
+func topN(counts map[string]int, n int) []rankedCount {
+	ranked := make([]rankedCount, 0, len(counts))
+	for id, c := range counts {
+		ranked = append(ranked, rankedCount{id, c})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].N != ranked[j].N {
+			return ranked[i].N > ranked[j].N
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	return ranked[:min(n, len(ranked))]
+}
+
+// formatRanked renders a ranking as "alice (5), bob (3)".
+func formatRanked(ranked []rankedCount) string {
+	if len(ranked) == 0 {
+		return "(none)"
+	}
+	parts := make([]string, len(ranked))
+	for i, r := range ranked {
+		parts[i] = fmt.Sprintf("%s (%d)", r.ID, r.N)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// distinctPairs counts the agent pairs that exchanged at least one
+// message. Directed pairs count alice->bob and bob->alice separately;
+// undirected pairs count them once. Self-messages are not pairs.
+func distinctPairs(events []model.Event, directed bool) int {
+	pairs := make(map[[2]string]bool)
+	for _, e := range events {
+		if e.Kind != model.EventMsg || e.Target == "" || e.Target == e.AgentID {
+			continue
+		}
+		p := [2]string{e.AgentID, e.Target}
+		if !directed && p[1] < p[0] {
+			p[0], p[1] = p[1], p[0]
+		}
+		pairs[p] = true
+	}
+	return len(pairs)
+}
+
+// detailSection identifies a foldable Agent Detail section. The numbering
+// matches the 1-4 fold keys.
+type detailSection int
+
+const (
+	sectionLocks detailSection = iota
+	sectionSent
+	sectionReceived
+	sectionActivity
+	sectionCount
+)
+
+func (d detailSection) String() string {
+	switch d {
+	case sectionLocks:
+		return "Locks Held"
+	case sectionSent:
+		return "Messages Sent"
+	case sectionReceived:
+		return "Messages Received"
+	case sectionActivity:
+		return "Recent Activity"
+	}
+	return "?"
+}
+
+// writeDetailSection writes an Agent Detail section: its header, then body
+// (or "(none)" when n is 0). A folded section is just its header with the
+// item count.
+func (m uiModel) writeDetailSection(b *strings.Builder, sec detailSection, n int, body string) {
+	if m.foldedSections[sec] {
+		b.WriteString(detailSectionStyle.Render(fmt.Sprintf("%s (%d)", sec, n)))
+		b.WriteString(dimStyle.Render(fmt.Sprintf(" folded, %d to expand", sec+1)))
+		b.WriteRune('\n')
+		return
 	}
-	if actCount == 0 {
+	b.WriteString(detailSectionStyle.Render(sec.String()))
+	b.WriteRune('\n')
+	if n == 0 {
 		b.WriteString(dimStyle.Render("  (none)"))
 		b.WriteRune('\n')
+		return
 	}
+	b.WriteString(body)
+}
 
-	return b.String()
+// --- Footers ---
+
+// renderFooter renders a view's closing count line, separated from the
+// content by a blank line.
+func renderFooter(text string) string {
+	return "\n" + dimStyle.Render("  "+text) + "\n"
 }
 
 // --- Split-pane rendering ---
@@ -1686,8 +4908,106 @@ func stripAnsi(s string) string {
 
 // --- Helpers ---
 
+// findAgent returns the agent with the given ID.
+func findAgent(agents []model.Agent, id string) (model.Agent, bool) {
+	for _, ag := range agents {
+		if ag.ID == id {
+			return ag, true
+		}
+	}
+	return model.Agent{}, false
+}
+
+// defaultNewAgentWindow is how long after registration an agent is badged NEW.
+const defaultNewAgentWindow = 30 * time.Second
+
+// isNewAgent reports whether ag registered within window of now. NEW is
+// independent of ACTIVE/STALE: a fresh agent is usually also active, and
+// the badge is shown alongside rather than instead of the status color.
+// A zero window disables the badge.
+func isNewAgent(ag model.Agent, window time.Duration, now time.Time) bool {
+	if window <= 0 || ag.Registered.IsZero() {
+		return false
+	}
+	return now.Sub(ag.Registered) < window
+}
+
 // eventMatchesAgent returns true if the event involves the given agent as
 // sender (AgentID) or receiver (Target). Empty filter matches everything.
+// eventInvolvesPair reports whether e belongs to the conversation between
+// agents a and b: a message with both endpoints in {a, b}, or a lock event
+// by either of them. Messages to or from a third agent are excluded.
+func eventInvolvesPair(e model.Event, a, b string) bool {
+	in := func(id string) bool { return id == a || id == b }
+	switch e.Kind {
+	case model.EventMsg:
+		return in(e.AgentID) && in(e.Target)
+	case model.EventLockReq, model.EventLockRel:
+		return in(e.AgentID)
+	}
+	return false
+}
+
+// choosePair advances the focus pair selection with agent id: the first
+// pick sets one end, a different second pick completes the pair, picking
+// the same agent twice clears it, and a pick after a complete pair starts
+// over.
+func choosePair(p [2]string, id string) [2]string {
+	switch {
+	case p[0] == "" || p[1] != "":
+		return [2]string{id, ""}
+	case p[0] == id:
+		return [2]string{}
+	default:
+		return [2]string{p[0], id}
+	}
+}
+
+// pairLabel renders the focus pair for the status bar, or "" if none.
+func pairLabel(p [2]string) string {
+	switch {
+	case p[0] == "":
+		return ""
+	case p[1] == "":
+		return "pair: " + p[0] + "\u2194?"
+	}
+	return "pair: " + p[0] + "\u2194" + p[1]
+}
+
+// pair returns the focus pair once both ends are chosen.
+func (m uiModel) pair() (a, b string, ok bool) {
+	if m.focusPair[0] == "" || m.focusPair[1] == "" {
+		return "", "", false
+	}
+	return m.focusPair[0], m.focusPair[1], true
+}
+
+// timelineKinds is the cycle K steps through in the Timeline; "" shows
+// every kind.
+var timelineKinds = []model.EventKind{"", model.EventMsg, model.EventLockReq, model.EventLockRel, model.EventProgress}
+
+// nextTimelineKind returns the kind filter after k in timelineKinds.
+func nextTimelineKind(k model.EventKind) model.EventKind {
+	i := slices.Index(timelineKinds, k)
+	return timelineKinds[(i+1)%len(timelineKinds)]
+}
+
+// pairEvents filters events to the focus pair's conversation, or returns
+// them unchanged when no pair is set.
+func (m uiModel) pairEvents(events []model.Event) []model.Event {
+	a, b, ok := m.pair()
+	if !ok {
+		return events
+	}
+	var out []model.Event
+	for _, e := range events {
+		if eventInvolvesPair(e, a, b) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
 func eventMatchesAgent(e model.Event, agent string) bool {
 	if agent == "" {
 		return true
@@ -1705,6 +5025,18 @@ func filterEvents(events []model.Event, kind model.EventKind) []model.Event {
 	return out
 }
 
+// hScrollStep is how many columns one left/right press pans message bodies.
+const hScrollStep = 8
+
+// hSlice returns the visible columns [offset, offset+width) of s. It is
+// ANSI-aware: escape codes don't count toward width and styling is kept.
+func hSlice(s string, offset, width int) string {
+	if offset < 0 {
+		offset = 0
+	}
+	return ansi.Cut(s, offset, offset+width)
+}
+
 // truncateLines truncates each line in content to at most width visible
 // characters, preserving ANSI escape codes. This prevents terminal line
 // wrapping when the window is resized narrower.
@@ -1771,6 +5103,26 @@ func wrapParagraph(s string, width int) []string {
 	return lines
 }
 
+// agentIDWidth is the Dashboard's agent ID column width; longer IDs are
+// shortened to it there and in the Frontier.
+const agentIDWidth = 16
+
+// ellipsizeMiddle shortens s to at most max runes by replacing its middle
+// with "…", keeping both ends recognizable ("0b7f3c…9e2a41"). The prefix
+// gets the odd rune. Strings that fit are returned unchanged.
+func ellipsizeMiddle(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	if max <= 1 {
+		return string([]rune("…")[:max])
+	}
+	keep := max - 1
+	head, tail := (keep+1)/2, keep/2
+	return string(runes[:head]) + "…" + string(runes[len(runes)-tail:])
+}
+
 func truncate(s string, n int) string {
 	if len(s) <= n {
 		return s
@@ -1778,6 +5130,16 @@ func truncate(s string, n int) string {
 	return s[:n] + "..."
 }
 
+// agentUptime is how long ag has been around: until now while it is
+// active, or until it was last seen once it has gone stale.
+func agentUptime(ag model.Agent, now time.Time) time.Duration {
+	end := now
+	if isStale(ag, now) {
+		end = ag.LastSeen
+	}
+	return end.Sub(ag.Registered)
+}
+
 func shortDuration(d time.Duration) string {
 	if d < 0 {
 		return "expired"
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+// defaultDBOpener is used when $CMV_DB_OPENER is unset and it is on PATH.
+const defaultDBOpener = "sqlitebrowser"
+
+// copyToClipboard copies text via the terminal (OSC 52). main points it at
+// the TUI's syncOutput; tests replace it.
+var copyToClipboard = termenv.Copy
+
+// syncOutput is the TUI's stdout with writes serialized. The copy commands
+// run in tea.Cmd goroutines while Bubble Tea's renderer draws; sharing the
+// lock keeps an OSC 52 sequence from landing in the middle of a frame,
+// which the renderer writes in one call. The embedded file keeps its Fd,
+// so Bubble Tea still sees a terminal.
+type syncOutput struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (o *syncOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.File.Write(p)
+}
+
+// noticeMsg carries a one-line result to show in the status bar.
+type noticeMsg struct {
+	text string
+}
+
+// dbOpenerCommand returns the argv that opens the database at path with
+// opener, a command line split on whitespace (no shell quoting; use a
+// wrapper script for anything fancier). A "{}" argument is replaced by
+// path; otherwise path is appended.
+func dbOpenerCommand(opener, path string) []string {
+	argv := strings.Fields(opener)
+	replaced := false
+	for i, a := range argv {
+		if a == "{}" {
+			argv[i] = path
+			replaced = true
+		}
+	}
+	if !replaced {
+		argv = append(argv, path)
+	}
+	return argv
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but safe
+// characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+:@", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// openDBCmd launches $CMV_DB_OPENER (or sqlitebrowser) on path without
+// waiting for it. With no opener available, it copies a ready-to-paste
+// sqlite3 command to the clipboard instead. The outcome is reported as a
+// noticeMsg.
+func openDBCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		opener := os.Getenv("CMV_DB_OPENER")
+		if opener == "" {
+			if _, err := exec.LookPath(defaultDBOpener); err != nil {
+				line := "sqlite3 " + shellQuote(path)
+				copyToClipboard(line)
+				return noticeMsg{text: "no DB opener; copied: " + line}
+			}
+			opener = defaultDBOpener
+		}
+		argv := dbOpenerCommand(opener, path)
+		if len(argv) == 0 {
+			return noticeMsg{text: "open DB: empty CMV_DB_OPENER"}
+		}
+		c := exec.Command(argv[0], argv[1:]...)
+		if err := c.Start(); err != nil {
+			return noticeMsg{text: fmt.Sprintf("open DB: %v", err)}
+		}
+		go c.Wait() // reap the child when it exits
+		return noticeMsg{text: "opened DB with " + argv[0]}
+	}
+}
+
+// copyAgentIDCmd copies an agent ID to the clipboard for pasting into cm
+// commands. OSC 52 gives no acknowledgement, so "copied" means the request
+// was sent to the terminal.
+func copyAgentIDCmd(id string) tea.Cmd {
+	return func() tea.Msg {
+		if id == "" {
+			return noticeMsg{text: "copy: no agent selected"}
+		}
+		copyToClipboard(id)
+		return noticeMsg{text: "copied " + id}
+	}
+}
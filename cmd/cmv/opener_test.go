@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+func TestDBOpenerCommand(t *testing.T) {
+	tests := []struct {
+		opener, path string
+		want         []string
+	}{
+		{"sqlitebrowser", "/tmp/cm.db", []string{"sqlitebrowser", "/tmp/cm.db"}},
+		{"open -a Datasette", "/x/cm.db", []string{"open", "-a", "Datasette", "/x/cm.db"}},
+		{"litecli {} --auto-vertical-output", "/x/cm.db", []string{"litecli", "/x/cm.db", "--auto-vertical-output"}},
+		{"tool", "/dir with space/cm.db", []string{"tool", "/dir with space/cm.db"}},
+	}
+	for _, tt := range tests {
+		if got := dbOpenerCommand(tt.opener, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("dbOpenerCommand(%q, %q) = %q, want %q", tt.opener, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"/home/me/.clockmail/clockmail.db": "/home/me/.clockmail/clockmail.db",
+		"/dir with space/cm.db":            "'/dir with space/cm.db'",
+		"/it's/cm.db":                      `'/it'\''s/cm.db'`,
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOpenDBWithoutOpenerCopiesCommand(t *testing.T) {
+	t.Setenv("CMV_DB_OPENER", "")
+	t.Setenv("PATH", t.TempDir()) // no sqlitebrowser
+	var copied string
+	defer func(f func(string)) { copyToClipboard = f }(copyToClipboard)
+	copyToClipboard = func(s string) { copied = s }
+
+	msg := openDBCmd("/x y/cm.db")().(noticeMsg)
+	if copied != "sqlite3 '/x y/cm.db'" {
+		t.Errorf("copied %q", copied)
+	}
+	if !strings.Contains(msg.text, "copied") {
+		t.Errorf("notice = %q", msg.text)
+	}
+}
+
+func TestOpenDBFailureReported(t *testing.T) {
+	t.Setenv("CMV_DB_OPENER", "/nonexistent/opener")
+	msg := openDBCmd("/x/cm.db")().(noticeMsg)
+	if !strings.HasPrefix(msg.text, "open DB:") {
+		t.Errorf("failure should be reported, got %q", msg.text)
+	}
+
+	// The notice shows in the status bar.
+	m := testModel()
+	updated, _ := m.Update(msg)
+	m = updated.(uiModel)
+	m.lastRefresh = time.Now()
+	if bar := stripAnsi(m.renderStatusBar()); !strings.Contains(bar, "open DB:") {
+		t.Errorf("status bar should show the notice: %q", bar)
+	}
+}
+
+func TestCopyAgentIDCopiesSelection(t *testing.T) {
+	var copied []string
+	defer func(f func(string)) { copyToClipboard = f }(copyToClipboard)
+	copyToClipboard = func(s string) { copied = append(copied, s) }
+
+	press := func(m uiModel) noticeMsg {
+		t.Helper()
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Y")})
+		if cmd == nil {
+			t.Fatal("Y returned no command")
+		}
+		return cmd().(noticeMsg)
+	}
+
+	// Dashboard: the selected row, not the first agent.
+	m := testModel().selectIndex(1)
+	if msg := press(m); msg.text != "copied bob" {
+		t.Errorf("Dashboard notice = %q, want \"copied bob\"", msg.text)
+	}
+
+	// Agent Detail: the agent being shown.
+	m = testModel()
+	m.activeView, m.detailAgentID = viewAgentDetail, "alice"
+	if msg := press(m); msg.text != "copied alice" {
+		t.Errorf("Agent Detail notice = %q, want \"copied alice\"", msg.text)
+	}
+	if !slices.Equal(copied, []string{"bob", "alice"}) {
+		t.Errorf("clipboard writes = %q, want [bob alice]", copied)
+	}
+
+	// Nothing selected: no clipboard write, and the notice says so.
+	m = testModel()
+	m.activeView = viewLocks
+	if msg := press(m); !strings.Contains(msg.text, "no agent selected") {
+		t.Errorf("no selection notice = %q", msg.text)
+	}
+	if len(copied) != 2 {
+		t.Errorf("clipboard written without a selection: %q", copied)
+	}
+}
+
+func TestSyncOutputCarriesClipboard(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color") // not screen, which wraps the sequence
+	f, err := os.Create(filepath.Join(t.TempDir(), "tty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	out := &syncOutput{File: f}
+	if out.Fd() != f.Fd() {
+		t.Error("syncOutput should expose the file's descriptor")
+	}
+
+	termenv.NewOutput(out).Copy("bob")
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("bob")); !strings.HasPrefix(string(got), want) {
+		t.Errorf("output = %q, want an OSC 52 copy of bob", got)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// pingPongMinRun is how many strictly alternating messages between two
+// agents the Messages view flags as a ping-pong loop.
+const pingPongMinRun = 6
+
+// pingPong is a run of messages bouncing between two agents.
+type pingPong struct {
+	a, b        string // the pair, a < b
+	count       int    // messages in the run
+	first, last int64  // Lamport span of the run
+}
+
+func (p pingPong) String() string {
+	return fmt.Sprintf("ping-pong: %s↔%s x%d (L:%d–%d)", p.a, p.b, p.count, p.first, p.last)
+}
+
+// detectPingPong finds runs of at least minRun messages between the same
+// two agents that strictly alternate direction (A→B, B→A, A→B, …) with
+// neither agent advancing its epoch/round in between. Messages to other
+// agents don't interrupt a run; two in a row from the same side, or
+// progress by either agent, end it. Events must be in Lamport order.
+// Runs are returned in order of their first message.
+func detectPingPong(events []model.Event, minRun int) []pingPong {
+	type run struct {
+		pingPong
+		lastFrom string
+	}
+	runs := make(map[[2]string]*run)
+	progress := make(map[string]model.Timestamp)
+	var found []pingPong
+	finish := func(key [2]string) {
+		if r := runs[key]; r != nil && r.count >= minRun {
+			found = append(found, r.pingPong)
+		}
+		delete(runs, key)
+	}
+
+	for _, e := range events {
+		switch e.Kind {
+		case model.EventProgress:
+			ts := model.Timestamp{Epoch: e.Epoch, Round: e.Round}
+			prev, seen := progress[e.AgentID]
+			progress[e.AgentID] = ts
+			if !seen || prev == ts {
+				continue
+			}
+			for key := range runs {
+				if key[0] == e.AgentID || key[1] == e.AgentID {
+					finish(key)
+				}
+			}
+		case model.EventMsg:
+			if e.Target == "" || e.Target == e.AgentID {
+				continue
+			}
+			key := [2]string{e.AgentID, e.Target}
+			if key[1] < key[0] {
+				key[0], key[1] = key[1], key[0]
+			}
+			r := runs[key]
+			if r != nil && r.lastFrom == e.AgentID {
+				finish(key)
+				r = nil
+			}
+			if r == nil {
+				r = &run{pingPong: pingPong{a: key[0], b: key[1], first: e.LamportTS}}
+				runs[key] = r
+			}
+			r.count++
+			r.last = e.LamportTS
+			r.lastFrom = e.AgentID
+		}
+	}
+	for key := range runs {
+		finish(key)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].first != found[j].first {
+			return found[i].first < found[j].first
+		}
+		return found[i].a+"\x00"+found[i].b < found[j].a+"\x00"+found[j].b
+	})
+	return found
+}
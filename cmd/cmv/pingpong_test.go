@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// exchange builds messages in Lamport order from "from>to" pairs.
+func exchange(pairs ...string) []model.Event {
+	events := make([]model.Event, len(pairs))
+	for i, p := range pairs {
+		from, to, _ := strings.Cut(p, ">")
+		events[i] = model.Event{ID: int64(i + 1), AgentID: from, Target: to, LamportTS: int64(i + 1), Kind: model.EventMsg}
+	}
+	return events
+}
+
+func TestDetectPingPong(t *testing.T) {
+	// alice and bob alternate four times; carol's message in between
+	// doesn't interrupt them.
+	events := exchange("alice>bob", "bob>alice", "carol>dave", "alice>bob", "bob>alice")
+	got := detectPingPong(events, 4)
+	want := []pingPong{{a: "alice", b: "bob", count: 4, first: 1, last: 5}}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("detectPingPong = %+v, want %+v", got, want)
+	}
+	if s := got[0].String(); s != "ping-pong: alice↔bob x4 (L:1–5)" {
+		t.Errorf("String() = %q", s)
+	}
+
+	// The same number of messages without strict alternation is no loop.
+	events = exchange("alice>bob", "alice>bob", "bob>alice", "bob>alice", "alice>bob")
+	if got := detectPingPong(events, 4); len(got) != 0 {
+		t.Errorf("non-alternating exchange flagged: %+v", got)
+	}
+
+	// Progress by either agent ends the run.
+	events = exchange("alice>bob", "bob>alice", "alice>bob", "bob>alice")
+	events = append(events[:2:2],
+		model.Event{AgentID: "bob", Kind: model.EventProgress, Epoch: 0, LamportTS: 2},
+		model.Event{AgentID: "bob", Kind: model.EventProgress, Epoch: 1, LamportTS: 2},
+	)
+	events = append(events, exchange("alice>bob", "bob>alice")...)
+	if got := detectPingPong(events, 4); len(got) != 0 {
+		t.Errorf("run across progress flagged: %+v", got)
+	}
+}
+
+func TestPingPongShownInMessages(t *testing.T) {
+	m := testModel()
+	m.snap.Events = exchange("alice>bob", "bob>alice", "alice>bob", "bob>alice", "alice>bob", "bob>alice", "alice>bob", "bob>alice")
+	if out := stripAnsi(m.renderMessages()); !strings.Contains(out, "ping-pong: alice↔bob x8") {
+		t.Errorf("Messages lacks the ping-pong warning:\n%s", out)
+	}
+}
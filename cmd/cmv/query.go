@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// knownKinds are the event kinds accepted by the query "kind" clause.
+var knownKinds = []model.EventKind{
+	model.EventMsg, model.EventLockReq, model.EventLockRel,
+	model.EventProgress, model.EventReviewReq, model.EventReviewDone,
+}
+
+// parseQuery parses the --query DSL into an event predicate. A query is a
+// sequence of clauses, all of which must match:
+//
+//	messages          message events
+//	locks             lock acquire and release events
+//	from X            sent by agent X
+//	to X              addressed to X (message recipient or lock path)
+//	kind K            event kind K (msg, lock_req, progress, ...)
+//	since D           created within duration D of now (e.g. 10m)
+//	lamport OP N      Lamport timestamp comparison; OP is > >= < <= =
+//
+// For example: "messages from alice to bob since 1h". An empty query
+// matches every event.
+func parseQuery(s string) (func(model.Event) bool, error) {
+	var preds []func(model.Event) bool
+	toks := strings.Fields(s)
+	arg := func(i int, clause string) (string, error) {
+		if i >= len(toks) {
+			return "", fmt.Errorf("query: %q needs an argument", clause)
+		}
+		return toks[i], nil
+	}
+
+	for i := 0; i < len(toks); i++ {
+		switch clause := strings.ToLower(toks[i]); clause {
+		case "messages":
+			preds = append(preds, func(e model.Event) bool { return e.Kind == model.EventMsg })
+		case "locks":
+			preds = append(preds, func(e model.Event) bool {
+				return e.Kind == model.EventLockReq || e.Kind == model.EventLockRel
+			})
+		case "from", "to":
+			v, err := arg(i+1, clause)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			if clause == "from" {
+				preds = append(preds, func(e model.Event) bool { return e.AgentID == v })
+			} else {
+				preds = append(preds, func(e model.Event) bool { return e.Target == v })
+			}
+		case "kind":
+			v, err := arg(i+1, clause)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			kind, ok := parseKind(v)
+			if !ok {
+				return nil, fmt.Errorf("query: unknown kind %q", v)
+			}
+			preds = append(preds, func(e model.Event) bool { return e.Kind == kind })
+		case "since":
+			v, err := arg(i+1, clause)
+			if err != nil {
+				return nil, err
+			}
+			i++
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("query: since: bad duration %q", v)
+			}
+			cutoff := time.Now().Add(-d)
+			preds = append(preds, func(e model.Event) bool { return !e.CreatedAt.Before(cutoff) })
+		case "lamport":
+			op, err := arg(i+1, clause)
+			if err != nil {
+				return nil, err
+			}
+			v, err := arg(i+2, clause+" "+op)
+			if err != nil {
+				return nil, err
+			}
+			i += 2
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("query: lamport: bad number %q", v)
+			}
+			cmp, err := lamportCmp(op, n)
+			if err != nil {
+				return nil, err
+			}
+			preds = append(preds, cmp)
+		default:
+			return nil, fmt.Errorf("query: unknown clause %q", toks[i])
+		}
+	}
+
+	return func(e model.Event) bool {
+		for _, p := range preds {
+			if !p(e) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// parseKind matches s against knownKinds.
+func parseKind(s string) (model.EventKind, bool) {
+	for _, k := range knownKinds {
+		if string(k) == s {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// lamportCmp builds the predicate for "lamport OP n".
+func lamportCmp(op string, n int64) (func(model.Event) bool, error) {
+	switch op {
+	case ">":
+		return func(e model.Event) bool { return e.LamportTS > n }, nil
+	case ">=":
+		return func(e model.Event) bool { return e.LamportTS >= n }, nil
+	case "<":
+		return func(e model.Event) bool { return e.LamportTS < n }, nil
+	case "<=":
+		return func(e model.Event) bool { return e.LamportTS <= n }, nil
+	case "=", "==":
+		return func(e model.Event) bool { return e.LamportTS == n }, nil
+	}
+	return nil, fmt.Errorf("query: lamport: unknown operator %q", op)
+}
+
+// runQuery writes every event matching query as one formatEventLine line
+// and returns how many matched.
+func runQuery(w io.Writer, events []model.Event, query string) (int, error) {
+	match, err := parseQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, e := range events {
+		if match(e) {
+			if _, err := fmt.Fprintln(w, formatEventLine(e)); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+func queryEvents() []model.Event {
+	now := time.Now()
+	return []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: "hi", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventMsg, Target: "alice", Body: "yo", CreatedAt: now.Add(-time.Minute)},
+		{ID: 3, AgentID: "alice", LamportTS: 3, Kind: model.EventLockReq, Target: "main.go", CreatedAt: now},
+		{ID: 4, AgentID: "alice", LamportTS: 4, Kind: model.EventLockRel, Target: "main.go", CreatedAt: now},
+		{ID: 5, AgentID: "alice", LamportTS: 5, Kind: model.EventMsg, Target: "bob", Body: "again", CreatedAt: now},
+		{ID: 6, AgentID: "bob", LamportTS: 6, Kind: model.EventProgress, CreatedAt: now},
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"", []int64{1, 2, 3, 4, 5, 6}},
+		{"messages", []int64{1, 2, 5}},
+		{"messages from alice to bob", []int64{1, 5}},
+		{"locks", []int64{3, 4}},
+		{"kind lock_rel", []int64{4}},
+		{"from bob kind progress", []int64{6}},
+		{"messages since 10m", []int64{2, 5}},
+		{"lamport > 4", []int64{5, 6}},
+		{"lamport <= 2", []int64{1, 2}},
+		{"MESSAGES from alice lamport = 5", []int64{5}},
+	}
+	for _, tt := range tests {
+		match, err := parseQuery(tt.query)
+		if err != nil {
+			t.Errorf("parseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		var got []int64
+		for _, e := range queryEvents() {
+			if match(e) {
+				got = append(got, e.ID)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, q := range []string{
+		"from",
+		"messages to",
+		"kind chat",
+		"since yesterday",
+		"lamport",
+		"lamport >",
+		"lamport ~ 3",
+		"lamport > three",
+		"everything",
+	} {
+		if _, err := parseQuery(q); err == nil {
+			t.Errorf("parseQuery(%q) should fail", q)
+		}
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	var b strings.Builder
+	n, err := runQuery(&b, queryEvents(), "messages from alice")
+	if err != nil || n != 2 {
+		t.Fatalf("runQuery = %d, %v; want 2, nil", n, err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "#1 ") || !strings.Contains(lines[1], "alice -> bob: again") {
+		t.Errorf("unexpected output:\n%s", b.String())
+	}
+}
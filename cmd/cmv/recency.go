@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// recencyFade is the age at which the Dashboard's recency gradient has
+// fully faded from the active color to the dim one.
+const recencyFade = 10 * time.Minute
+
+// recencyColor maps how long ago an agent was last seen to a color between
+// the active agent color (just now) and the dim color (recencyFade or
+// older). The endpoints follow the theme and CMV_COLOR_* overrides.
+func recencyColor(age time.Duration) lipgloss.Color {
+	from := styleRGB(agentActiveStyle, [3]uint8{0xA6, 0xE3, 0xA1})
+	to := styleRGB(dimStyle, [3]uint8{0x6C, 0x70, 0x86})
+	f := min(max(float64(age)/float64(recencyFade), 0), 1)
+	var c [3]uint8
+	for i := range c {
+		c[i] = uint8(float64(from[i]) + (float64(to[i])-float64(from[i]))*f + 0.5)
+	}
+	return lipgloss.Color(fmt.Sprintf("#%02X%02X%02X", c[0], c[1], c[2]))
+}
+
+// styleRGB returns st's foreground as RGB, or fallback when it is not a
+// hex color.
+func styleRGB(st lipgloss.Style, fallback [3]uint8) [3]uint8 {
+	c, ok := st.GetForeground().(lipgloss.Color)
+	if !ok {
+		return fallback
+	}
+	rgb, ok := parseHexColor(string(c))
+	if !ok {
+		return fallback
+	}
+	return rgb
+}
+
+// parseHexColor parses a #RGB or #RRGGBB color.
+func parseHexColor(s string) ([3]uint8, bool) {
+	if !isHexColor(s) {
+		return [3]uint8{}, false
+	}
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	var rgb [3]uint8
+	for i := range rgb {
+		v, err := strconv.ParseUint(hex[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return [3]uint8{}, false
+		}
+		rgb[i] = uint8(v)
+	}
+	return rgb, true
+}
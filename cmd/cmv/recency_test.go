@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecencyColorDistinguishesAge(t *testing.T) {
+	fresh := recencyColor(2 * time.Second)
+	old := recencyColor(9 * time.Minute)
+	if fresh == old {
+		t.Fatalf("recent and 9-minute-old agents share color %s", fresh)
+	}
+	// The gradient runs from the active color to the dim one.
+	if got, want := recencyColor(0), agentActiveStyle.GetForeground(); got != want {
+		t.Errorf("recencyColor(0) = %v, want the active color %v", got, want)
+	}
+	if got, want := recencyColor(time.Hour), dimStyle.GetForeground(); got != want {
+		t.Errorf("recencyColor(1h) = %v, want the dim color %v", got, want)
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [3]uint8
+		ok   bool
+	}{
+		{"#A6E3A1", [3]uint8{0xA6, 0xE3, 0xA1}, true},
+		{"#fa0", [3]uint8{0xFF, 0xAA, 0x00}, true},
+		{"red", [3]uint8{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseHexColor(tt.in); got != tt.want || ok != tt.ok {
+			t.Errorf("parseHexColor(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
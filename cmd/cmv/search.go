@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// bodyMatches reports whether e's body contains query, ignoring case. The
+// empty query matches every event.
+func bodyMatches(e model.Event, query string) bool {
+	return query == "" || strings.Contains(strings.ToLower(e.Body), strings.ToLower(query))
+}
+
+// highlightMatches renders each occurrence of query in line, ignoring
+// case, with searchMatchStyle. Lines that already carry styling, or whose
+// byte offsets lowercasing would shift, are returned unchanged.
+func highlightMatches(line, query string) string {
+	if query == "" || strings.Contains(line, "\x1b") {
+		return line
+	}
+	lower, q := strings.ToLower(line), strings.ToLower(query)
+	if len(lower) != len(line) {
+		return line
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, q)
+		if i < 0 {
+			break
+		}
+		b.WriteString(line[:i])
+		b.WriteString(searchMatchStyle.Render(line[i : i+len(q)]))
+		line, lower = line[i+len(q):], lower[i+len(q):]
+	}
+	b.WriteString(line)
+	return b.String()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// healthMaxAge is the least age at which /healthz calls the last good
+// snapshot too old. The serve loop rebuilds at least every --refresh, so
+// the limit grows to two intervals when --refresh is longer: only an older
+// snapshot means builds are failing or stuck.
+const healthMaxAge = 30 * time.Second
+
+// snapshotServer holds the latest snapshot for --serve and answers HTTP
+// requests from it; handlers never touch the store.
+type snapshotServer struct {
+	mu      sync.RWMutex
+	snap    *snapshot.DataSnapshot
+	builtAt time.Time
+	err     error // last build failure, cleared by the next success
+
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// newSnapshotServer returns a server for a loop that rebuilds at least
+// every interval.
+func newSnapshotServer(interval time.Duration) *snapshotServer {
+	return &snapshotServer{maxAge: max(healthMaxAge, 2*interval), now: time.Now}
+}
+
+// update records the result of a build. A failed build keeps the previous
+// snapshot for /snapshot but marks the server unhealthy.
+func (s *snapshotServer) update(snap *snapshot.DataSnapshot, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	if err == nil && snap != nil {
+		s.snap, s.builtAt = snap, s.now()
+	}
+}
+
+// handler routes GET /snapshot (the --json document) and GET /healthz.
+func (s *snapshotServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /snapshot", s.serveSnapshot)
+	mux.HandleFunc("GET /healthz", s.serveHealth)
+	return mux
+}
+
+func (s *snapshotServer) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+	if snap == nil {
+		writeJSONStatus(w, http.StatusServiceUnavailable, map[string]string{"error": "no snapshot yet"})
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, buildJSONOutput(snap, jsonOptions{}))
+}
+
+// healthBody is the /healthz response.
+type healthBody struct {
+	OK      bool   `json:"ok"`
+	Agents  int    `json:"agents"`
+	Blocked int    `json:"blocked"`
+	Stale   int    `json:"stale"`
+	Error   string `json:"error,omitempty"`
+}
+
+// serveHealth answers 200 while the last build succeeded and is recent,
+// 503 otherwise. The counts come from the cached snapshot either way.
+func (s *snapshotServer) serveHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	snap, builtAt, buildErr := s.snap, s.builtAt, s.err
+	s.mu.RUnlock()
+
+	var body healthBody
+	if snap != nil {
+		safe, total := finalizable(snap.FrontierStatus)
+		body.Agents = len(snap.Agents)
+		body.Blocked = total - safe
+		body.Stale = snap.StaleAgents
+	}
+	switch age := s.now().Sub(builtAt); {
+	case buildErr != nil:
+		body.Error = buildErr.Error()
+	case snap == nil:
+		body.Error = "no snapshot yet"
+	case age > s.maxAge:
+		body.Error = fmt.Sprintf("snapshot is %s old", age.Truncate(time.Second))
+	default:
+		body.OK = true
+	}
+	status := http.StatusOK
+	if !body.OK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSONStatus(w, status, body)
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// runServe is the headless --serve loop: it serves HTTP on addr and
+// rebuilds the snapshot on startup, on every database change, and at least
+// once per interval, until stop is closed. Build failures do not stop it;
+// they surface through /healthz.
+func runServe(addr string, s snapshot.Reader, changes <-chan struct{}, interval time.Duration, stop <-chan struct{}) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+	srv := newSnapshotServer(interval)
+	httpSrv := &http.Server{Handler: srv.handler()}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpSrv.Serve(ln) }()
+	defer httpSrv.Close()
+
+	refresh := func() { srv.update(snapshot.BuildWithTimeout(s, snapshotTimeout, staleAfter)) }
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return fmt.Errorf("serve: %w", err)
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil // watcher closed: keep polling
+				continue
+			}
+		case <-ticker.C:
+		}
+		refresh()
+	}
+}
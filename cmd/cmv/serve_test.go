@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// getHealth fetches /healthz and decodes the body.
+func getHealth(t *testing.T, srv *snapshotServer) (int, healthBody) {
+	t.Helper()
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz: %v", err)
+	}
+	defer resp.Body.Close()
+	var body healthBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode /healthz: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestHealthzFreshSnapshot(t *testing.T) {
+	srv := newSnapshotServer(0)
+	srv.update(testSnapshot(), nil)
+
+	status, body := getHealth(t, srv)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%+v)", status, body)
+	}
+	// testSnapshot: alice is blocked by bob, bob is safe.
+	want := healthBody{OK: true, Agents: 2, Blocked: 1, Stale: 0}
+	if body != want {
+		t.Errorf("body = %+v, want %+v", body, want)
+	}
+}
+
+func TestHealthzBuildError(t *testing.T) {
+	srv := newSnapshotServer(0)
+	srv.update(testSnapshot(), nil)
+	srv.update(nil, errors.New("database is locked"))
+
+	status, body := getHealth(t, srv)
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", status)
+	}
+	if body.OK || body.Error != "database is locked" {
+		t.Errorf("body = %+v, want ok=false with the build error", body)
+	}
+	// The last good snapshot still answers /snapshot.
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/snapshot")
+	if err != nil {
+		t.Fatalf("GET /snapshot: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/snapshot status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestHealthzStaleSnapshot(t *testing.T) {
+	now := time.Now()
+	srv := newSnapshotServer(0)
+	srv.now = func() time.Time { return now }
+	srv.update(testSnapshot(), nil)
+	now = now.Add(healthMaxAge + time.Second)
+
+	if status, body := getHealth(t, srv); status != http.StatusServiceUnavailable || body.OK {
+		t.Errorf("old snapshot: status = %d, body = %+v; want 503", status, body)
+	}
+}
+
+func TestHealthzMaxAgeFollowsInterval(t *testing.T) {
+	now := time.Now()
+	srv := newSnapshotServer(time.Minute)
+	srv.now = func() time.Time { return now }
+	srv.update(testSnapshot(), nil)
+
+	// Idle database, --refresh 1m: a 45s-old snapshot is expected.
+	now = now.Add(45 * time.Second)
+	if status, body := getHealth(t, srv); status != http.StatusOK {
+		t.Errorf("45s old with a 1m interval: status = %d, body = %+v; want 200", status, body)
+	}
+	now = now.Add(2 * time.Minute)
+	if status, _ := getHealth(t, srv); status != http.StatusServiceUnavailable {
+		t.Errorf("two missed rebuilds: status = %d, want 503", status)
+	}
+}
+
+func TestHealthzNoSnapshotYet(t *testing.T) {
+	if status, _ := getHealth(t, newSnapshotServer(0)); status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 before the first build", status)
+	}
+}
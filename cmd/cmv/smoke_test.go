@@ -22,7 +22,7 @@ func TestSmokeDBConnection(t *testing.T) {
 
 	t.Logf("connected to %s", path)
 
-	snap, err := snapshot.Build(s)
+	snap, err := snapshot.Build(s, nil)
 	if err != nil {
 		t.Fatalf("snapshot build failed: %v", err)
 	}
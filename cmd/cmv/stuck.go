@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// stuckThreshold is how long an agent's epoch/round may stay unchanged,
+// while other agents advance, before it is badged STUCK.
+const stuckThreshold = 5 * time.Minute
+
+// progressMark is an agent's progress and when cmv first saw it there.
+type progressMark struct {
+	progress model.Timestamp
+	since    time.Time
+}
+
+// progressTracker remembers, across refreshes, when each agent's
+// epoch/round last changed. A single snapshot only says where agents are;
+// "frozen while others move" needs this history.
+//
+// observe returns a new tracker rather than mutating, so copies held by
+// earlier uiModel values stay consistent.
+type progressTracker struct {
+	marks       map[string]progressMark
+	lastAdvance time.Time // when any agent's progress last changed
+}
+
+// observe records the agents' progress as seen at now. Agents seen for
+// the first time start their clock at now; agents no longer present are
+// forgotten.
+func (t progressTracker) observe(agents []model.Agent, now time.Time) progressTracker {
+	next := progressTracker{marks: make(map[string]progressMark, len(agents)), lastAdvance: t.lastAdvance}
+	for _, ag := range agents {
+		cur := model.Timestamp{Epoch: ag.Epoch, Round: ag.Round}
+		prev, seen := t.marks[ag.ID]
+		switch {
+		case !seen:
+			next.marks[ag.ID] = progressMark{progress: cur, since: now}
+		case prev.progress != cur:
+			next.marks[ag.ID] = progressMark{progress: cur, since: now}
+			next.lastAdvance = now
+		default:
+			next.marks[ag.ID] = prev
+		}
+	}
+	return next
+}
+
+// stuckFor reports how long agent id has been at its current progress, and
+// whether that counts as stuck: unchanged for at least stuckThreshold while
+// some other agent advanced. Agents that simply have nothing to do (no one
+// is progressing) are not stuck.
+func (t progressTracker) stuckFor(id string, now time.Time) (time.Duration, bool) {
+	mark, ok := t.marks[id]
+	if !ok {
+		return 0, false
+	}
+	d := now.Sub(mark.since)
+	return d, d >= stuckThreshold && t.lastAdvance.After(mark.since)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+func TestProgressTrackerStuck(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	first := []model.Agent{{ID: "alice", Epoch: 1, Round: 0}, {ID: "bob", Epoch: 1, Round: 0}}
+	// Six minutes later bob has moved on; alice hasn't.
+	second := []model.Agent{{ID: "alice", Epoch: 1, Round: 0}, {ID: "bob", Epoch: 2, Round: 1}}
+
+	var tr progressTracker
+	tr = tr.observe(first, t0)
+	now := t0.Add(6 * time.Minute)
+	tr = tr.observe(second, now)
+
+	if d, stuck := tr.stuckFor("alice", now); !stuck || d != 6*time.Minute {
+		t.Errorf("alice: stuckFor = %v, %v; want 6m, true", d, stuck)
+	}
+	if d, stuck := tr.stuckFor("bob", now); stuck || d != 0 {
+		t.Errorf("bob just advanced: stuckFor = %v, %v; want 0, false", d, stuck)
+	}
+	if _, stuck := tr.stuckFor("carol", now); stuck {
+		t.Error("unknown agent reported stuck")
+	}
+}
+
+func TestProgressTrackerNotStuckWhenNobodyMoves(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	agents := []model.Agent{{ID: "alice", Epoch: 1}, {ID: "bob", Epoch: 1}}
+
+	var tr progressTracker
+	tr = tr.observe(agents, t0)
+	now := t0.Add(time.Hour)
+	tr = tr.observe(agents, now)
+	if _, stuck := tr.stuckFor("alice", now); stuck {
+		t.Error("an idle session should not mark agents stuck")
+	}
+}
+
+func TestProgressTrackerBelowThreshold(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	var tr progressTracker
+	tr = tr.observe([]model.Agent{{ID: "alice"}, {ID: "bob"}}, t0)
+	now := t0.Add(stuckThreshold - time.Second)
+	tr = tr.observe([]model.Agent{{ID: "alice"}, {ID: "bob", Round: 1}}, now)
+	if _, stuck := tr.stuckFor("alice", now); stuck {
+		t.Error("alice stuck before the threshold")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme selects the palette the TUI is drawn with.
+type Theme int
+
+const (
+	ThemeDark  Theme = iota // Catppuccin Mocha, the default
+	ThemeLight              // Catppuccin Latte, for light-on-white terminals
+)
+
+func (t Theme) String() string {
+	if t == ThemeLight {
+		return "light"
+	}
+	return "dark"
+}
+
+// parseThemeFlag validates a --theme value: "auto", "dark", or "light".
+func parseThemeFlag(s string) (auto bool, t Theme, err error) {
+	switch s {
+	case "auto", "":
+		return true, ThemeDark, nil
+	case "dark":
+		return false, ThemeDark, nil
+	case "light":
+		return false, ThemeLight, nil
+	}
+	return false, ThemeDark, fmt.Errorf("unknown theme %q (valid: auto, dark, light)", s)
+}
+
+// chooseTheme picks the theme readable on a background of the given
+// relative luminance (0 = black, 1 = white).
+func chooseTheme(bgLuminance float64) Theme {
+	if bgLuminance >= 0.5 {
+		return ThemeLight
+	}
+	return ThemeDark
+}
+
+// backgroundDetector reports the terminal's background luminance, or false
+// if it cannot be determined.
+type backgroundDetector interface {
+	BackgroundLuminance() (float64, bool)
+}
+
+// termenvDetector queries the terminal via termenv (OSC 11, then
+// $COLORFGBG). It only asks when stdout is a terminal.
+type termenvDetector struct {
+	out *termenv.Output
+}
+
+func (d termenvDetector) BackgroundLuminance() (float64, bool) {
+	if d.out.TTY() == nil {
+		return 0, false
+	}
+	c := termenv.ConvertToRGB(d.out.BackgroundColor())
+	return 0.2126*c.R + 0.7152*c.G + 0.0722*c.B, true
+}
+
+// detectTheme returns the theme for the detected background, falling back
+// to dark when detection fails.
+func detectTheme(d backgroundDetector) Theme {
+	lum, ok := d.BackgroundLuminance()
+	if !ok {
+		return ThemeDark
+	}
+	return chooseTheme(lum)
+}
+
+// lightPalette maps themeStyles names to Catppuccin Latte foregrounds. The
+// dark palette is the styles' declared defaults.
+var lightPalette = map[string]string{
+	"TITLE":            "#8839EF",
+	"TAB_ACTIVE":       "#EFF1F5",
+	"TAB_INACTIVE":     "#6C6F85",
+	"HEADER":           "#1E66F5",
+	"AGENT_ACTIVE":     "#40A02B",
+	"AGENT_STALE":      "#D20F39",
+	"LOCK":             "#FE640B",
+	"SAFE":             "#40A02B",
+	"UNSAFE":           "#D20F39",
+	"DIM":              "#8C8FA1",
+	"MSG_FROM":         "#1E66F5",
+	"MSG_TO":           "#40A02B",
+	"STATUS_BAR":       "#4C4F69",
+	"NEW_BADGE":        "#179299",
+	"PIN_BADGE":        "#EA76CB",
+	"CONCURRENT":       "#DF8E1D",
+	"CONCURRENT_PAIR":  "#DF8E1D",
+	"CONCURRENT_HEAVY": "#D20F39",
+	"CAUSAL":           "#40A02B",
+	"EPOCH_MARKER":     "#DF8E1D",
+	"SEARCH_MATCH":     "#DF8E1D",
+	"DIAGRAM_LINE":     "#8C8FA1",
+	"DIAGRAM_EVENT":    "#4C4F69",
+	"DIAGRAM_MSG":      "#DF8E1D",
+	"DETAIL_HEADER":    "#8839EF",
+	"DETAIL_SECTION":   "#1E66F5",
+}
+
+// lightBackgrounds are the Latte backgrounds for styles that paint one.
+var lightBackgrounds = map[string]string{
+	"TITLE":        "#E6E9EF",
+	"TAB_ACTIVE":   "#8839EF",
+	"TAB_INACTIVE": "#CCD0DA",
+	"STATUS_BAR":   "#E6E9EF",
+}
+
+// applyTheme restyles the named styles for t. ThemeDark leaves the
+// declared defaults untouched.
+func applyTheme(styles map[string]*lipgloss.Style, t Theme) {
+	if t != ThemeLight {
+		return
+	}
+	for name, fg := range lightPalette {
+		if st, ok := styles[name]; ok {
+			*st = st.Foreground(lipgloss.Color(fg))
+		}
+	}
+	for name, bg := range lightBackgrounds {
+		if st, ok := styles[name]; ok {
+			*st = st.Background(lipgloss.Color(bg))
+		}
+	}
+}
+
+// resolveTheme returns the theme to use for a parsed --theme flag,
+// detecting the background only in auto mode.
+func resolveTheme(auto bool, t Theme) Theme {
+	if !auto {
+		return t
+	}
+	return detectTheme(termenvDetector{out: termenv.NewOutput(os.Stdout)})
+}
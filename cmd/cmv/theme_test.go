@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestChooseTheme(t *testing.T) {
+	tests := []struct {
+		lum  float64
+		want Theme
+	}{
+		{1.0, ThemeLight}, // white
+		{0.9, ThemeLight}, // Latte base
+		{0.5, ThemeLight},
+		{0.49, ThemeDark},
+		{0.02, ThemeDark}, // Mocha base
+		{0, ThemeDark},    // black
+	}
+	for _, tt := range tests {
+		if got := chooseTheme(tt.lum); got != tt.want {
+			t.Errorf("chooseTheme(%v) = %v, want %v", tt.lum, got, tt.want)
+		}
+	}
+}
+
+type fakeDetector struct {
+	lum float64
+	ok  bool
+}
+
+func (f fakeDetector) BackgroundLuminance() (float64, bool) { return f.lum, f.ok }
+
+func TestDetectTheme(t *testing.T) {
+	if got := detectTheme(fakeDetector{lum: 0.95, ok: true}); got != ThemeLight {
+		t.Errorf("light background: got %v, want light", got)
+	}
+	if got := detectTheme(fakeDetector{lum: 0.05, ok: true}); got != ThemeDark {
+		t.Errorf("dark background: got %v, want dark", got)
+	}
+	if got := detectTheme(fakeDetector{lum: 0.95, ok: false}); got != ThemeDark {
+		t.Errorf("failed detection should fall back to dark, got %v", got)
+	}
+}
+
+func TestParseThemeFlag(t *testing.T) {
+	if auto, _, err := parseThemeFlag("auto"); !auto || err != nil {
+		t.Errorf("auto: got auto=%v err=%v", auto, err)
+	}
+	if auto, th, err := parseThemeFlag("light"); auto || th != ThemeLight || err != nil {
+		t.Errorf("light: got auto=%v theme=%v err=%v", auto, th, err)
+	}
+	if _, _, err := parseThemeFlag("solarized"); err == nil {
+		t.Error("unknown theme should be an error")
+	}
+}
+
+func TestApplyLightTheme(t *testing.T) {
+	st := lipgloss.NewStyle().Foreground(lipgloss.Color("#A6E3A1"))
+	styles := map[string]*lipgloss.Style{"SAFE": &st}
+
+	applyTheme(styles, ThemeDark)
+	if st.GetForeground() != lipgloss.Color("#A6E3A1") {
+		t.Error("dark theme should keep the declared colors")
+	}
+	applyTheme(styles, ThemeLight)
+	if st.GetForeground() != lipgloss.Color(lightPalette["SAFE"]) {
+		t.Errorf("light theme foreground = %v", st.GetForeground())
+	}
+	for name := range lightPalette {
+		if _, ok := themeStyles[name]; !ok {
+			t.Errorf("lightPalette names unknown style %q", name)
+		}
+	}
+}
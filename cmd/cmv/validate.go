@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/daviddao/clockmail_viewer/internal/snapshot"
+)
+
+// validateSnapshot checks snap for internal inconsistencies that suggest
+// partial or corrupt data, returning one message per issue (nil if none).
+// --json prints these to stderr; --strict makes any of them fatal.
+func validateSnapshot(snap *snapshot.DataSnapshot) []string {
+	var issues []string
+	registered := make(map[string]bool, len(snap.Agents))
+	for _, ag := range snap.Agents {
+		registered[ag.ID] = true
+	}
+
+	for _, l := range snap.Locks {
+		if !registered[l.AgentID] {
+			issues = append(issues, fmt.Sprintf("lock %s held by unregistered agent %q", l.Path, l.AgentID))
+		}
+	}
+	for _, ag := range snap.Agents {
+		if _, ok := snap.FrontierStatus[ag.ID]; !ok && snap.FrontierAvailable {
+			issues = append(issues, fmt.Sprintf("no frontier status for agent %q", ag.ID))
+		}
+	}
+	for _, p := range snap.Frontier {
+		if !registered[p.AgentID] {
+			issues = append(issues, fmt.Sprintf("frontier pointstamp for unregistered agent %q", p.AgentID))
+		}
+	}
+	unknownSenders := make(map[string]bool)
+	for _, e := range snap.Events {
+		if !registered[e.AgentID] && !unknownSenders[e.AgentID] {
+			unknownSenders[e.AgentID] = true
+			issues = append(issues, fmt.Sprintf("events from unregistered agent %q", e.AgentID))
+		}
+	}
+	if n := snap.ActiveAgents + snap.StaleAgents; n != len(snap.Agents) {
+		issues = append(issues, fmt.Sprintf("agent counts (%d active + %d stale) disagree with %d agents",
+			snap.ActiveAgents, snap.StaleAgents, len(snap.Agents)))
+	}
+	if snap.ActiveLocks != len(snap.Locks) {
+		issues = append(issues, fmt.Sprintf("lock count %d disagrees with %d locks", snap.ActiveLocks, len(snap.Locks)))
+	}
+	return issues
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daviddao/clockmail/pkg/model"
+)
+
+// wantIssue asserts that exactly one issue was reported and that it
+// contains substr.
+func wantIssue(t *testing.T, issues []string, substr string) {
+	t.Helper()
+	if len(issues) != 1 || !strings.Contains(issues[0], substr) {
+		t.Errorf("issues = %q, want one containing %q", issues, substr)
+	}
+}
+
+func TestValidateSnapshotConsistent(t *testing.T) {
+	if issues := validateSnapshot(testSnapshot()); len(issues) != 0 {
+		t.Errorf("test snapshot should be consistent, got %q", issues)
+	}
+}
+
+func TestValidateSnapshotLockHolderUnregistered(t *testing.T) {
+	snap := testSnapshot()
+	snap.Locks[0].AgentID = "ghost"
+	wantIssue(t, validateSnapshot(snap), `lock main.go held by unregistered agent "ghost"`)
+}
+
+func TestValidateSnapshotMissingFrontierStatus(t *testing.T) {
+	snap := testSnapshot()
+	delete(snap.FrontierStatus, "bob")
+	wantIssue(t, validateSnapshot(snap), `no frontier status for agent "bob"`)
+}
+
+func TestValidateSnapshotFrontierUnregistered(t *testing.T) {
+	snap := testSnapshot()
+	snap.Frontier = append(snap.Frontier, model.Pointstamp{AgentID: "ghost"})
+	wantIssue(t, validateSnapshot(snap), `frontier pointstamp for unregistered agent "ghost"`)
+}
+
+func TestValidateSnapshotEventsFromUnregistered(t *testing.T) {
+	snap := testSnapshot()
+	snap.Events = append(snap.Events,
+		model.Event{ID: 10, AgentID: "ghost", Kind: model.EventProgress},
+		model.Event{ID: 11, AgentID: "ghost", Kind: model.EventProgress})
+	// Reported once per agent, not per event.
+	wantIssue(t, validateSnapshot(snap), `events from unregistered agent "ghost"`)
+}
+
+func TestValidateSnapshotCounts(t *testing.T) {
+	snap := testSnapshot()
+	snap.StaleAgents++
+	wantIssue(t, validateSnapshot(snap), "agent counts")
+
+	snap = testSnapshot()
+	snap.ActiveLocks = 0
+	wantIssue(t, validateSnapshot(snap), "lock count 0 disagrees with 1 locks")
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 
 	"github.com/daviddao/clockmail/pkg/frontier"
 	"github.com/daviddao/clockmail/pkg/model"
@@ -42,16 +49,18 @@ func testSnapshot() *snapshot.DataSnapshot {
 	}
 
 	return &snapshot.DataSnapshot{
-		Agents:         agents,
-		Events:         events,
-		Locks:          locks,
-		Frontier:       f,
-		FrontierStatus: fStatus,
-		ActiveAgents:   2,
-		StaleAgents:    0,
-		TotalEvents:    4,
-		ActiveLocks:    1,
-		BuiltAt:        now,
+		Agents:            agents,
+		Events:            events,
+		Locks:             locks,
+		Frontier:          f,
+		Pointstamps:       active,
+		FrontierStatus:    fStatus,
+		FrontierAvailable: true,
+		ActiveAgents:      2,
+		StaleAgents:       0,
+		TotalEvents:       4,
+		ActiveLocks:       1,
+		BuiltAt:           now,
 	}
 }
 
@@ -65,6 +74,9 @@ func testModel() uiModel {
 		lastRefresh: time.Now(),
 	}
 	m.help.Width = 80
+	for v := range m.seenEventID {
+		m.seenEventID[v] = latestEventID(snap.Events)
+	}
 	return m
 }
 
@@ -526,7 +538,7 @@ func TestContextHelp(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		got := contextHelp(tt.v)
+		got := contextHelp(tt.v, false)
 		if !strings.Contains(got, tt.must) {
 			t.Errorf("contextHelp(%v) = %q, should contain %q", tt.v, got, tt.must)
 		}
@@ -594,7 +606,7 @@ func TestShortDuration(t *testing.T) {
 
 func TestBuildJSONOutput(t *testing.T) {
 	snap := testSnapshot()
-	out := buildJSONOutput(snap)
+	out := buildJSONOutput(snap, jsonOptions{})
 
 	// Validate structure.
 	if len(out.Agents) != 2 {
@@ -640,12 +652,74 @@ func TestBuildJSONOutput(t *testing.T) {
 	}
 }
 
+func TestBuildJSONOutputBlockers(t *testing.T) {
+	snap := testSnapshot()
+	snap.FrontierStatus["alice"] = frontier.FrontierStatus{
+		BlockedBy: []model.Pointstamp{{AgentID: "bob", Timestamp: model.Timestamp{Epoch: 2, Round: 3}}},
+	}
+
+	// Default output is unchanged: no blocked_by key at all.
+	data, _ := json.Marshal(buildJSONOutput(snap, jsonOptions{}))
+	if strings.Contains(string(data), "blocked_by") {
+		t.Errorf("default output should not include blocked_by: %s", data)
+	}
+
+	out := buildJSONOutput(snap, jsonOptions{Blockers: true})
+	want := []jsonPoint{{AgentID: "bob", Epoch: 2, Round: 3}}
+	if got := out.Agents[0].BlockedBy; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("alice blocked_by = %+v, want %+v", got, want)
+	}
+	if out.Agents[0].Safe {
+		t.Error("a blocked agent should not be safe")
+	}
+	data, _ = json.Marshal(out)
+	if !strings.Contains(string(data), `"blocked_by":[{"agent_id":"bob","epoch":2,"round":3}]`) {
+		t.Errorf("serialized blockers missing: %s", data)
+	}
+}
+
+func TestBuildAgentJSON(t *testing.T) {
+	out, err := buildAgentJSON(testSnapshot(), "alice", defaultDetailLimit)
+	if err != nil {
+		t.Fatalf("buildAgentJSON: %v", err)
+	}
+	if out.Agent.ID != "alice" || out.Agent.Safe || len(out.Agent.BlockedBy) != 1 {
+		t.Errorf("agent = %+v, want alice blocked by one pointstamp", out.Agent)
+	}
+	if len(out.Locks) != 1 || out.Locks[0].Path != "main.go" {
+		t.Errorf("locks_held = %+v, want main.go", out.Locks)
+	}
+	if len(out.Sent) != 1 || out.Sent[0].Body != "hello" {
+		t.Errorf("sent = %+v, want alice's hello", out.Sent)
+	}
+	if len(out.Received) != 1 || out.Received[0].From != "bob" {
+		t.Errorf("received = %+v, want bob's reply", out.Received)
+	}
+
+	data, _ := json.Marshal(out)
+	for _, key := range []string{`"locks_held":[`, `"sent":[`, `"received":[`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("missing %s in %s", key, data)
+		}
+	}
+
+	// bob holds nothing, but the arrays are still present.
+	bob, _ := buildAgentJSON(testSnapshot(), "bob", defaultDetailLimit)
+	if data, _ := json.Marshal(bob); !strings.Contains(string(data), `"locks_held":[]`) {
+		t.Errorf("empty locks_held should serialize as []: %s", data)
+	}
+
+	if _, err := buildAgentJSON(testSnapshot(), "nobody", defaultDetailLimit); err == nil {
+		t.Error("unknown agent should be an error")
+	}
+}
+
 func TestBuildJSONOutputEmptySnapshot(t *testing.T) {
 	snap := &snapshot.DataSnapshot{
 		FrontierStatus: map[string]frontier.FrontierStatus{},
 		BuiltAt:        time.Now(),
 	}
-	out := buildJSONOutput(snap)
+	out := buildJSONOutput(snap, jsonOptions{})
 
 	if len(out.Agents) != 0 {
 		t.Errorf("expected 0 agents, got %d", len(out.Agents))
@@ -1158,7 +1232,7 @@ func TestBuildDiagramData(t *testing.T) {
 		{ID: 4, AgentID: "bob", LamportTS: 5, Kind: model.EventLockReq, Target: "file.go", CreatedAt: now},
 	}
 
-	agentOrder, rows := buildDiagramData(agents, events)
+	agentOrder, rows := buildDiagramData(agents, events, nil, diagramByRegistration)
 
 	// Agent order should match registration order.
 	if len(agentOrder) != 2 || agentOrder[0] != "alice" || agentOrder[1] != "bob" {
@@ -1215,7 +1289,7 @@ func TestBuildDiagramData(t *testing.T) {
 }
 
 func TestBuildDiagramDataEmpty(t *testing.T) {
-	agentOrder, rows := buildDiagramData(nil, nil)
+	agentOrder, rows := buildDiagramData(nil, nil, nil, diagramByRegistration)
 	if len(agentOrder) != 0 {
 		t.Errorf("expected empty agentOrder, got %v", agentOrder)
 	}
@@ -1573,17 +1647,3062 @@ func TestRenderTimelineFilteredEmpty(t *testing.T) {
 }
 
 func TestContextHelpShowsFilter(t *testing.T) {
-	got := contextHelp(viewMessages)
+	got := contextHelp(viewMessages, false)
 	if !strings.Contains(got, "/") {
 		t.Error("messages context help should mention / for filter")
 	}
-	got = contextHelp(viewTimeline)
+	got = contextHelp(viewTimeline, false)
 	if !strings.Contains(got, "/") {
 		t.Error("timeline context help should mention / for filter")
 	}
 	// Other views should not mention filter.
-	got = contextHelp(viewDashboard)
+	got = contextHelp(viewDashboard, false)
 	if strings.Contains(got, "filter") {
 		t.Error("dashboard context help should not mention filter")
 	}
 }
+
+// --- NEW agent badge ---
+
+func TestRenderDashboardNewAgentBadge(t *testing.T) {
+	now := time.Now()
+	snap := testSnapshot()
+	snap.Agents[0].Registered = now.Add(-5 * time.Second) // alice just joined
+	snap.Agents[1].Registered = now.Add(-2 * time.Hour)   // bob is an old hand
+
+	m := testModel()
+	m.snap = snap
+	m.newAgentWindow = 30 * time.Second
+	out := m.renderDashboard()
+
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.Contains(line, "top "): // talker rankings, not agent rows
+		case strings.Contains(line, "alice") && !strings.Contains(line, "held by"):
+			if !strings.Contains(line, "NEW") {
+				t.Errorf("just-registered alice should carry NEW badge: %q", line)
+			}
+		case strings.Contains(line, "bob") && !strings.Contains(line, "BLOCKED"):
+			if strings.Contains(line, "NEW") {
+				t.Errorf("old-registered bob should not carry NEW badge: %q", line)
+			}
+		}
+	}
+}
+
+func TestIsNewAgent(t *testing.T) {
+	now := time.Now()
+	window := 30 * time.Second
+	tests := []struct {
+		name       string
+		registered time.Time
+		window     time.Duration
+		want       bool
+	}{
+		{"just registered", now.Add(-time.Second), window, true},
+		{"outside window", now.Add(-time.Minute), window, false},
+		{"zero window disables", now, 0, false},
+		{"zero registered time", time.Time{}, window, false},
+	}
+	for _, tt := range tests {
+		ag := model.Agent{ID: "x", Registered: tt.registered, LastSeen: now}
+		if got := isNewAgent(ag, tt.window, now); got != tt.want {
+			t.Errorf("%s: isNewAgent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderAgentDetailNewBadgeAlongsideActive(t *testing.T) {
+	m := testModel()
+	m.newAgentWindow = 30 * time.Second
+	out := m.renderAgentDetailFor("alice")
+
+	if !strings.Contains(out, "ACTIVE") || !strings.Contains(out, "NEW") {
+		t.Error("freshly registered active agent should show both ACTIVE and NEW badges")
+	}
+}
+
+// --- Heartbeat collapsing ---
+
+func TestCollapseHeartbeatsInterruptedByMessage(t *testing.T) {
+	events := []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 40, Kind: model.EventProgress},
+		{ID: 2, AgentID: "alice", LamportTS: 41, Kind: model.EventProgress},
+		{ID: 3, AgentID: "alice", LamportTS: 42, Kind: model.EventProgress},
+		{ID: 4, AgentID: "alice", LamportTS: 43, Kind: model.EventMsg, Target: "bob", Body: "hi"},
+		{ID: 5, AgentID: "alice", LamportTS: 44, Kind: model.EventProgress},
+		{ID: 6, AgentID: "alice", LamportTS: 45, Kind: model.EventProgress},
+		{ID: 7, AgentID: "bob", LamportTS: 46, Kind: model.EventProgress},
+	}
+
+	items := collapseHeartbeats(events)
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items (run, msg, run, single), got %d", len(items))
+	}
+	if len(items[0].run) != 3 || items[0].event.ID != 3 {
+		t.Errorf("first item should be a 3-heartbeat run ending at ID 3, got run=%d id=%d",
+			len(items[0].run), items[0].event.ID)
+	}
+	if items[1].run != nil || items[1].event.Kind != model.EventMsg {
+		t.Error("second item should be the message, uncollapsed")
+	}
+	if len(items[2].run) != 2 {
+		t.Errorf("third item should be a 2-heartbeat run, got %d", len(items[2].run))
+	}
+	if items[3].run != nil || items[3].event.AgentID != "bob" {
+		t.Error("bob's lone heartbeat should not join alice's run")
+	}
+}
+
+func TestCollapseHeartbeatsEmpty(t *testing.T) {
+	if items := collapseHeartbeats(nil); len(items) != 0 {
+		t.Errorf("expected no items, got %d", len(items))
+	}
+}
+
+func TestRenderTimelineCollapsedHeartbeats(t *testing.T) {
+	m := testModel()
+	m.width = 120
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 40, Kind: model.EventProgress},
+		{ID: 2, AgentID: "alice", LamportTS: 41, Kind: model.EventProgress},
+		{ID: 3, AgentID: "alice", LamportTS: 63, Kind: model.EventProgress},
+		{ID: 4, AgentID: "alice", LamportTS: 64, Kind: model.EventMsg, Target: "bob", Body: "done"},
+	}
+
+	out := stripAnsi(m.renderTimeline())
+	if strings.Count(out, "heartbeat") != 3 {
+		t.Errorf("uncollapsed timeline should show 3 heartbeats, got:\n%s", out)
+	}
+
+	m.collapseBeats = true
+	out = stripAnsi(m.renderTimeline())
+	if !strings.Contains(out, "alice: 3 heartbeats (L:40–63)") {
+		t.Errorf("collapsed timeline should show run summary, got:\n%s", out)
+	}
+	if !strings.Contains(out, "done") {
+		t.Error("message should still be rendered when collapsing heartbeats")
+	}
+}
+
+func TestHeartbeatToggleOnlyInTimeline(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	m = updated.(uiModel)
+	if !m.collapseBeats {
+		t.Error("H in Timeline should enable heartbeat collapsing")
+	}
+
+	m.activeView = viewDashboard
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	m = updated.(uiModel)
+	if !m.collapseBeats {
+		t.Error("H outside Timeline should not toggle collapsing")
+	}
+}
+
+// --- Two-column Messages layout ---
+
+func TestMessagesTwoColumnsOnWideTerminal(t *testing.T) {
+	m := testModel()
+	m.activeView = viewMessages
+	m.messageColumns = true
+	m.height = 40
+
+	m.width = 160
+	if got := m.messageColumnCount(); got != 2 {
+		t.Fatalf("width 160: expected 2 columns, got %d", got)
+	}
+	out := stripAnsi(m.View())
+	var twoCol bool
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "│") && strings.Contains(line, "[L:") {
+			twoCol = true
+		}
+	}
+	if !twoCol {
+		t.Errorf("width 160: expected a column separator beside message lines:\n%s", out)
+	}
+	if !strings.Contains(out, "hello") || !strings.Contains(out, "hi back") {
+		t.Error("both messages should be visible across the two columns")
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if left, right, ok := strings.Cut(line, "│"); ok && strings.Contains(left, "[L:") {
+			if !strings.Contains(left, "[L:1]") || !strings.Contains(right, "[L:2]") {
+				t.Errorf("width 160: want the older message left, the newer right: %q", line)
+			}
+			break
+		}
+	}
+
+	m.width = 100
+	if got := m.messageColumnCount(); got != 1 {
+		t.Fatalf("width 100: expected 1 column, got %d", got)
+	}
+	out = stripAnsi(m.View())
+	if strings.Contains(out, "│") {
+		t.Errorf("width 100: expected single-column layout:\n%s", out)
+	}
+}
+
+func TestMessagesColumnsOffByDefault(t *testing.T) {
+	m := testModel()
+	m.width = 160
+	if got := m.messageColumnCount(); got != 1 {
+		t.Errorf("columns should be opt-in, got %d", got)
+	}
+}
+
+func TestMessageColumnsKeepsBlocksWhole(t *testing.T) {
+	m := testModel()
+	m.width = 160
+	m.messageColumns = true
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: "first"},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventMsg, Target: "alice", Body: "second"},
+		{ID: 3, AgentID: "alice", LamportTS: 3, Kind: model.EventMsg, Target: "bob", Body: "third"},
+		{ID: 4, AgentID: "bob", LamportTS: 4, Kind: model.EventMsg, Target: "alice", Body: "fourth"},
+	}
+
+	out := stripAnsi(m.renderMessageColumns())
+	lines := strings.Split(out, "\n")
+	// Header + 4 block lines split 2/2: oldest two on the left.
+	if len(lines) < 3 {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	left, right, _ := strings.Cut(lines[1], "│")
+	if !strings.Contains(left, "[L:1]") || !strings.Contains(right, "[L:3]") {
+		t.Errorf("expected older blocks on the left and newer on the right: %q", lines[1])
+	}
+}
+
+// --- Snapshot build failures ---
+
+func TestStatusBarShowsSnapshotTimeout(t *testing.T) {
+	m := testModel()
+	prev := m.snap
+
+	updated, _ := m.Update(snapshotReadyMsg{err: snapshot.ErrBuildTimeout})
+	m = updated.(uiModel)
+	if m.snap != prev {
+		t.Error("a timed-out build should keep the previous snapshot")
+	}
+	if !strings.Contains(m.renderStatusBar(), "snapshot timed out") {
+		t.Errorf("status bar should report the timeout, got %q", m.renderStatusBar())
+	}
+
+	updated, _ = m.Update(snapshotReadyMsg{snap: testSnapshot()})
+	m = updated.(uiModel)
+	if strings.Contains(m.renderStatusBar(), "timed out") {
+		t.Error("timeout status should clear after a successful build")
+	}
+}
+
+// --- Per-view footers ---
+
+func TestViewFootersReportCounts(t *testing.T) {
+	m := testModel()
+	tests := []struct {
+		name string
+		out  string
+		want string
+	}{
+		{"messages", m.renderMessages(), "2 messages (0 filtered)"},
+		{"locks", m.renderLocks(), "1 locks (0 expired)"},
+		{"frontier", m.renderFrontier(), "1 safe / 1 blocked"},
+		{"diagram", m.renderDiagram(), "4 rows, 2 agents"},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(stripAnsi(tt.out), tt.want) {
+			t.Errorf("%s footer: want %q in:\n%s", tt.name, tt.want, stripAnsi(tt.out))
+		}
+	}
+}
+
+func TestMessagesFooterCountsFiltered(t *testing.T) {
+	m := testModel()
+	m.snap.Events = append(m.snap.Events,
+		model.Event{ID: 5, AgentID: "bob", LamportTS: 5, Kind: model.EventMsg, Target: "carol", Body: "psst"})
+	m.filterAgent = "alice"
+
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, "2 messages (1 filtered)") {
+		t.Errorf("expected filtered count in footer:\n%s", out)
+	}
+}
+
+func TestLocksFooterCountsExpired(t *testing.T) {
+	m := testModel()
+	m.snap.Locks[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+	out := stripAnsi(m.renderLocks())
+	if !strings.Contains(out, "1 locks (1 expired)") {
+		t.Errorf("expected expired count in footer:\n%s", out)
+	}
+}
+
+// --- Resize anchoring ---
+
+func TestResizeKeepsTimelineAnchor(t *testing.T) {
+	long := strings.Repeat("lorem ipsum dolor sit amet ", 20)
+	m := testModel()
+	m.activeView = viewTimeline
+	m.width = 160
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventLockReq, Target: "main.go"},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventMsg, Target: "alice", Body: long},
+		{ID: 3, AgentID: "alice", LamportTS: 3, Kind: model.EventMsg, Target: "bob", Body: long},
+	}
+
+	// Scroll so alice's lock (rendered last, below two long messages) is at the top.
+	_, owners := m.timelineLayout()
+	m.scrollPos = firstLineOf(owners, 1)
+	if m.scrollPos < 0 {
+		t.Fatal("anchor event not rendered")
+	}
+
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 60, Height: 24})
+	m = updated.(uiModel)
+
+	_, owners = m.timelineLayout()
+	if got := lineOwner(owners, m.scrollPos); got != 1 {
+		t.Errorf("after resize, top line belongs to event %d, want 1", got)
+	}
+	if m.scrollPos != firstLineOf(owners, 1) {
+		t.Errorf("scrollPos %d should be the anchor's first line %d", m.scrollPos, firstLineOf(owners, 1))
+	}
+	top := strings.Split(m.renderTimeline(), "\n")[m.scrollPos]
+	if !strings.Contains(top, "lock main.go") {
+		t.Errorf("anchored event should be at the viewport top, got %q", stripAnsi(top))
+	}
+}
+
+func TestResizeAnchorsWrappedBodyToItsEvent(t *testing.T) {
+	long := strings.Repeat("word ", 60)
+	m := testModel()
+	m.activeView = viewTimeline
+	m.width = 60
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: long},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventMsg, Target: "alice", Body: long},
+	}
+	_, owners := m.timelineLayout()
+	// Top of viewport is mid-way through event 1's wrapped body.
+	m.scrollPos = firstLineOf(owners, 1) + 2
+
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 200, Height: 24})
+	m = updated.(uiModel)
+	_, owners = m.timelineLayout()
+	if m.scrollPos != firstLineOf(owners, 1) {
+		t.Errorf("expected scroll to snap to event 1's header line %d, got %d", firstLineOf(owners, 1), m.scrollPos)
+	}
+}
+
+func TestLineOwnerClamps(t *testing.T) {
+	owners := []int64{0, 0, 5, 5, 7}
+	if got := lineOwner(owners, 99); got != 7 {
+		t.Errorf("lineOwner past end = %d, want 7", got)
+	}
+	if got := lineOwner(nil, 3); got != 0 {
+		t.Errorf("lineOwner(nil) = %d, want 0", got)
+	}
+	if got := firstLineOf(owners, 5); got != 2 {
+		t.Errorf("firstLineOf(5) = %d, want 2", got)
+	}
+	if got := firstLineOf(owners, 9); got != -1 {
+		t.Errorf("firstLineOf(missing) = %d, want -1", got)
+	}
+}
+
+// --- Concurrency bracket intensity ---
+
+func TestConcurrentStyleIntensifiesWithGroupSize(t *testing.T) {
+	two, three, four := concurrentStyleFor(2), concurrentStyleFor(3), concurrentStyleFor(4)
+	if two.GetForeground() == four.GetForeground() {
+		t.Error("4-way groups should use a different color than 2-way groups")
+	}
+	if !two.GetFaint() {
+		t.Error("2-way groups should be dimmed")
+	}
+	if three.GetFaint() || !three.GetBold() {
+		t.Error("3-way groups should be brighter than 2-way groups")
+	}
+	if concurrentStyleFor(7).GetForeground() != four.GetForeground() {
+		t.Error("all groups of 4+ should share the heavy style")
+	}
+}
+
+func TestRenderTimelineFourWayKeepsBracketGlyphs(t *testing.T) {
+	m := testModel()
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "a", LamportTS: 5, Kind: model.EventProgress},
+		{ID: 2, AgentID: "b", LamportTS: 5, Kind: model.EventProgress},
+		{ID: 3, AgentID: "c", LamportTS: 5, Kind: model.EventProgress},
+		{ID: 4, AgentID: "d", LamportTS: 5, Kind: model.EventProgress},
+	}
+	out := m.renderTimeline()
+	for _, glyph := range []string{"╓", "║", "╙"} {
+		if !strings.Contains(out, glyph) {
+			t.Errorf("4-way group should still use bracket glyph %q", glyph)
+		}
+	}
+}
+
+// --- Color environment overrides ---
+
+func TestApplyColorEnv(t *testing.T) {
+	active := lipgloss.NewStyle().Foreground(lipgloss.Color("#A6E3A1"))
+	stale := lipgloss.NewStyle().Foreground(lipgloss.Color("#F38BA8"))
+	styles := map[string]*lipgloss.Style{
+		"AGENT_ACTIVE": &active,
+		"AGENT_STALE":  &stale,
+	}
+
+	var warn strings.Builder
+	applyColorEnv(styles, []string{
+		"HOME=/root",
+		"CMV_COLOR_AGENT_ACTIVE=#00FF00",
+		"CMV_COLOR_AGENT_STALE=red",
+		"CMV_COLOR_NOPE=#123456",
+	}, &warn)
+
+	if got := active.GetForeground(); got != lipgloss.Color("#00FF00") {
+		t.Errorf("AGENT_ACTIVE foreground = %v, want #00FF00", got)
+	}
+	if got := stale.GetForeground(); got != lipgloss.Color("#F38BA8") {
+		t.Errorf("invalid value should leave AGENT_STALE unchanged, got %v", got)
+	}
+	if !strings.Contains(warn.String(), "CMV_COLOR_AGENT_STALE") {
+		t.Error("expected a warning for the invalid color")
+	}
+	if !strings.Contains(warn.String(), "CMV_COLOR_NOPE") {
+		t.Error("expected a warning for the unknown style name")
+	}
+}
+
+func TestIsHexColor(t *testing.T) {
+	for s, want := range map[string]bool{
+		"#fff": true, "#A6E3A1": true, "A6E3A1": false, "#A6E3A": false, "#GGGGGG": false, "": false,
+	} {
+		if got := isHexColor(s); got != want {
+			t.Errorf("isHexColor(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+// --- Leaked lock detection ---
+
+func TestIsLeakedLock(t *testing.T) {
+	now := time.Now()
+	lock := model.Lock{Path: "main.go", AgentID: "alice", LamportTS: 3, ExpiresAt: now.Add(time.Hour)}
+	active := []model.Agent{{ID: "alice", LastSeen: now}}
+	stale := []model.Agent{{ID: "alice", LastSeen: now.Add(-30 * time.Minute)}}
+	release := []model.Event{{ID: 9, AgentID: "alice", LamportTS: 7, Kind: model.EventLockRel, Target: "main.go"}}
+	oldRelease := []model.Event{{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventLockRel, Target: "main.go"}}
+
+	tests := []struct {
+		name   string
+		agents []model.Agent
+		events []model.Event
+		want   bool
+	}{
+		{"stale holder, no release", stale, nil, true},
+		{"holder unregistered, no release", nil, nil, true},
+		{"active holder", active, nil, false},
+		{"stale holder but released", stale, release, false},
+		{"release predates acquisition", stale, oldRelease, true},
+	}
+	for _, tt := range tests {
+		if got := isLeakedLock(lock, tt.agents, tt.events, now); got != tt.want {
+			t.Errorf("%s: isLeakedLock = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderLocksLeakedSection(t *testing.T) {
+	m := testModel()
+	out := m.renderLocks()
+	if strings.Contains(out, "Leaked Locks") {
+		t.Error("lock held by an active agent should not be reported as leaked")
+	}
+
+	m.snap.Agents[0].LastSeen = time.Now().Add(-time.Hour) // alice goes stale
+	out = stripAnsi(m.renderLocks())
+	if !strings.Contains(out, "Leaked Locks") {
+		t.Fatalf("expected Leaked Locks section:\n%s", out)
+	}
+	if !strings.Contains(out, "holder last seen") || !strings.Contains(out, "no release") {
+		t.Errorf("leaked lock line should explain why:\n%s", out)
+	}
+}
+
+// --- No-wrap horizontal panning ---
+
+func TestMessagesNoWrapPansHorizontally(t *testing.T) {
+	body := "START " + strings.Repeat("x", 150) + " FINISH"
+	m := testModel()
+	m.activeView = viewMessages
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: body},
+	}
+
+	wrapped := m.renderMessageBlocks()[0]
+	if strings.Count(wrapped, "\n") <= 2 {
+		t.Fatal("sanity: wrap mode should spread the long body over several lines")
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	m = updated.(uiModel)
+	if !m.noWrap {
+		t.Fatal("w in Messages should switch to no-wrap mode")
+	}
+	block := m.renderMessageBlocks()[0]
+	if got := strings.Count(block, "\n"); got != 2 {
+		t.Errorf("no-wrap: expected header + one body line, got %d lines:\n%s", got, block)
+	}
+	if !strings.Contains(block, "START") || strings.Contains(block, "FINISH") {
+		t.Errorf("no-wrap at offset 0 should show the start only:\n%s", block)
+	}
+
+	for i := 0; i < 12; i++ {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+		m = updated.(uiModel)
+	}
+	block = m.renderMessageBlocks()[0]
+	if !strings.Contains(block, "FINISH") || strings.Contains(block, "START") {
+		t.Errorf("panning right should reveal the end of the body:\n%s", block)
+	}
+
+	for i := 0; i < 50; i++ {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+		m = updated.(uiModel)
+	}
+	if m.hScroll != 0 {
+		t.Errorf("panning left should stop at 0, got %d", m.hScroll)
+	}
+}
+
+func TestHSliceANSIAware(t *testing.T) {
+	styled := dimStyle.Render("abcdef")
+	if got := stripAnsi(hSlice(styled, 2, 3)); got != "cde" {
+		t.Errorf("hSlice = %q, want %q", got, "cde")
+	}
+	if got := hSlice("abc", 10, 5); got != "" {
+		t.Errorf("hSlice past end = %q, want empty", got)
+	}
+}
+
+// --- Wall-clock dividers ---
+
+func TestRenderTimelineMinuteDividers(t *testing.T) {
+	base := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	m := testModel()
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventProgress, CreatedAt: base.Add(5 * time.Second)},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventProgress, CreatedAt: base.Add(40 * time.Second)},
+		{ID: 3, AgentID: "alice", LamportTS: 3, Kind: model.EventProgress, CreatedAt: base.Add(65 * time.Second)},
+	}
+
+	out := stripAnsi(m.renderTimeline())
+	if got := strings.Count(out, "───"); got != 2 {
+		t.Fatalf("expected exactly one divider (2 rule segments), got %d:\n%s", got, out)
+	}
+	// Newest first: event 3 (next minute), divider, then events 2 and 1.
+	lines := strings.Split(out, "\n")
+	var order []string
+	for _, l := range lines {
+		switch {
+		case strings.Contains(l, "───"):
+			order = append(order, "div")
+		case strings.Contains(l, "[L:"):
+			order = append(order, strings.Fields(l)[0])
+		}
+	}
+	want := "[L:3 div [L:2 [L:1"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("divider placement = %q, want %q", got, want)
+	}
+	if !strings.Contains(out, "10 minutes ago") {
+		t.Errorf("divider should label the older bucket's age:\n%s", out)
+	}
+}
+
+func TestAgoLabel(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "just now"},
+		{90 * time.Second, "1 minute ago"},
+		{2 * time.Minute, "2 minutes ago"},
+		{90 * time.Minute, "1 hour ago"},
+		{5 * time.Hour, "5 hours ago"},
+	}
+	for _, tt := range tests {
+		if got := agoLabel(tt.d); got != tt.want {
+			t.Errorf("agoLabel(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+// --- Diagram pinning ---
+
+func TestBuildDiagramDataAllowedAgents(t *testing.T) {
+	agents := []model.Agent{{ID: "alice"}, {ID: "bob"}, {ID: "carol"}}
+	events := []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: "to bob"},
+		{ID: 2, AgentID: "alice", LamportTS: 2, Kind: model.EventMsg, Target: "carol", Body: "to carol"},
+		{ID: 3, AgentID: "carol", LamportTS: 3, Kind: model.EventProgress},
+		{ID: 4, AgentID: "bob", LamportTS: 4, Kind: model.EventProgress},
+	}
+
+	agentOrder, rows := buildDiagramData(agents, events, map[string]bool{"alice": true, "bob": true}, diagramByRegistration)
+
+	if len(agentOrder) != 2 || agentOrder[0] != "alice" || agentOrder[1] != "bob" {
+		t.Fatalf("agentOrder = %v, want [alice bob]", agentOrder)
+	}
+	// carol's only event (ts=3) is dropped, so its row disappears.
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	for _, row := range rows {
+		if _, ok := row.cells["carol"]; ok {
+			t.Errorf("row ts=%d has a cell for hidden agent carol", row.lamportTS)
+		}
+		for _, msg := range row.messages {
+			if msg.toAgent == "carol" || msg.fromAgent == "carol" {
+				t.Errorf("row ts=%d has an arrow to hidden agent: %+v", row.lamportTS, msg)
+			}
+		}
+	}
+	if len(rows[0].messages) != 1 {
+		t.Errorf("alice->bob arrow should remain, got %d messages", len(rows[0].messages))
+	}
+	// alice's send to carol still shows as an event in alice's column.
+	if rows[1].cells["alice"].label != ">" || len(rows[1].messages) != 0 {
+		t.Errorf("ts=2: want alice cell without arrow, got cell %+v messages %d",
+			rows[1].cells["alice"], len(rows[1].messages))
+	}
+}
+
+func TestPinKeyRestrictsDiagram(t *testing.T) {
+	m := testModel()
+	m.activeView = viewDashboard
+	m.selectedAgent = 1 // bob
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m = updated.(uiModel)
+	if !m.pinned["bob"] || len(m.pinned) != 1 {
+		t.Fatalf("expected bob pinned, got %v", m.pinned)
+	}
+	if !strings.Contains(stripAnsi(m.renderDashboard()), "PIN") {
+		t.Error("dashboard should badge the pinned agent")
+	}
+
+	out := stripAnsi(m.renderDiagram())
+	if !strings.Contains(out, "showing 1 of 2 agents") {
+		t.Errorf("diagram should note the pinned subset:\n%s", out)
+	}
+	for _, l := range strings.Split(out, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), "L ") && strings.Contains(l, "alice") {
+			t.Errorf("alice column should be hidden: %q", l)
+		}
+	}
+
+	// Toggling again unpins and restores all columns.
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m = updated.(uiModel)
+	if len(m.pinned) != 0 {
+		t.Errorf("expected no pins after second toggle, got %v", m.pinned)
+	}
+}
+
+// --- Agent Detail folding ---
+
+func TestAgentDetailFoldSection(t *testing.T) {
+	m := testModel()
+	m.activeView = viewAgentDetail
+	m.detailAgentID = "alice"
+
+	before := stripAnsi(m.renderAgentDetailFor("alice"))
+	if !strings.Contains(before, "[L:1] -> bob: hello") {
+		t.Fatalf("expected alice's sent message before folding:\n%s", before)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'2'}})
+	m = updated.(uiModel)
+	if !m.foldedSections[sectionSent] {
+		t.Fatal("key 2 should fold Messages Sent")
+	}
+
+	out := stripAnsi(m.renderAgentDetailFor("alice"))
+	if strings.Contains(out, "[L:1] -> bob: hello") {
+		t.Errorf("folded Messages Sent should hide its rows:\n%s", out)
+	}
+	if !strings.Contains(out, "Messages Sent (1)") {
+		t.Errorf("folded section should keep its header with a count:\n%s", out)
+	}
+	// Other sections are unaffected.
+	if !strings.Contains(out, "main.go") || !strings.Contains(out, "bob: hi back") {
+		t.Errorf("unfolded sections should still render:\n%s", out)
+	}
+
+	// Pressing 2 again unfolds.
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'2'}})
+	m = updated.(uiModel)
+	if !strings.Contains(stripAnsi(m.renderAgentDetailFor("alice")), "[L:1] -> bob: hello") {
+		t.Error("second press should unfold Messages Sent")
+	}
+}
+
+func TestFoldKeyIgnoredOutsideAgentDetail(t *testing.T) {
+	m := testModel()
+	m.activeView = viewDashboard
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'1'}})
+	if updated.(uiModel).foldedSections[sectionLocks] {
+		t.Error("fold keys should only act in Agent Detail")
+	}
+}
+
+// --- Unknown message targets ---
+
+func TestUnknownTargets(t *testing.T) {
+	agents := []model.Agent{{ID: "alice"}, {ID: "bob"}}
+	events := []model.Event{
+		{ID: 1, AgentID: "alice", Kind: model.EventMsg, Target: "bob", Body: "real"},
+		{ID: 2, AgentID: "alice", Kind: model.EventMsg, Target: "ghost", Body: "typo"},
+		{ID: 3, AgentID: "alice", Kind: model.EventLockReq, Target: "ghost.go"},
+	}
+	got := unknownTargets(events, agents)
+	if !got[2] {
+		t.Error("message to unregistered ghost should be flagged")
+	}
+	if got[1] {
+		t.Error("message to registered bob should not be flagged")
+	}
+	if got[3] {
+		t.Error("lock targets are paths, not agents, and should not be flagged")
+	}
+}
+
+func TestUnknownTargetMarkerRendered(t *testing.T) {
+	m := testModel()
+	m.snap.Events = append(m.snap.Events, model.Event{
+		ID: 5, AgentID: "alice", LamportTS: 12, Kind: model.EventMsg, Target: "ghost", Body: "anyone?",
+	})
+
+	for name, out := range map[string]string{
+		"messages": stripAnsi(m.renderMessages()),
+		"timeline": stripAnsi(m.renderTimeline()),
+	} {
+		if got := strings.Count(out, "unknown target"); got != 1 {
+			t.Errorf("%s: expected one unknown target marker, got %d:\n%s", name, got, out)
+		}
+		if !strings.Contains(out, "ghost \u26a0 unknown target") {
+			t.Errorf("%s: marker should follow the ghost target:\n%s", name, out)
+		}
+	}
+}
+
+// --- Reset ---
+
+func TestResetKeyRestoresDefaults(t *testing.T) {
+	m := testModel()
+	m.activeView = viewMessages
+	snap := m.snap
+	m.filterAgent = "alice"
+	m.pinned = map[string]bool{"bob": true}
+	m.collapseBeats = true
+	m.messageColumns = true
+	m.noWrap = true
+	m.hScroll = 16
+	m.scrollPos = 5
+	m.foldedSections[sectionSent] = true
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	m = updated.(uiModel)
+
+	if m.filterAgent != "" || len(m.pinned) != 0 || m.collapseBeats || m.messageColumns ||
+		m.noWrap || m.hScroll != 0 || m.scrollPos != 0 || m.foldedSections != [sectionCount]bool{} {
+		t.Errorf("view state not reset: %+v", m)
+	}
+	if m.snap != snap {
+		t.Error("reset must keep the live snapshot")
+	}
+	if m.activeView != viewMessages {
+		t.Errorf("reset should stay in the current view, got %s", m.activeView)
+	}
+}
+
+// --- Compact frontier ---
+
+func TestSummarizeFrontier(t *testing.T) {
+	points := []model.Pointstamp{
+		{AgentID: "carol", Timestamp: model.Timestamp{Epoch: 0, Round: 2}},
+		{AgentID: "alice", Timestamp: model.Timestamp{Epoch: 1, Round: 0}},
+		{AgentID: "bob", Timestamp: model.Timestamp{Epoch: 0, Round: 1}},
+	}
+	want := "e0: bob,carol | e1: alice (3 points)"
+	if got := summarizeFrontier(points); got != want {
+		t.Errorf("summarizeFrontier = %q, want %q", got, want)
+	}
+	if got := summarizeFrontier(points[:1]); got != "e0: carol (1 point)" {
+		t.Errorf("single point = %q", got)
+	}
+	if got := summarizeFrontier(nil); got != "(empty)" {
+		t.Errorf("empty = %q", got)
+	}
+}
+
+func TestFrontierCompactToggle(t *testing.T) {
+	m := testModel()
+	m.activeView = viewFrontier
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	m = updated.(uiModel)
+	if !m.frontierCompact {
+		t.Fatal("c should enable compact mode in Frontier")
+	}
+	out := stripAnsi(m.renderFrontier())
+	if !strings.Contains(out, summarizeFrontier(m.snap.Frontier)) {
+		t.Errorf("compact frontier line missing:\n%s", out)
+	}
+	if strings.Contains(out, "@ epoch=") {
+		t.Errorf("compact mode should replace the per-point lines:\n%s", out)
+	}
+}
+
+// --- Relationships ---
+
+func TestAgentRelationships(t *testing.T) {
+	events := []model.Event{
+		{ID: 1, AgentID: "alice", Kind: model.EventMsg, Target: "bob"},
+		{ID: 2, AgentID: "alice", Kind: model.EventMsg, Target: "bob"},
+		{ID: 3, AgentID: "bob", Kind: model.EventMsg, Target: "alice"},
+		{ID: 4, AgentID: "carol", Kind: model.EventMsg, Target: "alice"},
+		{ID: 5, AgentID: "carol", Kind: model.EventMsg, Target: "bob"},        // not involving alice
+		{ID: 6, AgentID: "alice", Kind: model.EventLockReq, Target: "bob.go"}, // not a message
+	}
+	got := agentRelationships(events, "alice")
+	want := map[string]relationship{
+		"bob":   {Sent: 2, Recv: 1},
+		"carol": {Sent: 0, Recv: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("agentRelationships = %v, want %v", got, want)
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("%s: got %+v, want %+v", id, got[id], w)
+		}
+	}
+}
+
+func TestAgentDetailShowsRelationships(t *testing.T) {
+	m := testModel()
+	out := stripAnsi(m.renderAgentDetailFor("alice"))
+	if !strings.Contains(out, "↔ bob: 1 sent, 1 recv") {
+		t.Errorf("expected relationship line for bob:\n%s", out)
+	}
+}
+
+// --- Long message bodies ---
+
+func longBodyModel(n int) uiModel {
+	m := testModel()
+	m.activeView = viewMessages
+	m.snap.Events = []model.Event{{
+		ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob",
+		Body: strings.Repeat("lorem ipsum ", n/12),
+	}}
+	m.maxBody = defaultMaxBody
+	return m
+}
+
+func TestLongBodyClipped(t *testing.T) {
+	m := longBodyModel(100 * 1024)
+
+	out := stripAnsi(m.renderMessages())
+	if lines := strings.Count(out, "\n"); lines > 60 {
+		t.Errorf("100KB body rendered %d lines, want a bounded count", lines)
+	}
+	if !strings.Contains(out, "chars, press Enter to expand)") {
+		t.Errorf("expected expand note:\n%s", out)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(uiModel)
+	if !m.expandBodies {
+		t.Fatal("Enter in Messages should expand bodies")
+	}
+	if out := stripAnsi(m.renderMessages()); strings.Contains(out, "press Enter to expand") {
+		t.Error("expanded body should not carry the note")
+	}
+}
+
+func TestClipBody(t *testing.T) {
+	if got, n := clipBody("short", 10); got != "short" || n != 0 {
+		t.Errorf("short body = %q, %d", got, n)
+	}
+	if got, n := clipBody("abcdef", 0); got != "abcdef" || n != 0 {
+		t.Errorf("max 0 should never clip, got %q, %d", got, n)
+	}
+	// "é" is two bytes: a cut inside it backs off to the rune start.
+	got, n := clipBody("aé", 2)
+	if got != "a" || n != 1 {
+		t.Errorf("clipBody(aé, 2) = %q, %d; want \"a\", 1", got, n)
+	}
+}
+
+func BenchmarkRenderMessagesLongBody(b *testing.B) {
+	m := longBodyModel(100 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.renderMessages()
+	}
+}
+
+// --- Lock epoch divergence ---
+
+func TestLocksEpochDivergenceNote(t *testing.T) {
+	m := testModel()
+	m.snap.Agents[0].Epoch = 4 // alice moved on; her lock was taken at e1
+	m.snap.Locks = append(m.snap.Locks, model.Lock{
+		Path: "bob.go", AgentID: "bob", LamportTS: 6, Epoch: 0, ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	out := stripAnsi(m.renderLocks())
+	if !strings.Contains(out, "held at e1, holder now at e4") {
+		t.Errorf("expected divergence note for alice's lock:\n%s", out)
+	}
+	if strings.Count(out, "holder now at") != 1 {
+		t.Errorf("bob's lock matches his epoch and should not be flagged:\n%s", out)
+	}
+}
+
+// --- Diagram timestamp column ---
+
+func TestDiagramTimestampColumnAligned(t *testing.T) {
+	m := testModel()
+	m.snap.Agents = m.snap.Agents[:1] // alice only
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventProgress},
+		{ID: 2, AgentID: "alice", LamportTS: 42, Kind: model.EventProgress},
+		{ID: 3, AgentID: "alice", LamportTS: 1000, Kind: model.EventProgress},
+	}
+
+	out := stripAnsi(m.renderDiagram())
+	var cols []int
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) == 2 && f[1] == "*" {
+			cols = append(cols, strings.Index(line, "*"))
+			// Right-aligned: the label ends right before the two-space gap.
+			if !strings.HasSuffix(strings.TrimRight(line[:strings.Index(line, "*")], " "), f[0]) {
+				t.Errorf("label not adjacent to gap: %q", line)
+			}
+		}
+	}
+	if len(cols) != 3 {
+		t.Fatalf("expected 3 event rows, got %d:\n%s", len(cols), out)
+	}
+	for _, c := range cols[1:] {
+		if c != cols[0] {
+			t.Errorf("agent column shifts with TS magnitude: %v\n%s", cols, out)
+			break
+		}
+	}
+	if !strings.Contains(out, "     1  *") || !strings.Contains(out, "  1000  *") {
+		t.Errorf("labels should be right-aligned to the widest TS:\n%s", out)
+	}
+}
+
+// --- Unseen tab badges ---
+
+func TestTabBadgeForUnseenMessages(t *testing.T) {
+	m := testModel()
+	m.activeView = viewDashboard
+	if strings.Contains(stripAnsi(m.renderTabBar()), "(") {
+		t.Fatalf("no badges expected before new events: %q", stripAnsi(m.renderTabBar()))
+	}
+
+	// Two messages and a heartbeat arrive while on the Dashboard.
+	snap := *m.snap
+	snap.Events = append(append([]model.Event{}, snap.Events...),
+		model.Event{ID: 5, AgentID: "bob", LamportTS: 11, Kind: model.EventMsg, Target: "alice", Body: "new 1"},
+		model.Event{ID: 6, AgentID: "alice", LamportTS: 12, Kind: model.EventMsg, Target: "bob", Body: "new 2"},
+		model.Event{ID: 7, AgentID: "bob", LamportTS: 13, Kind: model.EventProgress},
+	)
+	updated, _ := m.Update(snapshotReadyMsg{snap: &snap})
+	m = updated.(uiModel)
+
+	tabs := stripAnsi(m.renderTabBar())
+	if !strings.Contains(tabs, "Messages(2)") {
+		t.Errorf("expected Messages(2) badge: %q", tabs)
+	}
+	if !strings.Contains(tabs, "Timeline(3)") {
+		t.Errorf("expected Timeline(3) badge: %q", tabs)
+	}
+	if strings.Contains(tabs, "Dashboard(") || strings.Contains(tabs, "Locks(") {
+		t.Errorf("unexpected badge: %q", tabs)
+	}
+
+	// Visiting Messages clears its badge but not Timeline's.
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	m = updated.(uiModel)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	m = updated.(uiModel)
+	tabs = stripAnsi(m.renderTabBar())
+	if strings.Contains(tabs, "Messages(") {
+		t.Errorf("Messages badge should clear after visiting: %q", tabs)
+	}
+	if !strings.Contains(tabs, "Timeline(3)") {
+		t.Errorf("Timeline badge should persist: %q", tabs)
+	}
+}
+
+// --- Concurrent group navigation ---
+
+func TestNextConcurrentGroupKey(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	// Two concurrent groups (L:5 and L:2) separated by a lone event (L:3),
+	// rendered newest first: L:6, L:5 group, L:3, L:2 group, L:1.
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventProgress},
+		{ID: 2, AgentID: "alice", LamportTS: 2, Kind: model.EventProgress},
+		{ID: 3, AgentID: "bob", LamportTS: 2, Kind: model.EventProgress},
+		{ID: 4, AgentID: "alice", LamportTS: 3, Kind: model.EventProgress},
+		{ID: 5, AgentID: "alice", LamportTS: 5, Kind: model.EventProgress},
+		{ID: 6, AgentID: "bob", LamportTS: 5, Kind: model.EventProgress},
+		{ID: 7, AgentID: "bob", LamportTS: 6, Kind: model.EventProgress},
+	}
+
+	lines := strings.Split(stripAnsi(m.renderTimeline()), "\n")
+	lineOf := func(prefix string) int {
+		for i, l := range lines {
+			if strings.HasPrefix(strings.TrimSpace(l), prefix) {
+				return i
+			}
+		}
+		t.Fatalf("no line starting with %q", prefix)
+		return -1
+	}
+	// Each group starts at its event-count header.
+	first, second := lineOf("L:5 — 2 events"), lineOf("L:2 — 2 events")
+
+	press := func(r rune) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(uiModel)
+	}
+	press('n')
+	if m.scrollPos != first {
+		t.Fatalf("n: scrollPos = %d, want first group at %d", m.scrollPos, first)
+	}
+	press('n')
+	if m.scrollPos != second {
+		t.Fatalf("n: scrollPos = %d, want second group at %d", m.scrollPos, second)
+	}
+	press('n') // no further group: stay put
+	if m.scrollPos != second {
+		t.Errorf("n past the last group moved to %d", m.scrollPos)
+	}
+	press('N')
+	if m.scrollPos != first {
+		t.Errorf("N: scrollPos = %d, want %d", m.scrollPos, first)
+	}
+}
+
+func TestNextConcurrentGroupKeyOldestFirst(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	m.timelineAscending = true
+	// Rendered oldest first: L:1, L:2 group, L:3, L:5 group, L:6.
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventProgress},
+		{ID: 2, AgentID: "alice", LamportTS: 2, Kind: model.EventProgress},
+		{ID: 3, AgentID: "bob", LamportTS: 2, Kind: model.EventProgress},
+		{ID: 4, AgentID: "alice", LamportTS: 3, Kind: model.EventProgress},
+		{ID: 5, AgentID: "alice", LamportTS: 5, Kind: model.EventProgress},
+		{ID: 6, AgentID: "bob", LamportTS: 5, Kind: model.EventProgress},
+		{ID: 7, AgentID: "bob", LamportTS: 6, Kind: model.EventProgress},
+	}
+
+	lines := strings.Split(stripAnsi(m.renderTimeline()), "\n")
+	lineOf := func(prefix string) int {
+		for i, l := range lines {
+			if strings.HasPrefix(strings.TrimSpace(l), prefix) {
+				return i
+			}
+		}
+		t.Fatalf("no line starting with %q", prefix)
+		return -1
+	}
+	first, second := lineOf("L:2 — 2 events"), lineOf("L:5 — 2 events")
+
+	press := func(r rune) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(uiModel)
+	}
+	press('n')
+	if m.scrollPos != first {
+		t.Fatalf("n: scrollPos = %d, want first group at %d", m.scrollPos, first)
+	}
+	press('n')
+	if m.scrollPos != second {
+		t.Fatalf("n: scrollPos = %d, want second group at %d", m.scrollPos, second)
+	}
+	press('N')
+	if m.scrollPos != first {
+		t.Errorf("N: scrollPos = %d, want %d", m.scrollPos, first)
+	}
+}
+
+// --- Epoch-free sessions ---
+
+func TestUsesEpochs(t *testing.T) {
+	if usesEpochs([]model.Agent{{ID: "a"}, {ID: "b"}}) {
+		t.Error("all-zero epochs/rounds should report false")
+	}
+	if !usesEpochs([]model.Agent{{ID: "a"}, {ID: "b", Round: 1}}) {
+		t.Error("a nonzero round should report true")
+	}
+	if usesEpochs(nil) {
+		t.Error("no agents should report false")
+	}
+}
+
+func TestDashboardHidesProgressWithoutEpochs(t *testing.T) {
+	m := testModel()
+	if out := stripAnsi(m.renderDashboard()); !strings.Contains(out, "Progress") || !strings.Contains(out, "e1/r0") {
+		t.Errorf("with epochs in use the Progress column should appear:\n%s", out)
+	}
+
+	for i := range m.snap.Agents {
+		m.snap.Agents[i].Epoch, m.snap.Agents[i].Round = 0, 0
+	}
+	out := stripAnsi(m.renderDashboard())
+	if strings.Contains(out, "Progress") || strings.Contains(out, "e0/r0") {
+		t.Errorf("Progress column should be hidden when no agent uses epochs:\n%s", out)
+	}
+	if strings.Contains(out, "BLOCKED") || strings.Contains(out, "SAFE") {
+		t.Errorf("frontier badges should be hidden when no agent uses epochs:\n%s", out)
+	}
+	if !strings.Contains(out, "alice") {
+		t.Errorf("agents should still be listed:\n%s", out)
+	}
+}
+
+// --- Message direction balance ---
+
+func TestDirectionBalance(t *testing.T) {
+	msg := func(from, to string) model.Event {
+		return model.Event{AgentID: from, Kind: model.EventMsg, Target: to}
+	}
+	events := []model.Event{
+		msg("sender", "a"), msg("sender", "b"),
+		msg("a", "sink"), msg("b", "sink"),
+		msg("x", "y"), msg("y", "x"), msg("x", "y"), msg("y", "x"), msg("x", "y"),
+	}
+	tests := []struct {
+		id   string
+		want rune
+	}{
+		{"sender", '↑'}, // send-only
+		{"sink", '↓'},   // receive-only
+		{"x", '↔'},      // 3 sent, 2 recv
+		{"y", '↔'},      // 2 sent, 3 recv
+		{"quiet", ' '},  // no messages
+	}
+	for _, tt := range tests {
+		if got := directionBalance(events, tt.id); got != tt.want {
+			t.Errorf("directionBalance(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestDashboardShowsDirection(t *testing.T) {
+	m := testModel()
+	out := stripAnsi(m.renderDashboard())
+	if !strings.Contains(out, "Msg") {
+		t.Errorf("dashboard should have a Msg column:\n%s", out)
+	}
+	// alice and bob exchanged one message each way.
+	if strings.Count(out, "↔") != 2 {
+		t.Errorf("expected balanced indicators for alice and bob:\n%s", out)
+	}
+}
+
+// --- Lamport gaps ---
+
+func TestLamportGapsContiguous(t *testing.T) {
+	var events []model.Event
+	for ts := int64(1); ts <= 50; ts++ {
+		events = append(events, model.Event{AgentID: "alice", LamportTS: ts})
+	}
+	// Duplicate timestamps (concurrent events) are not gaps.
+	events = append(events, model.Event{AgentID: "bob", LamportTS: 7})
+	if gaps := lamportGaps(events, 10); len(gaps) != 0 {
+		t.Errorf("expected no gaps, got %v", gaps)
+	}
+	if gaps := lamportGaps(nil, 10); len(gaps) != 0 {
+		t.Errorf("expected no gaps for no events, got %v", gaps)
+	}
+}
+
+func TestLamportGapsLargeJump(t *testing.T) {
+	events := []model.Event{
+		{AgentID: "bob", LamportTS: 500},
+		{AgentID: "alice", LamportTS: 49},
+		{AgentID: "alice", LamportTS: 50},
+		{AgentID: "bob", LamportTS: 501},
+	}
+	gaps := lamportGaps(events, 100)
+	if len(gaps) != 1 || gaps[0] != [2]int64{50, 500} {
+		t.Fatalf("gaps = %v, want [[50 500]]", gaps)
+	}
+
+	m := testModel()
+	m.snap.Events = events
+	if out := stripAnsi(m.View()); !strings.Contains(out, "Lamport gap: L:50 → L:500") {
+		t.Errorf("banner should name the gap:\n%s", out)
+	}
+	if out := stripAnsi(testModel().View()); strings.Contains(out, "Lamport gap") {
+		t.Error("no banner expected without a gap")
+	}
+}
+
+// --- Focus pair ---
+
+func TestEventInvolvesPair(t *testing.T) {
+	tests := []struct {
+		name string
+		e    model.Event
+		want bool
+	}{
+		{"a to b", model.Event{AgentID: "alice", Kind: model.EventMsg, Target: "bob"}, true},
+		{"b to a", model.Event{AgentID: "bob", Kind: model.EventMsg, Target: "alice"}, true},
+		{"a to third party", model.Event{AgentID: "alice", Kind: model.EventMsg, Target: "carol"}, false},
+		{"third party to b", model.Event{AgentID: "carol", Kind: model.EventMsg, Target: "bob"}, false},
+		{"a's lock", model.Event{AgentID: "alice", Kind: model.EventLockReq, Target: "main.go"}, true},
+		{"third party lock", model.Event{AgentID: "carol", Kind: model.EventLockRel, Target: "main.go"}, false},
+		{"a's heartbeat", model.Event{AgentID: "alice", Kind: model.EventProgress}, false},
+	}
+	for _, tt := range tests {
+		if got := eventInvolvesPair(tt.e, "alice", "bob"); got != tt.want {
+			t.Errorf("%s: eventInvolvesPair = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChoosePair(t *testing.T) {
+	p := choosePair([2]string{}, "alice")
+	if p != [2]string{"alice", ""} {
+		t.Fatalf("first pick = %v", p)
+	}
+	if got := choosePair(p, "alice"); got != [2]string{} {
+		t.Errorf("same agent twice should clear, got %v", got)
+	}
+	p = choosePair(p, "bob")
+	if p != [2]string{"alice", "bob"} {
+		t.Fatalf("second pick = %v", p)
+	}
+	if got := choosePair(p, "carol"); got != [2]string{"carol", ""} {
+		t.Errorf("pick after a full pair should start over, got %v", got)
+	}
+}
+
+func TestFocusPairFiltersMessages(t *testing.T) {
+	m := testModel()
+	m.snap.Events = append(m.snap.Events, model.Event{
+		ID: 5, AgentID: "alice", LamportTS: 5, Kind: model.EventMsg, Target: "carol", Body: "side chat", CreatedAt: time.Now(),
+	})
+	for _, sel := range []int{0, 1} { // alice, then bob
+		m.selectedAgent = sel
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+		m = updated.(uiModel)
+	}
+	if bar := stripAnsi(m.renderStatusBar()); !strings.Contains(bar, "pair: alice↔bob") {
+		t.Errorf("status bar should show the pair: %q", bar)
+	}
+	m.activeView = viewMessages
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, "hello") || !strings.Contains(out, "hi back") {
+		t.Errorf("pair messages should be shown:\n%s", out)
+	}
+	if strings.Contains(out, "side chat") {
+		t.Errorf("message to a third party should be hidden:\n%s", out)
+	}
+}
+
+// --- Adaptive event window ---
+
+func TestSparseFilterWidensEventWindow(t *testing.T) {
+	s := newTestStore(t)
+	insertMsg(t, s, "carol", "alice", "from long ago", 1)
+	for i := int64(2); i <= 700; i++ {
+		insertMsg(t, s, "alice", "bob", "chatter", i)
+	}
+	snap, err := snapshot.Build(s, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	m := testModel()
+	m.store = s
+	m.snap = snap
+	m.activeView = viewMessages
+	m.filterAgent = "carol"
+	if strings.Contains(stripAnsi(m.renderMessages()), "from long ago") {
+		t.Fatal("precondition: the old message should be outside the snapshot window")
+	}
+
+	m, cmd := m.widenIfSparse()
+	if cmd == nil {
+		t.Fatal("an empty filtered result should trigger a widened load")
+	}
+	updated, _ := m.Update(cmd())
+	m = updated.(uiModel)
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, "from long ago") {
+		t.Errorf("widened load should find the old message:\n%s", out)
+	}
+	if !strings.Contains(out, "(searched 700 events back)") {
+		t.Errorf("header should report the search depth:\n%s", out)
+	}
+
+	// Older events survive the next refresh.
+	updated, _ = m.Update(snapshotReadyMsg{snap: snap})
+	m = updated.(uiModel)
+	if !strings.Contains(stripAnsi(m.renderMessages()), "from long ago") {
+		t.Error("older events should be kept across refreshes")
+	}
+
+	// Unfiltered views never widen.
+	m.filterAgent = ""
+	if _, cmd := m.widenIfSparse(); cmd != nil {
+		t.Error("no widening without a filter")
+	}
+}
+
+func TestWidenedEventsDroppedWhenWindowAdvances(t *testing.T) {
+	s := newTestStore(t)
+	insertMsg(t, s, "carol", "alice", "from long ago", 1)
+	for i := int64(2); i <= 700; i++ {
+		insertMsg(t, s, "alice", "bob", "chatter", i)
+	}
+	snap, err := snapshot.Build(s, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	m := testModel()
+	m.store = s
+	m.snap = snap
+	m.activeView = viewMessages
+	m.filterAgent = "carol"
+	m, cmd := m.widenIfSparse()
+	updated, _ := m.Update(cmd())
+	m = updated.(uiModel)
+	if len(m.older) == 0 {
+		t.Fatal("precondition: the widened load should keep older events")
+	}
+
+	// New events push the window forward past where the widening ended.
+	for i := int64(701); i <= 720; i++ {
+		insertMsg(t, s, "alice", "bob", "more chatter", i)
+	}
+	next, err := snapshot.Build(s, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	updated, _ = m.Update(snapshotReadyMsg{snap: next})
+	m = updated.(uiModel)
+	if m.older != nil || m.searchedBack != 0 {
+		t.Errorf("older events should be dropped: %d kept, searched %d", len(m.older), m.searchedBack)
+	}
+	if m.snap.MinLoadedID != next.MinLoadedID || len(m.snap.Events) != len(next.Events) {
+		t.Errorf("snapshot = IDs %d.., %d events; want the rebuilt window %d.., %d events",
+			m.snap.MinLoadedID, len(m.snap.Events), next.MinLoadedID, len(next.Events))
+	}
+	if strings.Contains(stripAnsi(m.renderMessages()), "from long ago") {
+		t.Error("events not joined to the window should not be shown")
+	}
+}
+
+// --- Registered / uptime ---
+
+func TestAgentDetailShowsUptime(t *testing.T) {
+	now := time.Now()
+	m := testModel()
+	m.snap.Agents[0].Registered = now.Add(-2 * time.Hour)
+	m.snap.Agents[0].LastSeen = now
+	// Stale agents stop accruing uptime at their last sighting.
+	m.snap.Agents[1].Registered = now.Add(-2 * time.Hour)
+	m.snap.Agents[1].LastSeen = now.Add(-30 * time.Minute)
+
+	if out := stripAnsi(m.renderAgentDetailFor("alice")); !strings.Contains(out, "Registered: ") ||
+		!strings.Contains(out, "Uptime: 2h0m") {
+		t.Errorf("active agent uptime should span registration to now:\n%s", out)
+	}
+	if out := stripAnsi(m.renderAgentDetailFor("bob")); !strings.Contains(out, "Uptime: 1h30m") {
+		t.Errorf("stale agent uptime should span registration to last seen:\n%s", out)
+	}
+}
+
+// --- Width simulation ---
+
+func TestWidthOverrideSwitchesLayout(t *testing.T) {
+	m := testModel() // 80 columns: single pane
+	press := func() {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'W'}})
+		m = updated.(uiModel)
+	}
+	isSplit := func() bool { return strings.Contains(m.View(), " │ ") }
+
+	if isSplit() {
+		t.Fatal("80 columns should render a single pane")
+	}
+	for _, want := range []int{60, 80, 120} {
+		press()
+		if m.widthOverride != want {
+			t.Fatalf("widthOverride = %d, want %d", m.widthOverride, want)
+		}
+	}
+	if !isSplit() {
+		t.Error("a simulated 120-column width should take the split-pane branch")
+	}
+	if bar := stripAnsi(m.renderStatusBar()); !strings.Contains(bar, "sim width 120") {
+		t.Errorf("status bar should show the simulated width: %q", bar)
+	}
+	press()
+	press()
+	if m.widthOverride != 0 || isSplit() {
+		t.Errorf("cycling past 160 should restore the real width, got %d", m.widthOverride)
+	}
+}
+
+// --- Finalizable fraction ---
+
+func TestFinalizable(t *testing.T) {
+	statuses := map[string]frontier.FrontierStatus{
+		"alice": {SafeToFinalize: true},
+		"bob":   {SafeToFinalize: false},
+		"carol": {SafeToFinalize: true},
+		"dave":  {SafeToFinalize: false},
+	}
+	if safe, total := finalizable(statuses); safe != 2 || total != 4 {
+		t.Errorf("finalizable = %d/%d, want 2/4", safe, total)
+	}
+	if safe, total := finalizable(nil); safe != 0 || total != 0 {
+		t.Errorf("finalizable(nil) = %d/%d, want 0/0", safe, total)
+	}
+
+	m := testModel()
+	m.snap.FrontierStatus = statuses
+	if out := stripAnsi(m.renderTitleBar()); !strings.Contains(out, "finalizable: 2/4") {
+		t.Errorf("title bar should show the fraction: %q", out)
+	}
+}
+
+func TestSampleRingAndSparkline(t *testing.T) {
+	var r sampleRing
+	for i := 0; i < sampleRingSize+2; i++ {
+		r = r.push(float64(i))
+	}
+	vals := r.values()
+	if len(vals) != sampleRingSize || vals[0] != 2 || vals[len(vals)-1] != sampleRingSize+1 {
+		t.Errorf("ring should keep the newest %d samples oldest first, got %v", sampleRingSize, vals)
+	}
+	if got := sparkline([]float64{0, 0.5, 1}); got != "▁▄█" {
+		t.Errorf("sparkline = %q, want ▁▄█", got)
+	}
+}
+
+// --- Distinct pairs ---
+
+func TestDistinctPairs(t *testing.T) {
+	msg := func(from, to string) model.Event {
+		return model.Event{AgentID: from, Kind: model.EventMsg, Target: to}
+	}
+	events := []model.Event{
+		msg("alice", "bob"),
+		msg("bob", "alice"), // bidirectional with the above
+		msg("alice", "bob"), // repeat
+		msg("alice", "carol"),
+		msg("carol", "carol"), // self-message
+		{AgentID: "dave", Kind: model.EventLockReq, Target: "main.go"},
+	}
+	if got := distinctPairs(events, true); got != 3 {
+		t.Errorf("directed pairs = %d, want 3", got)
+	}
+	if got := distinctPairs(events, false); got != 2 {
+		t.Errorf("undirected pairs = %d, want 2", got)
+	}
+	if got := distinctPairs(nil, false); got != 0 {
+		t.Errorf("no events: got %d pairs", got)
+	}
+
+	// testSnapshot: alice->bob and bob->alice.
+	if out := stripAnsi(testModel().renderDashboard()); !strings.Contains(out, "connections: 1 pair (2 directed)") {
+		t.Errorf("dashboard should show connections:\n%s", out)
+	}
+}
+
+// --- Dashboard sort ---
+
+func TestSortKeepsSelectedAgent(t *testing.T) {
+	m := testModel() // alice (clock 10), bob (clock 5)
+	m.snap.Agents = append(m.snap.Agents, model.Agent{ID: "carol", Clock: 20, LastSeen: time.Now()})
+	m = m.selectIndex(1) // bob
+	press := func() {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+		m = updated.(uiModel)
+	}
+
+	press() // by clock: carol, alice, bob
+	if m.dashboardSort != sortClock {
+		t.Fatalf("dashboardSort = %v, want clock", m.dashboardSort)
+	}
+	if ag, _ := m.selectedRow(); ag.ID != "bob" || m.selectedAgent != 2 {
+		t.Errorf("after sorting by clock, selected = %q at %d; want bob at 2", ag.ID, m.selectedAgent)
+	}
+	out := stripAnsi(m.renderDashboard())
+	if !strings.Contains(out, "sorted by clock") || strings.Index(out, "carol") > strings.Index(out, "alice") {
+		t.Errorf("dashboard should list agents by clock:\n%s", out)
+	}
+
+	press() // by last seen
+	press() // by ID: alice, bob, carol
+	if ag, _ := m.selectedRow(); ag.ID != "bob" || m.selectedAgent != 1 {
+		t.Errorf("after sorting by ID, selected = %q at %d; want bob at 1", ag.ID, m.selectedAgent)
+	}
+
+	// The selected agent vanishing falls back to the first row.
+	snap := testSnapshot()
+	snap.Agents = snap.Agents[:1]
+	updated, _ := m.Update(snapshotReadyMsg{snap: snap})
+	m = updated.(uiModel)
+	if ag, _ := m.selectedRow(); ag.ID != "alice" || m.selectedAgent != 0 {
+		t.Errorf("vanished selection should fall back to row 0, got %q at %d", ag.ID, m.selectedAgent)
+	}
+}
+
+// --- Event delta readout ---
+
+func TestSnapshotReadyShowsEventDelta(t *testing.T) {
+	m := testModel() // TotalEvents: 4
+	next := testSnapshot()
+	next.TotalEvents = 9
+	updated, _ := m.Update(snapshotReadyMsg{snap: next})
+	m = updated.(uiModel)
+	if m.eventDelta != 5 {
+		t.Fatalf("eventDelta = %d, want 5", m.eventDelta)
+	}
+	if bar := stripAnsi(m.renderStatusBar()); !strings.Contains(bar, "+5 events") {
+		t.Errorf("status bar should show the delta: %q", bar)
+	}
+
+	// The readout expires.
+	m.eventDeltaAt = time.Now().Add(-eventDeltaTTL)
+	if bar := stripAnsi(m.renderStatusBar()); strings.Contains(bar, "events |") {
+		t.Errorf("expired delta should be hidden: %q", bar)
+	}
+
+	// No previous snapshot: nothing to compare against.
+	first := testModel()
+	first.snap = nil
+	updated, _ = first.Update(snapshotReadyMsg{snap: next})
+	if d := updated.(uiModel).eventDelta; d != 0 {
+		t.Errorf("first snapshot should not set a delta, got %d", d)
+	}
+}
+
+// --- Send/receive pairing ---
+
+func TestSendReceivesAnnotation(t *testing.T) {
+	events := []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: "ping"},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventMsg, Target: "alice", Body: "pong"},
+		{ID: 3, AgentID: "alice", LamportTS: 3, Kind: model.EventMsg, Target: "carol", Body: "task"},
+		{ID: 4, AgentID: "carol", LamportTS: 5, Kind: model.EventLockReq, Target: "main.go"},
+		{ID: 5, AgentID: "bob", LamportTS: 6, Kind: model.EventMsg, Target: "alice", Body: "more"},
+	}
+	recv := sendReceives(events)
+	if next, ok := recv[1]; !ok || next.ID != 2 {
+		t.Errorf("alice->bob should pair with bob's reply, got %+v, %v", next, ok)
+	}
+	if next, ok := recv[3]; !ok || next.ID != 4 {
+		t.Errorf("alice->carol should pair with carol's next event, got %+v, %v", next, ok)
+	}
+	if _, ok := recv[5]; ok {
+		t.Error("alice has done nothing since bob's last message; it should be unpaired")
+	}
+
+	m := testModel()
+	m.snap.Events = events
+	m.snap.Agents = append(m.snap.Agents, model.Agent{ID: "carol", LastSeen: time.Now()})
+	out := stripAnsi(m.renderTimeline())
+	for _, want := range []string{
+		"alice -> bob ↩ answered at L:2",
+		"alice -> carol (carol next active at L:5)",
+		"bob -> alice (reply expected from alice)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("timeline missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// --- Diagram column order ---
+
+func TestDiagramActivityOrder(t *testing.T) {
+	agents := []model.Agent{{ID: "alice"}, {ID: "bob"}, {ID: "carol"}}
+	events := []model.Event{
+		{ID: 1, AgentID: "carol", LamportTS: 1, Kind: model.EventProgress},
+		{ID: 2, AgentID: "carol", LamportTS: 2, Kind: model.EventMsg, Target: "alice"},
+		{ID: 3, AgentID: "carol", LamportTS: 3, Kind: model.EventProgress},
+		{ID: 4, AgentID: "alice", LamportTS: 4, Kind: model.EventMsg, Target: "carol"},
+	}
+
+	order, _ := buildDiagramData(agents, events, nil, diagramByRegistration)
+	if strings.Join(order, ",") != "alice,bob,carol" {
+		t.Errorf("registration order = %v", order)
+	}
+	order, rows := buildDiagramData(agents, events, nil, diagramByActivity)
+	if strings.Join(order, ",") != "carol,alice,bob" {
+		t.Fatalf("activity order = %v, want carol (3 events), alice (1), bob (0)", order)
+	}
+	msg := rows[1].messages[0]
+	if agentIndex(order, msg.fromAgent) != 0 || agentIndex(order, msg.toAgent) != 1 {
+		t.Errorf("carol->alice should run from column 0 to 1, got %d -> %d",
+			agentIndex(order, msg.fromAgent), agentIndex(order, msg.toAgent))
+	}
+
+	// Rendered: carol's send arrow starts in the first column and points
+	// right at alice; alice's reply points back left.
+	m := testModel()
+	m.snap.Agents = agents
+	m.snap.Events = events
+	m.width = 100
+	m.activeView = viewDiagram
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+	m = updated.(uiModel)
+	out := stripAnsi(m.renderDiagram())
+	if !strings.Contains(out, "L  carol         alice         bob") {
+		t.Errorf("header should list carol first:\n%s", out)
+	}
+	if !strings.Contains(out, "   ╰──────────── ▶") || !strings.Contains(out, "   ◀─────────────╯") {
+		t.Errorf("arrows should connect columns 0 and 1:\n%s", out)
+	}
+}
+
+// --- Unavailable frontier ---
+
+func TestFrontierUnavailable(t *testing.T) {
+	m := testModel()
+	m.snap.FrontierAvailable = false
+	m.snap.Frontier = nil
+	m.snap.FrontierStatus = map[string]frontier.FrontierStatus{}
+
+	if out := stripAnsi(m.renderFrontier()); !strings.Contains(out, "Frontier unavailable") {
+		t.Errorf("Frontier view should say the frontier is unavailable:\n%s", out)
+	}
+	if out := stripAnsi(m.renderDashboard()); !strings.Contains(out, "(frontier unavailable)") {
+		t.Errorf("Dashboard should say the frontier is unavailable:\n%s", out)
+	}
+	if issues := validateSnapshot(m.snap); len(issues) != 0 {
+		t.Errorf("an unavailable frontier is not an integrity problem: %v", issues)
+	}
+}
+
+// --- Frontier blocked-only toggle ---
+
+func TestFrontierBlockedOnly(t *testing.T) {
+	m := testModel()
+	m.activeView = viewFrontier
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	m = updated.(uiModel)
+	if !m.blockedOnly {
+		t.Fatal("b should enable blocked-only in the Frontier view")
+	}
+
+	out := stripAnsi(m.renderFrontier())
+	if !strings.Contains(out, "Per-Agent Status (1 blocked)") {
+		t.Errorf("expected blocked count in header, got:\n%s", out)
+	}
+	if !strings.Contains(out, "alice: BLOCKED") {
+		t.Errorf("blocked agent alice should be listed, got:\n%s", out)
+	}
+	if strings.Contains(out, "bob: SAFE") {
+		t.Errorf("safe agent bob should be hidden, got:\n%s", out)
+	}
+}
+
+func TestFrontierBlockedOnlyAllSafe(t *testing.T) {
+	m := testModel()
+	m.snap.FrontierStatus = map[string]frontier.FrontierStatus{
+		"alice": {SafeToFinalize: true},
+		"bob":   {SafeToFinalize: true},
+	}
+	m.blockedOnly = true
+	out := stripAnsi(m.renderFrontier())
+	if !strings.Contains(out, "all agents safe to finalize") {
+		t.Errorf("expected all-safe note, got:\n%s", out)
+	}
+}
+
+// --- View menu ---
+
+func TestViewMenuSwitchesByNumber(t *testing.T) {
+	m := testModel()
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("0")})
+	m = updated.(uiModel)
+	if !m.viewMenu {
+		t.Fatal("0 should open the view menu")
+	}
+	out := stripAnsi(m.View())
+	for _, want := range []string{"1  Dashboard", "3  Locks", "(l)", "6  Diagram"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("menu should list %q, got:\n%s", want, out)
+		}
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("3")})
+	m = updated.(uiModel)
+	if m.viewMenu {
+		t.Error("picking a view should close the menu")
+	}
+	if m.activeView != viewLocks {
+		t.Errorf("activeView = %v, want Locks", m.activeView)
+	}
+}
+
+func TestViewMenuClosesOnOtherKey(t *testing.T) {
+	m := testModel()
+	m.viewMenu = true
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(uiModel)
+	if m.viewMenu || m.activeView != viewDashboard {
+		t.Errorf("esc should close the menu without switching, got menu=%v view=%v", m.viewMenu, m.activeView)
+	}
+}
+
+// --- Timeline kind filter ---
+
+func TestTimelineKindFilterGroupsVisibleEventsOnly(t *testing.T) {
+	now := time.Now()
+	m := testModel()
+	m.activeView = viewTimeline
+	// alice's lock and bob's heartbeat share L:5; with only lock events
+	// shown, the lock stands alone and must not be bracketed.
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 5, Kind: model.EventLockReq, Target: "file.go", CreatedAt: now},
+		{ID: 2, AgentID: "bob", LamportTS: 5, Kind: model.EventProgress, Epoch: 1, CreatedAt: now},
+		{ID: 3, AgentID: "alice", LamportTS: 6, Kind: model.EventMsg, Target: "bob", Body: "done", CreatedAt: now},
+	}
+	if out := m.renderTimeline(); !strings.Contains(out, "╓") {
+		t.Fatal("unfiltered timeline should bracket the L:5 group")
+	}
+
+	m.timelineKind = model.EventLockReq
+	out := stripAnsi(m.renderTimeline())
+	if strings.Contains(out, "╓") || strings.Contains(out, "╙") {
+		t.Errorf("bracket spans a hidden heartbeat:\n%s", out)
+	}
+	if !strings.Contains(out, "[kind: lock_req]") || strings.Contains(out, "done") {
+		t.Errorf("expected only lock events under a kind header:\n%s", out)
+	}
+	if lines := m.concurrentGroupLines(); len(lines) != 0 {
+		t.Errorf("n/N should find no concurrent groups, got %v", lines)
+	}
+}
+
+func TestTimelineKindKeyCycles(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	for _, want := range []model.EventKind{model.EventMsg, model.EventLockReq, model.EventLockRel, model.EventProgress, ""} {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("K")})
+		m = updated.(uiModel)
+		if m.timelineKind != want {
+			t.Fatalf("timelineKind = %q, want %q", m.timelineKind, want)
+		}
+	}
+}
+
+// --- View table ---
+
+func TestViewTableRoundTrips(t *testing.T) {
+	var out strings.Builder
+	listViews(&out)
+	listing := out.String()
+
+	if len(views) != int(viewCount) {
+		t.Fatalf("views has %d entries, want one per tab (%d)", len(views), viewCount)
+	}
+	for i, vi := range views {
+		if vi.id != viewID(i) {
+			t.Errorf("views[%d] is %v, want tab order", i, vi.id)
+		}
+		for _, name := range append([]string{vi.name, vi.key}, vi.aliases...) {
+			if got, err := parseViewFlag(name); err != nil || got != vi.id {
+				t.Errorf("parseViewFlag(%q) = %v, %v; want %v", name, got, err, vi.id)
+			}
+		}
+		if viewKeys[vi.key] != vi.id {
+			t.Errorf("viewKeys[%q] = %v, want %v", vi.key, viewKeys[vi.key], vi.id)
+		}
+		if !strings.Contains(listing, fmt.Sprintf("%-10s %s", vi.name, vi.key)) {
+			t.Errorf("--list-views output missing %s:\n%s", vi.name, listing)
+		}
+	}
+}
+
+// --- Event window coverage ---
+
+func TestCoverageNote(t *testing.T) {
+	m := testModel()
+	if got := m.coverageNote(); got != "" {
+		t.Errorf("snapshot holding every event should have no note, got %q", got)
+	}
+
+	m.snap.MinLoadedID, m.snap.MaxLoadedID = 4501, 5000
+	m.snap.TotalEvents = 5000
+	m.activeView = viewTimeline
+	if out := stripAnsi(m.renderTimeline()); !strings.Contains(out, "(showing events #4501–#5000 of 5000)") {
+		t.Errorf("Timeline header should note coverage, got:\n%s", out)
+	}
+	if out := stripAnsi(m.renderMessages()); !strings.Contains(out, "#4501–#5000 of 5000") {
+		t.Errorf("Messages header should note coverage, got:\n%s", out)
+	}
+}
+
+// --- Merged lock entries ---
+
+func TestTimelineMergeLocks(t *testing.T) {
+	now := time.Now()
+	m := testModel()
+	m.activeView = viewTimeline
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 3, Kind: model.EventLockReq, Target: "file.go", CreatedAt: now},
+		{ID: 2, AgentID: "bob", LamportTS: 4, Kind: model.EventLockReq, Target: "other.go", CreatedAt: now},
+		{ID: 3, AgentID: "alice", LamportTS: 9, Kind: model.EventLockRel, Target: "file.go", CreatedAt: now},
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("L")})
+	m = updated.(uiModel)
+	if !m.mergeLocks {
+		t.Fatal("L should enable lock merging in the Timeline")
+	}
+
+	events, runs := m.timelineEvents()
+	if len(events) != 2 {
+		t.Fatalf("expected the pair folded into one entry plus bob's open lock, got %d events", len(events))
+	}
+	if run := runs[3]; len(run) != 2 || run[0].ID != 1 {
+		t.Errorf("unlock should carry its lock, got %v", run)
+	}
+
+	out := stripAnsi(m.renderTimeline())
+	if !strings.Contains(out, "alice held file.go (L:3–9, 6 ticks)") {
+		t.Errorf("expected merged entry, got:\n%s", out)
+	}
+	if strings.Contains(out, "lock file.go") {
+		t.Errorf("merged lock should not also appear on its own, got:\n%s", out)
+	}
+	if !strings.Contains(out, "bob lock other.go") {
+		t.Errorf("unreleased lock should stay an open entry, got:\n%s", out)
+	}
+}
+
+// --- Unordered pairs ---
+
+func TestUnorderedPairCount(t *testing.T) {
+	// alice: a1 (L1), a2 sends to bob (L2). bob: b1 (L1), b2 (L3) receives.
+	// a1→b2 and a2→b2 via the message; a1‖b1 and a2‖b1 are unproven.
+	events := []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventProgress},
+		{ID: 2, AgentID: "bob", LamportTS: 1, Kind: model.EventProgress},
+		{ID: 3, AgentID: "alice", LamportTS: 2, Kind: model.EventMsg, Target: "bob"},
+		{ID: 4, AgentID: "bob", LamportTS: 3, Kind: model.EventProgress},
+	}
+	if got := unorderedPairCount(events); got != 2 {
+		t.Errorf("unorderedPairCount = %d, want 2", got)
+	}
+
+	// A chain through a third agent orders alice before carol too.
+	chain := []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob"},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventMsg, Target: "carol"},
+		{ID: 3, AgentID: "carol", LamportTS: 3, Kind: model.EventProgress},
+	}
+	if got := unorderedPairCount(chain); got != 0 {
+		t.Errorf("message chain should order every pair, got %d unordered", got)
+	}
+
+	// Without any message nothing across agents is ordered.
+	chain[0].Kind, chain[1].Kind = model.EventProgress, model.EventProgress
+	if got := unorderedPairCount(chain); got != 3 {
+		t.Errorf("no messages: unorderedPairCount = %d, want 3", got)
+	}
+}
+
+func TestUnorderedPairCountCached(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	updated, _ := m.Update(snapshotReadyMsg{snap: testSnapshot(), seq: 1})
+	m = updated.(uiModel)
+	events, _ := m.timelineEvents()
+	if !m.pairCount.valid || m.pairCount.n != unorderedPairCount(events) {
+		t.Fatalf("cache after a snapshot = %+v, want %d", m.pairCount, unorderedPairCount(events))
+	}
+
+	// A tick changes nothing the count depends on: the cache is kept.
+	key := m.pairCount.key
+	updated, _ = m.Update(tickMsg{})
+	m = updated.(uiModel)
+	if m.pairCount.key != key {
+		t.Error("tick should not recount")
+	}
+
+	// A filter change recounts over the filtered events.
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("K")})
+	m = updated.(uiModel)
+	events, _ = m.timelineEvents()
+	if m.pairCount.key != m.timelineKey() || m.pairCount.n != unorderedPairCount(events) {
+		t.Errorf("cache after K = %+v, want a count over kind %q", m.pairCount, m.timelineKind)
+	}
+}
+
+// --- Top talkers ---
+
+func TestTopN(t *testing.T) {
+	counts := map[string]int{"carol": 2, "alice": 5, "bob": 2, "dave": 2, "erin": 1}
+	got := topN(counts, 3)
+	want := []rankedCount{{"alice", 5}, {"bob", 2}, {"carol", 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("topN = %v, want %v (ties by ID)", got, want)
+	}
+	if got := topN(map[string]int{"bob": 1}, 3); len(got) != 1 {
+		t.Errorf("fewer agents than n should return them all, got %v", got)
+	}
+	if got := topN(nil, 3); len(got) != 0 {
+		t.Errorf("no counts should give an empty ranking, got %v", got)
+	}
+}
+
+func TestDashboardTopTalkers(t *testing.T) {
+	m := testModel()
+	out := stripAnsi(m.renderDashboard())
+	sent, recv := messageCounts(m.snap.Events)
+	for _, want := range []string{
+		"top senders:   " + formatRanked(topN(sent, topTalkers)),
+		"top receivers: " + formatRanked(topN(recv, topTalkers)),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dashboard missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// --- Agent set trend ---
+
+func TestAgentSetDelta(t *testing.T) {
+	agents := func(ids ...string) []model.Agent {
+		out := make([]model.Agent, len(ids))
+		for i, id := range ids {
+			out[i] = model.Agent{ID: id}
+		}
+		return out
+	}
+	tests := []struct {
+		prev, cur    []model.Agent
+		joined, left int
+	}{
+		{agents("alice", "bob"), agents("alice", "bob"), 0, 0},
+		{agents("alice", "bob"), agents("bob", "alice", "carol"), 1, 0},
+		{agents("alice", "bob", "carol"), agents("bob"), 0, 2},
+		{agents("alice", "bob"), agents("carol", "dave", "bob"), 2, 1},
+		{nil, agents("alice"), 1, 0},
+	}
+	for _, tt := range tests {
+		joined, left := agentSetDelta(tt.prev, tt.cur)
+		if joined != tt.joined || left != tt.left {
+			t.Errorf("agentSetDelta(%v, %v) = +%d -%d, want +%d -%d",
+				tt.prev, tt.cur, joined, left, tt.joined, tt.left)
+		}
+	}
+}
+
+func TestTitleBarAgentTrendPersists(t *testing.T) {
+	m := testModel()
+	next := testSnapshot()
+	next.Agents = append(next.Agents[:1], model.Agent{ID: "carol", LastSeen: time.Now()})
+	updated, _ := m.Update(snapshotReadyMsg{snap: next})
+	m = updated.(uiModel)
+	if out := stripAnsi(m.renderTitleBar()); !strings.Contains(out, "agents (+1 -1)") {
+		t.Errorf("title bar should show the trend, got %q", out)
+	}
+
+	// An unchanged agent set keeps the last trend on screen.
+	updated, _ = m.Update(snapshotReadyMsg{snap: next})
+	m = updated.(uiModel)
+	if out := stripAnsi(m.renderTitleBar()); !strings.Contains(out, "(+1 -1)") {
+		t.Errorf("trend should persist until the set changes, got %q", out)
+	}
+}
+
+// --- Per-view scroll memory ---
+
+func TestScrollPositionRestoredPerView(t *testing.T) {
+	m := testModel()
+	press := func(k string) {
+		t.Helper()
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = updated.(uiModel)
+	}
+
+	press("t")
+	for range 3 {
+		press("j")
+	}
+	if m.scrollPos != 3 {
+		t.Fatalf("scrollPos = %d after three downs, want 3", m.scrollPos)
+	}
+
+	press("m")
+	if m.scrollPos != 0 {
+		t.Errorf("Messages should start at its own offset, got %d", m.scrollPos)
+	}
+	press("j")
+
+	press("t")
+	if m.scrollPos != 3 {
+		t.Errorf("returning to Timeline should restore 3, got %d", m.scrollPos)
+	}
+	press("m")
+	if m.scrollPos != 1 {
+		t.Errorf("returning to Messages should restore 1, got %d", m.scrollPos)
+	}
+}
+
+func TestScrollMemoryResetWithFilter(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	m.filterAgent = "alice"
+	m.scrollPos = 4
+
+	// Leaving for an unfiltered view drops the filter, and with it the
+	// offset into the filtered list.
+	m, _ = m.switchView(viewLocks)
+	if m.filterAgent != "" {
+		t.Fatal("filter should clear outside Messages/Timeline")
+	}
+	m, _ = m.switchView(viewTimeline)
+	if m.scrollPos != 0 {
+		t.Errorf("Timeline offset should reset with the filter, got %d", m.scrollPos)
+	}
+}
+
+// --- Quiet hours ---
+
+func TestSessionIdleFor(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	recent := []model.Event{
+		{ID: 2, CreatedAt: now.Add(-30 * time.Second)},
+		{ID: 1, CreatedAt: now.Add(-20 * time.Minute)},
+	}
+	if got := sessionIdleFor(recent, now); got != 30*time.Second {
+		t.Errorf("idle = %v, want 30s (newest event, not last in slice)", got)
+	}
+	old := []model.Event{{CreatedAt: now.Add(-12 * time.Minute)}, {}}
+	if got := sessionIdleFor(old, now); got != 12*time.Minute {
+		t.Errorf("idle = %v, want 12m", got)
+	}
+	if got := sessionIdleFor(nil, now); got != 0 {
+		t.Errorf("no events should not count as idle, got %v", got)
+	}
+}
+
+func TestIdleSessionStatusNote(t *testing.T) {
+	m := testModel()
+	if strings.Contains(stripAnsi(m.renderStatusBar()), "idle for") {
+		t.Error("fresh session should not be idle")
+	}
+	for i := range m.snap.Events {
+		m.snap.Events[i].CreatedAt = time.Now().Add(-12*time.Minute - 5*time.Second)
+	}
+	if out := stripAnsi(m.renderStatusBar()); !strings.Contains(out, "idle for 12m (no new events)") {
+		t.Errorf("expected idle note, got %q", out)
+	}
+}
+
+// --- Decoded bodies ---
+
+func TestMaybeDecodeBody(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"eyJ0YXNrIjogImJ1aWxkIiwgIm9rIjogdHJ1ZX0=", `{"task": "build", "ok": true}`, true},
+		{"aGVsbG8gZnJvbSBhbGljZQ", "hello from alice", true}, // unpadded
+		{"68656c6c6f2c20626f6221", "hello, bob!", true},      // hex
+		{"please review main.go before merging", "please review main.go before merging", false},
+		{"c2hvcnQ=", "c2hvcnQ=", false},                                 // too short to trust
+		{"AAECAwQFBgcICQoLDA0ODw==", "AAECAwQFBgcICQoLDA0ODw==", false}, // binary payload
+	}
+	for _, tt := range tests {
+		got, ok := maybeDecodeBody(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("maybeDecodeBody(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMessagesDecodeToggle(t *testing.T) {
+	m := testModel()
+	m.activeView = viewMessages
+	m.snap.Events = append(m.snap.Events, model.Event{
+		ID: 99, AgentID: "alice", LamportTS: 50, Kind: model.EventMsg, Target: "bob",
+		Body: "aGVsbG8gZnJvbSBhbGljZQ==", CreatedAt: time.Now(),
+	})
+	if out := stripAnsi(m.renderMessages()); strings.Contains(out, "(decoded)") {
+		t.Error("bodies should stay raw until x is pressed")
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m = updated.(uiModel)
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, "hello from alice") || !strings.Contains(out, "(decoded)") {
+		t.Errorf("expected decoded body with tag, got:\n%s", out)
+	}
+}
+
+// --- Last message preview ---
+
+func TestDashboardLastMessagePreview(t *testing.T) {
+	now := time.Now()
+	m := testModel()
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: "first draft", CreatedAt: now},
+		{ID: 2, AgentID: "alice", LamportTS: 2, Kind: model.EventMsg, Target: "bob", Body: "ship it\nplease", CreatedAt: now},
+		{ID: 3, AgentID: "alice", LamportTS: 3, Kind: model.EventProgress, CreatedAt: now},
+		{ID: 4, AgentID: "bob", LamportTS: 4, Kind: model.EventLockReq, Target: "main.go", CreatedAt: now},
+	}
+	if e, ok := lastMessageFor(m.snap.Events, "alice"); !ok || e.ID != 2 {
+		t.Errorf("lastMessageFor(alice) = %d, %v; want event 2", e.ID, ok)
+	}
+	if _, ok := lastMessageFor(m.snap.Events, "bob"); ok {
+		t.Error("bob has sent no message")
+	}
+
+	if strings.Contains(stripAnsi(m.renderDashboard()), "ship it") {
+		t.Error("preview should be off by default")
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m = updated.(uiModel)
+	m.width = 110 // room beside the row, short of the split layout
+	for _, line := range strings.Split(stripAnsi(m.renderDashboard()), "\n") {
+		if !strings.HasPrefix(strings.TrimLeft(line, "> "), "alice ") {
+			continue
+		}
+		if !strings.Contains(line, "“ship it please”") {
+			t.Errorf("alice's row should preview her latest message, got %q", line)
+		}
+		if strings.Contains(line, "first draft") {
+			t.Errorf("only the latest message is previewed, got %q", line)
+		}
+	}
+
+	// Too narrow: the row is left alone rather than wrapping.
+	m.width = 80
+	if strings.Contains(stripAnsi(m.renderDashboard()), "ship it") {
+		t.Error("preview should be dropped when it does not fit")
+	}
+}
+
+// --- Narrow tab bar ---
+
+func TestTabBarFitsNarrowWidth(t *testing.T) {
+	m := testModel()
+	if out := stripAnsi(m.renderTabBar()); !strings.Contains(out, "Messages") {
+		t.Errorf("80 columns should fit full names, got %q", out)
+	}
+
+	m.width = 40
+	for _, v := range []viewID{viewDashboard, viewTimeline, viewDiagram} {
+		m.activeView = v
+		bar := m.renderTabBar()
+		if w := ansi.StringWidth(bar); w > 40 {
+			t.Errorf("%v: tab bar is %d columns wide, want <= 40: %q", v, w, stripAnsi(bar))
+		}
+		if !strings.Contains(bar, tabActiveStyle.Render(v.String())) {
+			t.Errorf("%v: active view should keep its highlighted full name: %q", v, stripAnsi(bar))
+		}
+	}
+
+	// Even a long Agent Detail tab is cut to the width.
+	m.activeView, m.detailAgentID = viewAgentDetail, strings.Repeat("x", 60)
+	if w := ansi.StringWidth(m.renderTabBar()); w > 40 {
+		t.Errorf("agent detail tab bar is %d columns wide, want <= 40", w)
+	}
+}
+
+// --- Stuck agents ---
+
+func TestSnapshotReadyMarksStuckAgent(t *testing.T) {
+	m := testModel()
+	m.progress = m.progress.observe(m.snap.Agents, time.Now().Add(-10*time.Minute))
+
+	// alice advances; bob is still at e0/r0.
+	next := testSnapshot()
+	next.Agents[0].Round = 1
+	updated, _ := m.Update(snapshotReadyMsg{snap: next})
+	m = updated.(uiModel)
+
+	// Agent table rows are the ones showing progress as e<epoch>/r<round>.
+	var bob, alice string
+	for _, line := range strings.Split(stripAnsi(m.renderDashboard()), "\n") {
+		switch {
+		case strings.Contains(line, "bob ") && strings.Contains(line, "e0/r0"):
+			bob = line
+		case strings.Contains(line, "alice ") && strings.Contains(line, "e1/r1"):
+			alice = line
+		}
+	}
+	if alice == "" || bob == "" {
+		t.Fatalf("agent rows not found:\n%s", stripAnsi(m.renderDashboard()))
+	}
+	if !strings.Contains(bob, "STUCK 10m") {
+		t.Errorf("bob row should be badged STUCK 10m: %q", bob)
+	}
+	if strings.Contains(alice, "STUCK") {
+		t.Errorf("alice advanced and should not be stuck: %q", alice)
+	}
+}
+
+// --- Fresh database ---
+
+func TestFreshDatabaseBanner(t *testing.T) {
+	m := testModel()
+	m.dbPath = "/tmp/.clockmail/clockmail.db"
+	m.snap = &snapshot.DataSnapshot{FrontierStatus: map[string]frontier.FrontierStatus{}}
+
+	want := "connected to /tmp/.clockmail/clockmail.db — no activity yet, waiting…"
+	if out := stripAnsi(m.View()); !strings.Contains(out, want) {
+		t.Errorf("empty snapshot should show the waiting banner:\n%s", out)
+	}
+
+	m.snap = testSnapshot()
+	if out := stripAnsi(m.View()); strings.Contains(out, "no activity yet") {
+		t.Errorf("populated snapshot should not show the waiting banner:\n%s", out)
+	}
+}
+
+// --- Diagram row cap ---
+
+func TestDiagramRowCap(t *testing.T) {
+	m := testModel()
+	m.snap.Agents = m.snap.Agents[:1] // alice only
+	m.snap.Events = nil
+	for i := int64(1); i <= 50; i++ {
+		m.snap.Events = append(m.snap.Events, model.Event{ID: i, AgentID: "alice", LamportTS: i, Kind: model.EventProgress})
+	}
+	m.diagramRows = 5
+
+	out := stripAnsi(m.renderDiagram())
+	var rows []string
+	for _, line := range strings.Split(out, "\n") {
+		if f := strings.Fields(line); len(f) == 2 && f[1] == "*" {
+			rows = append(rows, f[0])
+		}
+	}
+	if want := []string{"46", "47", "48", "49", "50"}; !slices.Equal(rows, want) {
+		t.Errorf("rows = %v, want the newest %v", rows, want)
+	}
+	if !strings.Contains(out, "⋮ (L:1–45, 45 older rows hidden") {
+		t.Errorf("missing summary marker:\n%s", out)
+	}
+
+	m.diagramRows = 0
+	if out := stripAnsi(m.renderDiagram()); strings.Contains(out, "⋮") {
+		t.Errorf("no cap should show every row:\n%s", out)
+	}
+}
+
+// --- Lamport ranks ---
+
+func TestLamportRanks(t *testing.T) {
+	events := []model.Event{
+		{LamportTS: 50}, {LamportTS: 3}, {LamportTS: 10}, {LamportTS: 3}, {LamportTS: 1000}, {LamportTS: 10},
+	}
+	got := lamportRanks(events)
+	want := map[int64]int{3: 1, 10: 2, 50: 3, 1000: 4}
+	if !maps.Equal(got, want) {
+		t.Errorf("lamportRanks = %v, want %v", got, want)
+	}
+	if got := lamportRanks(nil); len(got) != 0 {
+		t.Errorf("lamportRanks(nil) = %v, want empty", got)
+	}
+}
+
+func TestRanksToggleLabels(t *testing.T) {
+	m := testModel()
+	m.activeView = viewMessages
+	if out := stripAnsi(m.renderMessages()); strings.Contains(out, "(#") {
+		t.Errorf("ranks should be off by default:\n%s", out)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("#")})
+	m = updated.(uiModel)
+	if out := stripAnsi(m.renderMessages()); !strings.Contains(out, "[L:2 (#2)]") {
+		t.Errorf("# should add ranks to Lamport labels:\n%s", out)
+	}
+	if out := stripAnsi(m.renderTimeline()); !strings.Contains(out, "[L:4 (#4)]") {
+		t.Errorf("Timeline labels should show ranks too:\n%s", out)
+	}
+}
+
+// --- Out-of-order snapshot builds ---
+
+func TestOlderSnapshotIgnored(t *testing.T) {
+	m := testModel()
+	m, _ = m.refreshSnapshot()
+	m, _ = m.refreshSnapshot()
+	if m.buildSeq != 2 {
+		t.Fatalf("buildSeq = %d after two refreshes, want 2", m.buildSeq)
+	}
+
+	newer := testSnapshot()
+	newer.Events = append(newer.Events, model.Event{ID: 5, AgentID: "bob", LamportTS: 5, Kind: model.EventMsg, Target: "alice", Body: "latest"})
+	updated, _ := m.Update(snapshotReadyMsg{snap: newer, seq: 2})
+	m = updated.(uiModel)
+
+	// The first build finishes last, with older data and an error.
+	updated, _ = m.Update(snapshotReadyMsg{snap: testSnapshot(), err: errors.New("timeout"), seq: 1})
+	m = updated.(uiModel)
+
+	if m.snap != newer {
+		t.Error("an older build overwrote the newer snapshot")
+	}
+	if m.buildErr != nil {
+		t.Errorf("an older build's error was applied: %v", m.buildErr)
+	}
+	if m.appliedSeq != 2 {
+		t.Errorf("appliedSeq = %d, want 2", m.appliedSeq)
+	}
+}
+
+// --- Root blocker ---
+
+func TestRootBlocker(t *testing.T) {
+	pt := func(id string, e, r int64) model.Pointstamp {
+		return model.Pointstamp{AgentID: id, Timestamp: model.Timestamp{Epoch: e, Round: r}}
+	}
+	status := map[string]frontier.FrontierStatus{
+		"alice": {BlockedBy: []model.Pointstamp{pt("bob", 0, 0), pt("carol", 1, 0)}},
+		"dave":  {BlockedBy: []model.Pointstamp{pt("bob", 0, 0)}},
+		"erin":  {BlockedBy: []model.Pointstamp{pt("bob", 0, 0), pt("carol", 1, 0)}},
+		"carol": {BlockedBy: []model.Pointstamp{pt("bob", 0, 0)}},
+		"bob":   {SafeToFinalize: true},
+	}
+	if id, n := rootBlocker(status); id != "bob" || n != 4 {
+		t.Errorf("rootBlocker = %q, %d; want bob, 4", id, n)
+	}
+
+	// Equal counts: the lower position is the root.
+	tie := map[string]frontier.FrontierStatus{
+		"alice": {BlockedBy: []model.Pointstamp{pt("carol", 2, 0)}},
+		"dave":  {BlockedBy: []model.Pointstamp{pt("bob", 1, 3)}},
+	}
+	if id, n := rootBlocker(tie); id != "bob" || n != 1 {
+		t.Errorf("tie: rootBlocker = %q, %d; want bob, 1", id, n)
+	}
+
+	if id, n := rootBlocker(map[string]frontier.FrontierStatus{"bob": {SafeToFinalize: true}}); id != "" || n != 0 {
+		t.Errorf("nothing blocked: rootBlocker = %q, %d; want \"\", 0", id, n)
+	}
+}
+
+func TestRootBlockerCallout(t *testing.T) {
+	m := testModel()
+	m.width = 160
+	want := "root blocker: bob @ e0/r0 (blocking 1 agent)"
+	if out := stripAnsi(m.renderFrontier()); !strings.Contains(out, want) {
+		t.Errorf("Frontier view missing %q:\n%s", want, out)
+	}
+	if out := stripAnsi(m.renderTitleBar()); !strings.Contains(out, "root blocker: bob (1)") {
+		t.Errorf("title bar missing root blocker: %q", out)
+	}
+}
+
+// --- JSON bodies ---
+
+func TestPrettyIfJSON(t *testing.T) {
+	got, ok := prettyIfJSON(` {"task":"build","deps":[1,2]} `)
+	want := "{\n  \"task\": \"build\",\n  \"deps\": [\n    1,\n    2\n  ]\n}"
+	if !ok || got != want {
+		t.Errorf("prettyIfJSON(object) = %q, %v; want %q, true", got, ok, want)
+	}
+	for _, in := range []string{"hello world", "42", `"quoted"`, "{not json", ""} {
+		if got, ok := prettyIfJSON(in); ok || got != in {
+			t.Errorf("prettyIfJSON(%q) = %q, %v; want unchanged, false", in, got, ok)
+		}
+	}
+}
+
+func TestJSONBodyPrettyOnlyWhenExpanded(t *testing.T) {
+	m := testModel()
+	m.activeView = viewMessages
+	m.snap.Events[0].Body = `{"status":"ok","n":3}`
+
+	if out := stripAnsi(m.renderMessages()); !strings.Contains(out, `{"status":"ok","n":3}`) {
+		t.Errorf("collapsed view should show the raw body:\n%s", out)
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(uiModel)
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, `  "status": "ok",`) || strings.Contains(out, `{"status":"ok"`) {
+		t.Errorf("expanded view should pretty-print the JSON body:\n%s", out)
+	}
+}
+
+// --- Timeline group counts ---
+
+func TestTimelineGroupEventCount(t *testing.T) {
+	m := testModel()
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventProgress},
+		{ID: 2, AgentID: "alice", LamportTS: 4, Kind: model.EventProgress},
+		{ID: 3, AgentID: "bob", LamportTS: 4, Kind: model.EventProgress},
+		{ID: 4, AgentID: "carol", LamportTS: 4, Kind: model.EventMsg, Target: "alice", Body: "hi"},
+	}
+	out := stripAnsi(m.renderTimeline())
+	if !strings.Contains(out, "L:4 — 3 events") {
+		t.Errorf("three-event group should show its count:\n%s", out)
+	}
+	if strings.Contains(out, "L:1 —") {
+		t.Errorf("single-event group should have no count:\n%s", out)
+	}
+
+	// The count reflects the kind filter.
+	m.timelineKind = model.EventProgress
+	out = stripAnsi(m.renderTimeline())
+	if !strings.Contains(out, "L:4 — 2 events") {
+		t.Errorf("filtered group should count only shown events:\n%s", out)
+	}
+}
+
+// --- Nil snapshot ---
+
+func TestRenderWithNilSnapshot(t *testing.T) {
+	m := testModel()
+	m.snap = nil
+
+	renders := map[string]func() string{
+		"Dashboard":      m.renderDashboard,
+		"Messages":       m.renderMessages,
+		"MessageColumns": m.renderMessageColumns,
+		"Locks":          m.renderLocks,
+		"Frontier":       m.renderFrontier,
+		"Timeline":       m.renderTimeline,
+		"Diagram":        m.renderDiagram,
+		"AgentDetail":    func() string { return m.renderAgentDetailFor("alice") },
+	}
+	for name, render := range renders {
+		if out := stripAnsi(render()); !strings.Contains(out, "no data") {
+			t.Errorf("%s with nil snapshot = %q, want a no-data message", name, out)
+		}
+	}
+	for _, render := range []func() string{m.renderActivityStrip, m.renderGapBanner, m.renderFreshBanner, m.renderTabBar, m.renderStatusBar} {
+		render() // must not panic
+	}
+	if out := stripAnsi(m.renderTitleBar()); !strings.Contains(out, "no data") {
+		t.Errorf("title bar = %q, want a no-data note", out)
+	}
+
+	for v := viewID(0); v <= viewAgentDetail; v++ {
+		m.activeView = v
+		if out := stripAnsi(m.View()); !strings.Contains(out, "no data") {
+			t.Errorf("View in %s with nil snapshot lacks a no-data message:\n%s", v, out)
+		}
+	}
+	m.activeView, m.width = viewDashboard, 160 // split-pane layout
+	if out := stripAnsi(m.View()); !strings.Contains(out, "no snapshot loaded") {
+		t.Errorf("wide View with nil snapshot:\n%s", out)
+	}
+}
+
+func TestNewModelNilSnapshot(t *testing.T) {
+	m := newModel(nil, nil, nil, "cm.db")
+	if m.snap == nil {
+		t.Fatal("newModel kept a nil snapshot")
+	}
+	m.width, m.height = 80, 24
+	m.View() // must not panic
+}
+
+// --- Clock lag ---
+
+func TestClockLag(t *testing.T) {
+	agents := []model.Agent{{ID: "alice", Clock: 44}, {ID: "bob", Clock: 10}, {ID: "carol", Clock: 44}}
+	tests := map[string]int64{"alice": 0, "bob": 34, "carol": 0}
+	for _, ag := range agents {
+		if got := clockLag(ag, agents); got != tests[ag.ID] {
+			t.Errorf("clockLag(%s) = %d, want %d", ag.ID, got, tests[ag.ID])
+		}
+	}
+	if got := clockLag(model.Agent{Clock: 7}, nil); got != 0 {
+		t.Errorf("clockLag with no peers = %d, want 0", got)
+	}
+}
+
+func TestClockLagShown(t *testing.T) {
+	m := testModel() // alice at 10, bob at 5
+	out := stripAnsi(m.renderDashboard())
+	if !strings.Contains(out, "5 -5") {
+		t.Errorf("Dashboard should show bob 5 behind:\n%s", out)
+	}
+	if strings.Contains(out, "10 -") {
+		t.Errorf("the leader should show no lag:\n%s", out)
+	}
+	if out := stripAnsi(m.renderAgentDetailFor("bob")); !strings.Contains(out, "Lamport clock: 5 (5 behind)") {
+		t.Errorf("Agent Detail should show the lag:\n%s", out)
+	}
+}
+
+// --- Diagram column pan ---
+
+func TestDiagramPanKeepsLColumn(t *testing.T) {
+	m := testModel() // 80 columns: five agent columns fit
+	m.activeView = viewDiagram
+	m.snap.Agents, m.snap.Events = nil, nil
+	for i := range 8 {
+		id := fmt.Sprintf("agent%d", i)
+		m.snap.Agents = append(m.snap.Agents, model.Agent{ID: id})
+		m.snap.Events = append(m.snap.Events, model.Event{ID: int64(i + 1), AgentID: id, LamportTS: int64(i + 1), Kind: model.EventProgress})
+	}
+
+	header := func(out string) string {
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "L ") {
+				return line
+			}
+		}
+		t.Fatalf("no header row:\n%s", out)
+		return ""
+	}
+	out := stripAnsi(m.renderDiagram())
+	if h := header(out); strings.Contains(h, "agent7") || !strings.Contains(h, "agent0") {
+		t.Errorf("unpanned header = %q, want the first columns", h)
+	}
+	if !strings.Contains(out, "Columns 1–5 of 8") {
+		t.Errorf("missing pan note:\n%s", out)
+	}
+
+	for range 10 { // past the end: clamps to the last window
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRight})
+		m = updated.(uiModel)
+	}
+	out = stripAnsi(m.renderDiagram())
+	h := header(out)
+	if !strings.HasPrefix(h, "  L  ") {
+		t.Errorf("L column moved: %q", h)
+	}
+	if !strings.Contains(h, "agent7") || strings.Contains(h, "agent0") {
+		t.Errorf("panned header = %q, want the last columns", h)
+	}
+	if !strings.Contains(out, "Columns 4–8 of 8") {
+		t.Errorf("missing pan note after panning:\n%s", out)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	m = updated.(uiModel)
+	if m.diagramCol != 2 {
+		t.Errorf("diagramCol after left = %d, want 2", m.diagramCol)
+	}
+}
+
+// --- Concurrency stats ---
+
+func TestConcurrencyStats(t *testing.T) {
+	groups := groupByLamport([]model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1},
+		{ID: 2, AgentID: "alice", LamportTS: 2},
+		{ID: 3, AgentID: "bob", LamportTS: 2},
+		{ID: 4, AgentID: "carol", LamportTS: 2},
+		{ID: 5, AgentID: "dave", LamportTS: 2},
+		{ID: 6, AgentID: "bob", LamportTS: 3},
+		{ID: 7, AgentID: "carol", LamportTS: 5},
+		{ID: 8, AgentID: "dave", LamportTS: 5},
+	})
+	avg, peak := concurrencyStats(groups) // 8 events over 4 timestamps
+	if avg != 2 || peak != 4 {
+		t.Errorf("concurrencyStats = %v, %d; want 2, 4", avg, peak)
+	}
+	if avg, peak := concurrencyStats(nil); avg != 0 || peak != 0 {
+		t.Errorf("concurrencyStats(nil) = %v, %d; want 0, 0", avg, peak)
+	}
+
+	m := testModel() // four events at four timestamps
+	for name, out := range map[string]string{"Timeline": m.renderTimeline(), "Diagram": m.renderDiagram()} {
+		if !strings.Contains(stripAnsi(out), "avg concurrency 1.0, max 1") {
+			t.Errorf("%s lacks the stats line:\n%s", name, stripAnsi(out))
+		}
+	}
+}
+
+// --- Detail limit ---
+
+func TestDetailLimitTruncates(t *testing.T) {
+	m := testModel()
+	m.detailLimit = 2
+	for i := range 3 {
+		m.snap.Events = append(m.snap.Events, model.Event{
+			ID: int64(10 + i), AgentID: "alice", LamportTS: int64(10 + i),
+			Kind: model.EventMsg, Target: "bob", Body: fmt.Sprintf("note %d", i),
+		})
+	}
+	out := stripAnsi(m.renderAgentDetailFor("alice"))
+	// alice sent four messages: "hello" and notes 0-2; the newest two show.
+	for _, want := range []string{"note 2", "note 1"} {
+		if !strings.Contains(out, "-> bob: "+want) {
+			t.Errorf("missing sent %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "-> bob: note 0") {
+		t.Errorf("sent list exceeds the limit:\n%s", out)
+	}
+	if !strings.Contains(out, "(+2 more)") {
+		t.Errorf("missing truncation note for sent:\n%s", out)
+	}
+	// Recent Activity shares the limit: six events, four hidden.
+	if !strings.Contains(out, "(+4 more)") {
+		t.Errorf("missing truncation note for activity:\n%s", out)
+	}
+	// Received has a single message: no note.
+	if strings.Count(out, "more)") != 2 {
+		t.Errorf("want exactly two truncation notes:\n%s", out)
+	}
+
+	m.detailLimit = 0
+	if out := stripAnsi(m.renderAgentDetailFor("alice")); strings.Contains(out, "more)") {
+		t.Errorf("limit 0 should show everything:\n%s", out)
+	}
+}
+
+// --- Newest message overlay ---
+
+func TestNewestMessageOverlay(t *testing.T) {
+	m := testModel()
+	m.activeView = viewLocks
+	m.scrollPos = 3
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("E")})
+	m = updated.(uiModel)
+	if !m.newestOverlay {
+		t.Fatal("E did not open the overlay")
+	}
+	// Newest message in testSnapshot: bob -> alice "hi back" at L:2.
+	out := stripAnsi(m.View())
+	if !strings.Contains(out, "[L:2] bob -> alice") || !strings.Contains(out, "hi back") {
+		t.Errorf("overlay should show the newest message:\n%s", out)
+	}
+	if strings.Contains(out, "hello") {
+		t.Errorf("overlay shows an older message:\n%s", out)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	m = updated.(uiModel)
+	if m.newestOverlay || m.scrollPos != 3 {
+		t.Errorf("any key should just close the overlay (open=%v, scroll=%d)", m.newestOverlay, m.scrollPos)
+	}
+
+	m.snap.Events = nil
+	m.newestOverlay = true
+	if out := stripAnsi(m.View()); !strings.Contains(out, "(no messages yet)") {
+		t.Errorf("overlay without messages:\n%s", out)
+	}
+}
+
+// --- Long agent IDs ---
+
+func TestEllipsizeMiddle(t *testing.T) {
+	id := "0b7f3c2e-5d41-4a8e-9f6b-1c2d3e9e2a41"
+	got := ellipsizeMiddle(id, 16)
+	if got != "0b7f3c2e…e9e2a41" {
+		t.Errorf("ellipsizeMiddle = %q", got)
+	}
+	if n := utf8.RuneCountInString(got); n != 16 {
+		t.Errorf("width = %d, want 16", n)
+	}
+	if got := ellipsizeMiddle("alice", 16); got != "alice" {
+		t.Errorf("short ID changed: %q", got)
+	}
+	if got := ellipsizeMiddle(id, 1); got != "…" {
+		t.Errorf("max 1 = %q, want …", got)
+	}
+}
+
+func TestLongAgentIDsKeepColumns(t *testing.T) {
+	m := testModel()
+	long := "0b7f3c2e-5d41-4a8e-9f6b-1c2d3e9e2a41"
+	m.snap.Agents = append(m.snap.Agents, model.Agent{ID: long, Clock: 3, LastSeen: time.Now()})
+	dash := stripAnsi(m.renderDashboard())
+	if !strings.Contains(dash, "0b7f3c2e…e9e2a41 3") {
+		t.Errorf("Dashboard should middle-truncate the ID to its column:\n%s", dash)
+	}
+	m.activeView = viewDiagram
+	if diag := stripAnsi(m.renderDiagram()); !strings.Contains(diag, "0b7f3c…e2a41") {
+		t.Errorf("Diagram header should middle-truncate the ID:\n%s", diag)
+	}
+}
+
+// --- Epoch markers ---
+
+func TestTimelineEpochMarkers(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventProgress, Epoch: 1},
+		{ID: 2, AgentID: "alice", LamportTS: 2, Kind: model.EventProgress, Epoch: 1},
+		{ID: 3, AgentID: "bob", LamportTS: 3, Kind: model.EventProgress, Epoch: 4}, // baseline only
+		{ID: 4, AgentID: "alice", LamportTS: 4, Kind: model.EventProgress, Epoch: 2},
+		{ID: 5, AgentID: "alice", LamportTS: 5, Kind: model.EventProgress, Epoch: 2},
+		{ID: 6, AgentID: "alice", LamportTS: 6, Kind: model.EventProgress, Epoch: 3},
+	}
+	if out := stripAnsi(m.renderTimeline()); strings.Contains(out, "advanced to epoch") {
+		t.Errorf("markers shown before e:\n%s", out)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = updated.(uiModel)
+	out := stripAnsi(m.renderTimeline())
+	for _, epoch := range []string{"2", "3"} {
+		if n := strings.Count(out, "── alice advanced to epoch "+epoch+" ──"); n != 1 {
+			t.Errorf("marker for epoch %s appears %d times, want once:\n%s", epoch, n, out)
+		}
+	}
+	if n := strings.Count(out, "advanced to epoch"); n != 2 {
+		t.Errorf("want exactly two markers, got %d:\n%s", n, out)
+	}
+	// Newest first: the epoch 3 marker sits above the epoch 2 one.
+	if strings.Index(out, "epoch 3") > strings.Index(out, "epoch 2") {
+		t.Errorf("markers out of order:\n%s", out)
+	}
+}
+
+// --- Split ratio ---
+
+func TestSplitRatio(t *testing.T) {
+	m := testModel()
+	m.width, m.height = 160, 40
+
+	left, right, ok := splitWidths(m.width, 0) // default: even split
+	if !ok || left != 79 || right != 78 {
+		t.Errorf("default split = %d/%d (ok=%v), want 79/78", left, right, ok)
+	}
+	m.splitRatio = 0.4
+	if got := m.dashboardWidth(); got != 63 {
+		t.Errorf("dashboardWidth at 0.4 = %d, want 63", got)
+	}
+	if left, right, _ := splitWidths(m.width, 0.4); left+right+3 != m.width || right != 94 {
+		t.Errorf("0.4 split = %d/%d, want 63/94", left, right)
+	}
+	// The detail pane starts right after the narrower Dashboard.
+	found := false
+	for _, line := range strings.Split(stripAnsi(m.View()), "\n") {
+		if i := strings.Index(line, "Agent: alice"); i >= 0 {
+			found = true
+			if col := ansi.StringWidth(line[:i]); col < 63 || col > 70 {
+				t.Errorf("detail pane at column %d, want just past 63:\n%s", col, line)
+			}
+		}
+	}
+	if !found {
+		t.Error("split view lacks the detail pane")
+	}
+
+	// A ratio leaving a pane under minSplitPane falls back to one pane.
+	m.splitRatio = 0.2
+	if m.dashboardSplit() {
+		t.Error("0.2 of 160 columns should not split")
+	}
+	if got := m.dashboardWidth(); got != m.width {
+		t.Errorf("single-pane dashboardWidth = %d, want %d", got, m.width)
+	}
+	if out := stripAnsi(m.View()); strings.Contains(out, "Agent: alice") {
+		t.Errorf("fallback should show only the Dashboard:\n%s", out)
+	}
+}
+
+// --- Frontier consistency ---
+
+func TestFrontierConsistency(t *testing.T) {
+	agents := []model.Agent{{ID: "alice"}, {ID: "bob"}}
+	points := []model.Pointstamp{
+		{AgentID: "alice", Timestamp: model.Timestamp{Epoch: 1}},
+		{AgentID: "ghost", Timestamp: model.Timestamp{Epoch: 0}},
+	}
+	orphans, missing := frontierConsistency(points, agents)
+	if !slices.Equal(orphans, []string{"ghost"}) || !slices.Equal(missing, []string{"bob"}) {
+		t.Errorf("frontierConsistency = %v, %v; want [ghost], [bob]", orphans, missing)
+	}
+
+	m := testModel()
+	if out := stripAnsi(m.renderFrontier()); strings.Contains(out, "orphan") || strings.Contains(out, "no active pointstamp") {
+		t.Errorf("consistent snapshot flagged:\n%s", out)
+	}
+	m.snap.Pointstamps = points
+	out := stripAnsi(m.renderFrontier())
+	for _, want := range []string{"orphan pointstamp: ghost (no such agent)", "agent bob has no active pointstamp"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Frontier lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+// --- Diagram arrow focus ---
+
+func TestDiagramArrowFocus(t *testing.T) {
+	m := testModel()
+	m.activeView = viewDiagram
+	m.snap.Agents = append(m.snap.Agents, model.Agent{ID: "carol"})
+	m.snap.Events = []model.Event{
+		{ID: 1, AgentID: "alice", LamportTS: 1, Kind: model.EventMsg, Target: "bob", Body: "a"},
+		{ID: 2, AgentID: "bob", LamportTS: 2, Kind: model.EventMsg, Target: "carol", Body: "b"},
+		{ID: 3, AgentID: "carol", LamportTS: 3, Kind: model.EventMsg, Target: "alice", Body: "c"},
+	}
+	arrows := func(out string) int {
+		return strings.Count(out, "▶") + strings.Count(out, "◀")
+	}
+	if n := arrows(stripAnsi(m.renderDiagram())); n != 3 {
+		t.Fatalf("unfocused diagram has %d arrows, want 3", n)
+	}
+
+	m.selectedAgentID = "bob"
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = updated.(uiModel)
+	out := stripAnsi(m.renderDiagram())
+	// bob sent to carol and heard from alice; carol -> alice is dropped.
+	if n := arrows(out); n != 2 {
+		t.Errorf("focused on bob: %d arrows, want 2:\n%s", n, out)
+	}
+	if !strings.Contains(out, "carol") {
+		t.Errorf("columns should all remain:\n%s", out)
+	}
+	// carol's send at L:3 has no arrow: the next line is the footer gap.
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "  3  ") && i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			t.Errorf("carol -> alice arrow drawn while focused on bob:\n%s", out)
+		}
+	}
+}
+
+// --- Slow builds ---
+
+func TestSlowBuildWarnsAndCoalesces(t *testing.T) {
+	m := testModel()
+	m.refreshInterval = 100 * time.Millisecond
+
+	m, _ = m.refreshSnapshot()
+	if !m.building {
+		t.Fatal("building should be set while a build is in flight")
+	}
+	// A change mid-build is deferred, not started as a second build.
+	updated, cmd := m.Update(dbChangedMsg{})
+	m = updated.(uiModel)
+	if cmd != nil || m.buildSeq != 1 {
+		t.Fatalf("dbChangedMsg during a build started another (seq %d)", m.buildSeq)
+	}
+	if !m.refreshDue {
+		t.Fatal("the deferred change should be recorded")
+	}
+	updated, _ = m.Update(dbChangedMsg{})
+	m = updated.(uiModel)
+
+	// The slow build lands: warn, and run exactly one coalesced rebuild.
+	updated, cmd = m.Update(snapshotReadyMsg{snap: testSnapshot(), seq: 1, took: 300 * time.Millisecond})
+	m = updated.(uiModel)
+	if !m.slowBuild() {
+		t.Error("a 300ms build with --refresh 100ms should be flagged slow")
+	}
+	if !strings.Contains(stripAnsi(m.renderStatusBar()), "raise --refresh") {
+		t.Errorf("status bar lacks the warning: %q", stripAnsi(m.renderStatusBar()))
+	}
+	if cmd == nil || m.buildSeq != 2 || m.refreshDue {
+		t.Errorf("want one coalesced rebuild: cmd %v, seq %d, due %v", cmd != nil, m.buildSeq, m.refreshDue)
+	}
+
+	// A fast build clears the warning.
+	updated, _ = m.Update(snapshotReadyMsg{snap: testSnapshot(), seq: 2, took: 10 * time.Millisecond})
+	m = updated.(uiModel)
+	if m.slowBuild() || m.building {
+		t.Errorf("fast build: slow %v, building %v", m.slowBuild(), m.building)
+	}
+}
+
+// --- Wall-clock times ---
+
+func TestMessagesWallClock(t *testing.T) {
+	m := testModel()
+	now := time.Now()
+	m.snap.Events[0].CreatedAt = now.Add(-3*time.Minute - 10*time.Second)
+	m.snap.Events[1].CreatedAt = time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	m.utc = true
+
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, "3m ago alice -> bob") {
+		t.Errorf("recent message should show a relative time:\n%s", out)
+	}
+	if !strings.Contains(out, "15:04:05 bob -> alice") {
+		t.Errorf("older message should show the UTC time of day:\n%s", out)
+	}
+
+	// Too narrow for the extra text: the header keeps its arrow instead.
+	m.width = 24
+	for _, line := range strings.Split(stripAnsi(m.renderMessages()), "\n") {
+		if strings.Contains(line, "bob -> alice") && strings.Contains(line, "15:04:05") {
+			t.Errorf("time kept on a line too narrow for it: %q", line)
+		}
+	}
+	if !strings.Contains(stripAnsi(m.renderMessages()), "bob -> alice") {
+		t.Error("narrow header lost its arrow")
+	}
+}
+
+// --- Timeline order ---
+
+func TestTimelineOrderToggle(t *testing.T) {
+	m := testModel()
+	m.activeView = viewTimeline
+	m.scrollPos = 3
+	out := stripAnsi(m.renderTimeline())
+	if !strings.Contains(out, "L:4") || strings.Index(out, "L:4") > strings.Index(out, "L:1") {
+		t.Fatalf("default order should be newest first:\n%s", out)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	m = updated.(uiModel)
+	if !m.timelineAscending || m.scrollPos != 0 {
+		t.Fatalf("o: ascending %v, scrollPos %d; want true, 0", m.timelineAscending, m.scrollPos)
+	}
+	out = stripAnsi(m.renderTimeline())
+	if strings.Index(out, "L:1") > strings.Index(out, "L:4") {
+		t.Errorf("toggled order should be oldest first:\n%s", out)
+	}
+	if !strings.Contains(out, "oldest first") {
+		t.Errorf("legend should name the order:\n%s", out)
+	}
+	if help := contextHelp(m.activeView, m.timelineAscending); !strings.Contains(help, "o: newest first") {
+		t.Errorf("help should offer the way back: %q", help)
+	}
+}
+
+// --- Body search ---
+
+func TestBodySearch(t *testing.T) {
+	m := testModel()
+	m.activeView = viewMessages
+	press := func(msg tea.KeyMsg) {
+		t.Helper()
+		updated, _ := m.Update(msg)
+		m = updated.(uiModel)
+	}
+	typeText := func(s string) {
+		t.Helper()
+		for _, r := range s {
+			press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyCtrlF})
+	typeText("HIX")
+	press(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.searchQuery != "HI" || !m.searching {
+		t.Fatalf("query %q (searching %v), want \"HI\" while typing", m.searchQuery, m.searching)
+	}
+	// Typed keys are query text, not shortcuts: "t" must not switch views.
+	typeText("t")
+	press(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.activeView != viewMessages {
+		t.Fatalf("typing switched to view %v", m.activeView)
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.searching || m.searchQuery != "HI" {
+		t.Fatalf("enter should keep the query and close the prompt: %q, %v", m.searchQuery, m.searching)
+	}
+
+	out := stripAnsi(m.renderMessages())
+	if !strings.Contains(out, "[search: HI]") {
+		t.Errorf("header lacks the query:\n%s", out)
+	}
+	if !strings.Contains(out, "hi back") || strings.Contains(out, "hello") {
+		t.Errorf("want only the message containing \"hi\", ignoring case:\n%s", out)
+	}
+
+	m.activeView = viewTimeline
+	out = stripAnsi(m.renderTimeline())
+	if !strings.Contains(out, "hi back") || strings.Contains(out, "hello") || strings.Contains(out, "main.go") {
+		t.Errorf("Timeline should keep only matching bodies:\n%s", out)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.searchQuery != "" {
+		t.Errorf("esc left query %q", m.searchQuery)
+	}
+	if !strings.Contains(stripAnsi(m.renderTimeline()), "hello") {
+		t.Error("clearing the search should show every event again")
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	saved := searchMatchStyle
+	defer func() { searchMatchStyle = saved }()
+	searchMatchStyle = lipgloss.NewStyle().Transform(func(s string) string { return "<" + s + ">" })
+
+	if got := highlightMatches("Hi there, hi", "hi"); got != "<Hi> there, <hi>" {
+		t.Errorf("highlightMatches = %q", got)
+	}
+	if got := highlightMatches("\x1b[2mhi\x1b[0m", "hi"); got != "\x1b[2mhi\x1b[0m" {
+		t.Errorf("styled line changed: %q", got)
+	}
+}
+
+// --- Activity strip ---
+
+func TestActivityStripNotesPartialHistory(t *testing.T) {
+	m := testModel()
+	base := time.Now().Add(-time.Hour)
+	m.snap.Events = []model.Event{{CreatedAt: base}, {CreatedAt: base.Add(30 * time.Minute)}}
+	m.snap.TotalEvents = 40
+
+	out := stripAnsi(m.renderActivityStrip())
+	if !strings.HasPrefix(out, "activity/5m ") {
+		t.Errorf("unexpected strip: %q", out)
+	}
+	if !strings.Contains(out, "(2 of 40 events)") {
+		t.Errorf("strip should note it covers only loaded events: %q", out)
+	}
+	if !strings.Contains(stripAnsi(m.View()), "activity/5m") {
+		t.Error("strip should be shown above the status bar")
+	}
+
+	// A single bucket has no density to show.
+	m.snap.Events = m.snap.Events[:1]
+	if got := m.renderActivityStrip(); got != "" {
+		t.Errorf("single bucket should render nothing, got %q", got)
+	}
+}
+
+func TestActivityStripCountsWidenedEventsOnce(t *testing.T) {
+	m := testModel()
+	base := time.Now().Add(-time.Hour)
+	m.snap.Events = []model.Event{{ID: 3, CreatedAt: base.Add(30 * time.Minute)}, {ID: 4, CreatedAt: base.Add(40 * time.Minute)}}
+	m.snap.TotalEvents = 10
+
+	older := []model.Event{{ID: 1, CreatedAt: base}, {ID: 2, CreatedAt: base.Add(10 * time.Minute)}}
+	updated, _ := m.Update(olderEventsMsg{events: older, searched: 2})
+	m = updated.(uiModel)
+	if out := stripAnsi(m.renderActivityStrip()); !strings.Contains(out, "(4 of 10 events)") {
+		t.Errorf("widened events should be counted once: %q", out)
+	}
+}
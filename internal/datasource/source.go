@@ -10,12 +10,26 @@ import (
 )
 
 const (
-	defaultDir = ".clockmail"
-	defaultDB  = ".clockmail/clockmail.db"
+	defaultDir    = ".clockmail"
+	defaultDBName = "clockmail.db"
 )
 
+// dbName returns the database file name to look for in .clockmail/:
+// $CLOCKMAIL_DB_NAME, or clockmail.db. It must be a bare file name.
+func dbName() (string, error) {
+	name := os.Getenv("CLOCKMAIL_DB_NAME")
+	if name == "" {
+		return defaultDBName, nil
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("CLOCKMAIL_DB_NAME=%q: must be a file name, not a path", name)
+	}
+	return name, nil
+}
+
 // Discover finds the clockmail database path.
-// Priority: CLOCKMAIL_DB env var > .clockmail/clockmail.db in CWD > walk up parents.
+// Priority: CLOCKMAIL_DB env var > .clockmail/<name> in CWD > walk up parents,
+// where <name> is $CLOCKMAIL_DB_NAME or clockmail.db.
 func Discover() (string, error) {
 	if env := os.Getenv("CLOCKMAIL_DB"); env != "" {
 		if _, err := os.Stat(env); err == nil {
@@ -24,6 +38,12 @@ func Discover() (string, error) {
 		return "", fmt.Errorf("CLOCKMAIL_DB=%q: %w", env, os.ErrNotExist)
 	}
 
+	name, err := dbName()
+	if err != nil {
+		return "", err
+	}
+	defaultDB := filepath.Join(defaultDir, name)
+
 	// Check CWD first.
 	if _, err := os.Stat(defaultDB); err == nil {
 		abs, err := filepath.Abs(defaultDB)
@@ -169,3 +169,74 @@ func TestOpenFail(t *testing.T) {
 		t.Error("Open should fail when no database exists")
 	}
 }
+
+// makeNamedDB creates dir/.clockmail/<name> as a clockmail store.
+func makeNamedDB(t *testing.T, dir, name string) string {
+	t.Helper()
+	cmDir := filepath.Join(dir, ".clockmail")
+	if err := os.MkdirAll(cmDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	dbPath := filepath.Join(cmDir, name)
+	s, err := store.New(dbPath)
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	s.Close()
+	return dbPath
+}
+
+func TestDiscoverCustomNameInCWD(t *testing.T) {
+	dir := t.TempDir()
+	makeNamedDB(t, dir, "coordination.db")
+
+	t.Setenv("CLOCKMAIL_DB", "")
+	t.Setenv("CLOCKMAIL_DB_NAME", "coordination.db")
+	t.Chdir(dir)
+
+	path, err := Discover()
+	if err != nil {
+		t.Fatalf("Discover with custom name: %v", err)
+	}
+	if filepath.Base(path) != "coordination.db" {
+		t.Errorf("expected coordination.db, got %q", path)
+	}
+
+	// The default name is not found under the custom convention, and
+	// vice versa.
+	t.Setenv("CLOCKMAIL_DB_NAME", "")
+	if _, err := Discover(); err == nil {
+		t.Error("default name should not match coordination.db")
+	}
+}
+
+func TestDiscoverCustomNameInParentDir(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := makeNamedDB(t, dir, "coordination.db")
+	child := filepath.Join(dir, "sub", "deep")
+	if err := os.MkdirAll(child, 0o755); err != nil {
+		t.Fatalf("MkdirAll child: %v", err)
+	}
+
+	t.Setenv("CLOCKMAIL_DB", "")
+	t.Setenv("CLOCKMAIL_DB_NAME", "coordination.db")
+	t.Chdir(child)
+
+	path, err := Discover()
+	if err != nil {
+		t.Fatalf("Discover from parent with custom name: %v", err)
+	}
+	resolvedPath, _ := filepath.EvalSymlinks(path)
+	resolvedExpect, _ := filepath.EvalSymlinks(dbPath)
+	if resolvedPath != resolvedExpect {
+		t.Errorf("Discover() = %q, want %q", path, dbPath)
+	}
+}
+
+func TestDiscoverRejectsPathAsName(t *testing.T) {
+	t.Setenv("CLOCKMAIL_DB", "")
+	t.Setenv("CLOCKMAIL_DB_NAME", "../elsewhere.db")
+	if _, err := Discover(); err == nil {
+		t.Error("a path in CLOCKMAIL_DB_NAME should be rejected")
+	}
+}
@@ -6,13 +6,41 @@
 package snapshot
 
 import (
+	"errors"
 	"time"
 
 	"github.com/daviddao/clockmail/pkg/frontier"
 	"github.com/daviddao/clockmail/pkg/model"
-	"github.com/daviddao/clockmail/pkg/store"
 )
 
+// ErrBuildTimeout is returned by BuildWithTimeout when the store does not
+// answer in time (e.g. a long writer holds the database lock).
+var ErrBuildTimeout = errors.New("snapshot timed out")
+
+// Reader is the subset of store operations Build needs. *store.Store
+// satisfies it; tests can substitute a stub.
+type Reader interface {
+	ListAgents() ([]model.Agent, error)
+	ListEventsSinceID(sinceID int64, limit int) ([]model.Event, error)
+	MaxEventID() int64
+	CountEvents() int64
+	ListLocks() ([]model.Lock, error)
+	GetActivePointstamps() ([]model.Pointstamp, error)
+}
+
+// DefaultStaleAfter is how long an agent may go unseen before Build counts
+// it as stale, when the caller passes no threshold function.
+const DefaultStaleAfter = 10 * time.Minute
+
+// EventLimit is how many of the newest events a snapshot holds.
+const EventLimit = 500
+
+// EventWindowStart returns the ListEventsSinceID cursor Build uses to load
+// the newest EventLimit events when the highest event ID is maxID.
+func EventWindowStart(maxID int64) int64 {
+	return max(0, maxID-int64(EventLimit))
+}
+
 // DataSnapshot is an immutable, self-contained view of the clockmail state.
 type DataSnapshot struct {
 	Agents   []model.Agent
@@ -20,9 +48,24 @@ type DataSnapshot struct {
 	Locks    []model.Lock
 	Frontier []model.Pointstamp
 
+	// Pointstamps are the active pointstamps Frontier was computed from,
+	// one per agent the store tracks, including those the antichain drops.
+	Pointstamps []model.Pointstamp
+
 	// Pre-computed per-agent frontier status.
 	FrontierStatus map[string]frontier.FrontierStatus
 
+	// FrontierAvailable is false when the store could not report active
+	// pointstamps (e.g. an older clockmail without them). Frontier and
+	// FrontierStatus are then empty rather than computed from nothing.
+	FrontierAvailable bool
+
+	// MinLoadedID and MaxLoadedID are the lowest and highest event IDs in
+	// Events (0 when there are none). Compared with TotalEvents they tell
+	// how much of the log the snapshot covers.
+	MinLoadedID int64
+	MaxLoadedID int64
+
 	// Counts.
 	ActiveAgents int
 	StaleAgents  int
@@ -33,22 +76,46 @@ type DataSnapshot struct {
 	BuiltAt time.Time
 }
 
-// Build queries the store and returns a complete snapshot.
-func Build(s *store.Store) (*DataSnapshot, error) {
+// BuildWithTimeout is Build with a deadline. The store API is not
+// context-aware, so the build runs in its own goroutine; on timeout its
+// result is abandoned and ErrBuildTimeout is returned.
+func BuildWithTimeout(s Reader, timeout time.Duration, staleAfter func(agentID string) time.Duration) (*DataSnapshot, error) {
+	type result struct {
+		snap *DataSnapshot
+		err  error
+	}
+	done := make(chan result, 1) // buffered so an abandoned build can finish
+	go func() {
+		snap, err := Build(s, staleAfter)
+		done <- result{snap, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.snap, r.err
+	case <-timer.C:
+		return nil, ErrBuildTimeout
+	}
+}
+
+// Build queries the store and returns a complete snapshot. staleAfter
+// gives how long each agent may go unseen before it counts as stale; nil
+// means DefaultStaleAfter for every agent.
+func Build(s Reader, staleAfter func(agentID string) time.Duration) (*DataSnapshot, error) {
+	if staleAfter == nil {
+		staleAfter = func(string) time.Duration { return DefaultStaleAfter }
+	}
 	agents, err := s.ListAgents()
 	if err != nil {
 		return nil, err
 	}
 
-	// Fetch the newest 500 events by using MaxEventID as an anchor.
+	// Fetch the newest EventLimit events by using MaxEventID as an anchor.
 	// ListEvents(0, 500) would return the 500 oldest, missing recent activity.
-	const eventLimit = 500
-	maxID := s.MaxEventID()
-	sinceID := maxID - int64(eventLimit)
-	if sinceID < 0 {
-		sinceID = 0
-	}
-	events, err := s.ListEventsSinceID(sinceID, eventLimit)
+	sinceID := EventWindowStart(s.MaxEventID())
+	events, err := s.ListEventsSinceID(sinceID, EventLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -58,40 +125,101 @@ func Build(s *store.Store) (*DataSnapshot, error) {
 		return nil, err
 	}
 
+	// Pointstamps are optional: without them the rest of the snapshot is
+	// still useful, so degrade to an unavailable frontier instead of failing.
+	var f []model.Pointstamp
+	fStatus := make(map[string]frontier.FrontierStatus, len(agents))
 	active, err := s.GetActivePointstamps()
-	if err != nil {
-		return nil, err
-	}
-
-	f := frontier.ComputeFrontier(active)
+	frontierOK := err == nil
+	if frontierOK {
+		f = frontier.ComputeFrontier(active)
 
-	// Compute per-agent frontier status.
-	fStatus := make(map[string]frontier.FrontierStatus, len(agents))
-	for _, ag := range agents {
-		ts := model.Timestamp{Epoch: ag.Epoch, Round: ag.Round}
-		fStatus[ag.ID] = frontier.ComputeFrontierStatus(ag.ID, ts, active)
+		// Compute per-agent frontier status.
+		for _, ag := range agents {
+			ts := model.Timestamp{Epoch: ag.Epoch, Round: ag.Round}
+			fStatus[ag.ID] = frontier.ComputeFrontierStatus(ag.ID, ts, active)
+		}
 	}
 
 	// Count active vs stale.
 	var activeCount, staleCount int
 	for _, ag := range agents {
-		if time.Since(ag.LastSeen) > 10*time.Minute {
+		if time.Since(ag.LastSeen) > staleAfter(ag.ID) {
 			staleCount++
 		} else {
 			activeCount++
 		}
 	}
 
+	var minID, maxID int64
+	if len(events) > 0 {
+		minID, maxID = events[0].ID, events[len(events)-1].ID
+	}
+
 	return &DataSnapshot{
-		Agents:         agents,
-		Events:         events,
-		Locks:          locks,
-		Frontier:       f,
-		FrontierStatus: fStatus,
-		ActiveAgents:   activeCount,
-		StaleAgents:    staleCount,
-		TotalEvents:    int(s.CountEvents()), // Use COUNT(*), not max(id), to handle ID gaps
-		ActiveLocks:    len(locks),
-		BuiltAt:        time.Now(),
+		Agents:            agents,
+		Events:            events,
+		MinLoadedID:       minID,
+		MaxLoadedID:       maxID,
+		Locks:             locks,
+		Frontier:          f,
+		Pointstamps:       active,
+		FrontierStatus:    fStatus,
+		FrontierAvailable: frontierOK,
+		ActiveAgents:      activeCount,
+		StaleAgents:       staleCount,
+		TotalEvents:       int(s.CountEvents()), // Use COUNT(*), not max(id), to handle ID gaps
+		ActiveLocks:       len(locks),
+		BuiltAt:           time.Now(),
 	}, nil
 }
+
+// LoadOlder returns up to limit events immediately preceding beforeID (IDs
+// below it), oldest first. It is how callers reach back past the window
+// Build loads.
+func LoadOlder(s Reader, beforeID int64, limit int) ([]model.Event, error) {
+	sinceID := beforeID - 1 - int64(limit)
+	if sinceID < 0 {
+		sinceID = 0
+	}
+	events, err := s.ListEventsSinceID(sinceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for n < len(events) && events[n].ID < beforeID {
+		n++
+	}
+	return events[:n], nil
+}
+
+// WithOlder returns a copy of d whose Events are preceded by the events in
+// older that fall before its window. d itself is not modified.
+//
+// ok is false, and d is returned unmerged, when those events do not end
+// right before the window: once the window moves forward past them, the
+// events in between are in neither, and merging would hide the hole.
+func (d *DataSnapshot) WithOlder(older []model.Event) (merged *DataSnapshot, ok bool) {
+	first := int64(-1)
+	if len(d.Events) > 0 {
+		first = d.Events[0].ID
+	}
+	events := make([]model.Event, 0, len(older)+len(d.Events))
+	for _, e := range older {
+		if first < 0 || e.ID < first {
+			events = append(events, e)
+		}
+	}
+	if len(events) > 0 && first >= 0 && events[len(events)-1].ID != first-1 {
+		return d, false
+	}
+	cp := *d
+	cp.Events = append(events, d.Events...)
+	if len(events) > 0 {
+		cp.MinLoadedID = events[0].ID
+		if cp.MaxLoadedID == 0 {
+			cp.MaxLoadedID = events[len(events)-1].ID
+		}
+	}
+	return &cp, true
+}
@@ -1,6 +1,7 @@
 package snapshot
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"testing"
@@ -38,7 +39,7 @@ func makeEvent(agentID string, kind model.EventKind, target, body string, ts int
 func TestBuildEmptyStore(t *testing.T) {
 	s := newTestStore(t)
 
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -72,6 +73,29 @@ func TestBuildEmptyStore(t *testing.T) {
 	}
 }
 
+func TestBuildStaleAfterPerAgent(t *testing.T) {
+	s := newTestStore(t)
+	for _, id := range []string{"alice", "batch-1"} {
+		if _, err := s.RegisterAgent(id); err != nil {
+			t.Fatalf("RegisterAgent %s: %v", id, err)
+		}
+	}
+
+	// alice's threshold has already passed; batch-1 keeps the default.
+	snap, err := Build(s, func(id string) time.Duration {
+		if id == "alice" {
+			return -time.Hour
+		}
+		return DefaultStaleAfter
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if snap.StaleAgents != 1 || snap.ActiveAgents != 1 {
+		t.Errorf("stale/active = %d/%d, want 1/1", snap.StaleAgents, snap.ActiveAgents)
+	}
+}
+
 func TestBuildWithAgents(t *testing.T) {
 	s := newTestStore(t)
 
@@ -83,7 +107,7 @@ func TestBuildWithAgents(t *testing.T) {
 		t.Fatalf("RegisterAgent bob: %v", err)
 	}
 
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -122,7 +146,7 @@ func TestBuildWithEvents(t *testing.T) {
 		t.Fatalf("InsertEvent: %v", err)
 	}
 
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -154,7 +178,7 @@ func TestBuildWithLocks(t *testing.T) {
 	}
 	_ = lock
 
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -188,7 +212,7 @@ func TestBuildFrontierTwoAgents(t *testing.T) {
 		t.Fatalf("UpdateAgentClock alice: %v", err)
 	}
 
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -210,6 +234,11 @@ func TestBuildFrontierTwoAgents(t *testing.T) {
 	if !bobStatus.SafeToFinalize {
 		t.Error("bob should be safe to finalize (alice is ahead)")
 	}
+
+	// Both pointstamps are kept, though only bob's is in the antichain.
+	if len(snap.Pointstamps) != 2 {
+		t.Errorf("expected 2 active pointstamps, got %v", snap.Pointstamps)
+	}
 }
 
 func TestBuildSnapshotIsImmutable(t *testing.T) {
@@ -219,7 +248,7 @@ func TestBuildSnapshotIsImmutable(t *testing.T) {
 		t.Fatalf("RegisterAgent: %v", err)
 	}
 
-	snap1, err := Build(s)
+	snap1, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build 1: %v", err)
 	}
@@ -229,7 +258,7 @@ func TestBuildSnapshotIsImmutable(t *testing.T) {
 		t.Fatalf("RegisterAgent bob: %v", err)
 	}
 
-	snap2, err := Build(s)
+	snap2, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build 2: %v", err)
 	}
@@ -247,7 +276,7 @@ func TestBuildBuiltAtIsRecent(t *testing.T) {
 	s := newTestStore(t)
 
 	before := time.Now()
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -277,7 +306,7 @@ func TestBuildMultipleEventTypes(t *testing.T) {
 		}
 	}
 
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -303,7 +332,7 @@ func TestBuildWithReopenedStore(t *testing.T) {
 	}
 	defer s2.Close()
 
-	snap, err := Build(s2)
+	snap, err := Build(s2, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -327,7 +356,7 @@ func TestBuildFetchesNewestEvents(t *testing.T) {
 		}
 	}
 
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -360,7 +389,7 @@ func TestBuildClosedStoreReturnsError(t *testing.T) {
 	}
 	s.Close() // Close before Build
 
-	_, err = Build(s)
+	_, err = Build(s, nil)
 	if err == nil {
 		t.Error("Build on closed store should return an error")
 	}
@@ -377,7 +406,7 @@ func TestBuildFrontierStatusMap(t *testing.T) {
 	}
 
 	// All at same epoch — everyone should be safe (no one is behind anyone else).
-	snap, err := Build(s)
+	snap, err := Build(s, nil)
 	if err != nil {
 		t.Fatalf("Build: %v", err)
 	}
@@ -397,3 +426,161 @@ func TestBuildFrontierStatusMap(t *testing.T) {
 		}
 	}
 }
+
+// blockingReader is a Reader whose ListAgents blocks until release is closed,
+// simulating a store held by a long writer.
+type blockingReader struct {
+	*store.Store
+	release chan struct{}
+}
+
+func (r blockingReader) ListAgents() ([]model.Agent, error) {
+	<-r.release
+	return r.Store.ListAgents()
+}
+
+func TestBuildWithTimeoutBlockedStore(t *testing.T) {
+	r := blockingReader{Store: newTestStore(t), release: make(chan struct{})}
+	defer close(r.release)
+
+	start := time.Now()
+	snap, err := BuildWithTimeout(r, 50*time.Millisecond, nil)
+	if !errors.Is(err, ErrBuildTimeout) {
+		t.Fatalf("expected ErrBuildTimeout, got %v", err)
+	}
+	if snap != nil {
+		t.Error("timed-out build should not return a snapshot")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("BuildWithTimeout took %s, should abort near the deadline", elapsed)
+	}
+}
+
+func TestBuildWithTimeoutFastStore(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.RegisterAgent("alice"); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	snap, err := BuildWithTimeout(s, 5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("BuildWithTimeout: %v", err)
+	}
+	if len(snap.Agents) != 1 {
+		t.Errorf("expected 1 agent, got %d", len(snap.Agents))
+	}
+}
+
+func TestLoadOlderAndWithOlder(t *testing.T) {
+	s := newTestStore(t)
+	for i := 1; i <= 10; i++ {
+		e := makeEvent("alice", model.EventMsg, "bob", fmt.Sprintf("msg-%d", i), int64(i))
+		if _, err := s.InsertEvent(e); err != nil {
+			t.Fatalf("InsertEvent %d: %v", i, err)
+		}
+	}
+
+	older, err := LoadOlder(s, 8, 3)
+	if err != nil {
+		t.Fatalf("LoadOlder: %v", err)
+	}
+	if len(older) != 3 || older[0].ID != 5 || older[2].ID != 7 {
+		t.Fatalf("LoadOlder(8, 3) = %d events from %v; want IDs 5..7", len(older), older)
+	}
+	if all, _ := LoadOlder(s, 3, 100); len(all) != 2 {
+		t.Errorf("LoadOlder near the start should stop at ID 1, got %d events", len(all))
+	}
+
+	snap := &DataSnapshot{Events: []model.Event{{ID: 7}, {ID: 8}}}
+	merged, ok := snap.WithOlder(older)
+	if !ok {
+		t.Fatal("WithOlder should accept events that end right before the window")
+	}
+	var ids []int64
+	for _, e := range merged.Events {
+		ids = append(ids, e.ID)
+	}
+	if fmt.Sprint(ids) != "[5 6 7 8]" {
+		t.Errorf("WithOlder IDs = %v, want [5 6 7 8] (no duplicate of 7)", ids)
+	}
+	if len(snap.Events) != 2 {
+		t.Error("WithOlder must not modify the original snapshot")
+	}
+
+	// The window moved forward: 8 and 9 are in neither, so don't merge.
+	advanced := &DataSnapshot{Events: []model.Event{{ID: 10}}, MinLoadedID: 10, MaxLoadedID: 10}
+	if got, ok := advanced.WithOlder(older); ok || got != advanced {
+		t.Errorf("WithOlder across a gap = %v, ok %v; want the snapshot unmerged, false", got.Events, ok)
+	}
+}
+
+// noPointstampsReader is a store without pointstamp support.
+type noPointstampsReader struct {
+	*store.Store
+}
+
+func (noPointstampsReader) GetActivePointstamps() ([]model.Pointstamp, error) {
+	return nil, errors.New("no such table: pointstamps")
+}
+
+func TestBuildWithoutPointstamps(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.RegisterAgent("alice"); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if _, err := s.InsertEvent(makeEvent("alice", model.EventMsg, "bob", "hi", 1)); err != nil {
+		t.Fatalf("InsertEvent: %v", err)
+	}
+
+	snap, err := Build(noPointstampsReader{s}, nil)
+	if err != nil {
+		t.Fatalf("Build should tolerate a missing pointstamp query: %v", err)
+	}
+	if snap.FrontierAvailable {
+		t.Error("FrontierAvailable should be false")
+	}
+	if len(snap.Frontier) != 0 || len(snap.FrontierStatus) != 0 {
+		t.Errorf("frontier should be empty, got %v / %v", snap.Frontier, snap.FrontierStatus)
+	}
+	if len(snap.Agents) != 1 || len(snap.Events) != 1 {
+		t.Errorf("the rest of the snapshot should be intact: %d agents, %d events", len(snap.Agents), len(snap.Events))
+	}
+
+	if full, _ := Build(s, nil); !full.FrontierAvailable {
+		t.Error("a store with pointstamps should report FrontierAvailable")
+	}
+}
+
+func TestBuildLoadedIDRange(t *testing.T) {
+	s := newTestStore(t)
+	const total = EventLimit + 100
+	for i := 1; i <= total; i++ {
+		e := makeEvent("alice", model.EventMsg, "bob", fmt.Sprintf("msg-%d", i), int64(i))
+		if _, err := s.InsertEvent(e); err != nil {
+			t.Fatalf("InsertEvent %d: %v", i, err)
+		}
+	}
+
+	snap, err := Build(s, nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if snap.MinLoadedID != 101 || snap.MaxLoadedID != total {
+		t.Errorf("loaded IDs = %d..%d, want 101..%d", snap.MinLoadedID, snap.MaxLoadedID, total)
+	}
+	if snap.TotalEvents != total {
+		t.Errorf("TotalEvents = %d, want %d", snap.TotalEvents, total)
+	}
+
+	older, err := LoadOlder(s, snap.MinLoadedID, 50)
+	if err != nil {
+		t.Fatalf("LoadOlder: %v", err)
+	}
+	if merged, _ := snap.WithOlder(older); merged.MinLoadedID != 51 || merged.MaxLoadedID != total {
+		t.Errorf("after WithOlder IDs = %d..%d, want 51..%d", merged.MinLoadedID, merged.MaxLoadedID, total)
+	}
+
+	if empty, _ := Build(newTestStore(t), nil); empty.MinLoadedID != 0 || empty.MaxLoadedID != 0 {
+		t.Errorf("empty store IDs = %d..%d, want 0..0", empty.MinLoadedID, empty.MaxLoadedID)
+	}
+}